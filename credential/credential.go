@@ -0,0 +1,110 @@
+package credential
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/algon-320/KIDE/setting"
+	"github.com/algon-320/KIDE/util"
+)
+
+// Store ... オンラインジャッジのパスワードの保存先
+type Store interface {
+	// Get ... 保存されているパスワードを返す (保存されていない場合 ok は false)
+	Get(judge, handle string) (password string, ok bool, err error)
+	// Set ... パスワードを保存する (既にある場合は上書き)
+	Set(judge, handle, password string) error
+	// Delete ... パスワードを削除する (保存されていない場合は何もしない)
+	Delete(judge, handle string) error
+}
+
+const (
+	// Filename ... 暗号化したパスワードを保存するファイルの名前
+	Filename = "credentials.enc"
+)
+
+var (
+	mu       sync.Mutex
+	store    Store
+	migrated bool
+)
+
+// Open ... 設定に従ってパスワードの保存先を返す
+// `Credential.Helper`(環境変数`KIDE_CREDENTIAL_HELPER`)が設定されていれば外部コマンド、そうでなければ暗号化したファイルを使う
+func Open() Store {
+	mu.Lock()
+	defer mu.Unlock()
+	if store == nil {
+		if tmp, ok := setting.Get("Credential.Helper", "KIDE_CREDENTIAL_HELPER"); ok && tmp.(string) != "" {
+			store = &helperStore{command: tmp.(string)}
+		} else {
+			store = newFileStore(util.DataPath(Filename))
+		}
+	}
+	return store
+}
+
+// LoadPassword ... オンラインジャッジのパスワードを返す
+// 環境変数 envVarKey があればそれを、無ければ保存先から読み、保存されていなければユーザに入力させて保存する
+// judge: settings.jsonの`OnlineJudge`->`{judge}`と同じ名前, question: 入力させるときのメッセージ
+func LoadPassword(judge, handle, envVarKey, question string) string {
+	if v, ok := os.LookupEnv(envVarKey); ok {
+		util.DebugPrint("password loaded from environment variable `" + envVarKey + "`")
+		return v
+	}
+
+	s := Open()
+	migratePlaintext(s)
+
+	password, ok, err := s.Get(judge, handle)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if ok {
+		return password
+	}
+
+	password = util.AskPassword(question)
+	if err := s.Set(judge, handle, password); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, util.PrefixCaution+"The password is not saved.")
+	}
+	return password
+}
+
+// migratePlaintext ... 以前のバージョンでsettings.jsonに平文で保存したパスワードを保存先に移す
+func migratePlaintext(s Store) {
+	mu.Lock()
+	defer mu.Unlock()
+	if migrated {
+		return
+	}
+	migrated = true
+
+	tmp, ok := setting.Get("OnlineJudge", "")
+	if !ok {
+		return
+	}
+	judges, ok := tmp.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for judge, v := range judges {
+		account, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		password, ok1 := account["Password"].(string)
+		handle, ok2 := account["Handle"].(string)
+		if !ok1 || !ok2 {
+			continue
+		}
+		if err := s.Set(judge, handle, password); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, util.PrefixCaution+"The password of "+judge+" is left in settings.json.")
+			continue
+		}
+		setting.Delete("OnlineJudge." + judge + ".Password")
+		fmt.Fprintln(os.Stderr, util.PrefixInfo+"Moved the password of "+judge+" from settings.json to the credential store.")
+	}
+}
@@ -0,0 +1,134 @@
+package credential
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	fmt.Println("testing : file.go > fileStore")
+
+	dir, err := ioutil.TempDir("", "kide-credential")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, Filename)
+
+	asked := 0
+	ask := func(pass string) func(string) string {
+		return func(string) string {
+			asked++
+			return pass
+		}
+	}
+
+	fs := newFileStore(path)
+	fs.askPassphrase = ask("correct horse")
+	if _, ok, err := fs.Get("AtCoder", "kide"); ok || err != nil || asked != 0 {
+		t.Fatalf("Get before saving : ok %v, err %v, asked %d times", ok, err, asked)
+	}
+	if err := fs.Set("AtCoder", "kide", "p@ss word"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Set("Codeforces", "kide", "another"); err != nil {
+		t.Fatal(err)
+	}
+	if asked != 2 {
+		t.Errorf("passphrase asked %d times, expected 2 (new and retype)", asked)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(data), "p@ss word") {
+		t.Errorf("the password is written in plaintext")
+	}
+
+	asked = 0
+	fs = newFileStore(path)
+	fs.askPassphrase = ask("correct horse")
+	if password, ok, err := fs.Get("AtCoder", "kide"); !ok || err != nil || password != "p@ss word" {
+		t.Errorf("Get returned %q, %v, %v", password, ok, err)
+	}
+	if err := fs.Delete("Codeforces", "kide"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := fs.Get("Codeforces", "kide"); ok {
+		t.Errorf("deleted password still exists")
+	}
+	if asked != 1 {
+		t.Errorf("passphrase asked %d times, expected 1", asked)
+	}
+
+	fs = newFileStore(path)
+	fs.askPassphrase = ask("wrong")
+	if _, _, err := fs.Get("AtCoder", "kide"); err == nil {
+		t.Errorf("wrong passphrase is accepted")
+	} else if _, ok := err.(*ErrWrongPassphrase); !ok {
+		t.Errorf("unexpected error : %v", err)
+	}
+
+	fs = newFileStore(filepath.Join(dir, "mismatch.enc"))
+	pass := []string{"a", "b"}
+	fs.askPassphrase = func(string) string {
+		p := pass[0]
+		pass = pass[1:]
+		return p
+	}
+	if err := fs.Set("AtCoder", "kide", "x"); err == nil {
+		t.Errorf("mismatched passphrases are accepted")
+	}
+}
+
+func TestHelperStore(t *testing.T) {
+	fmt.Println("testing : helper.go > helperStore")
+
+	dir, err := ioutil.TempDir("", "kide-credential")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 受け取った入力をそのままファイルに保存し、getではpassword行を返すだけのヘルパー
+	script := filepath.Join(dir, "helper.sh")
+	saved := filepath.Join(dir, "saved")
+	body := `#!/bin/sh
+case "$1" in
+get) grep '^password=' ` + saved + ` 2>/dev/null; exit 0 ;;
+store) cat > ` + saved + ` ;;
+erase) rm -f ` + saved + ` ;;
+*) echo "unknown action" >&2; exit 1 ;;
+esac
+`
+	if err := ioutil.WriteFile(script, []byte(body), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &helperStore{command: script}
+	if _, ok, err := h.Get("AtCoder", "kide"); ok || err != nil {
+		t.Fatalf("Get before saving : ok %v, err %v", ok, err)
+	}
+	if err := h.Set("AtCoder", "kide", "p=ss"); err != nil {
+		t.Fatal(err)
+	}
+	input, _ := ioutil.ReadFile(saved)
+	if string(input) != "judge=AtCoder\nhandle=kide\npassword=p=ss\n" {
+		t.Errorf("helper received %q", string(input))
+	}
+	if password, ok, err := h.Get("AtCoder", "kide"); !ok || err != nil || password != "p=ss" {
+		t.Errorf("Get returned %q, %v, %v", password, ok, err)
+	}
+	if err := h.Delete("AtCoder", "kide"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := h.Get("AtCoder", "kide"); ok {
+		t.Errorf("erased password still exists")
+	}
+
+	if _, err := h.run("unknown", nil); err == nil || !strings.Contains(err.Error(), "unknown action") {
+		t.Errorf("unexpected error : %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package credential
+
+import (
+	"fmt"
+
+	"github.com/algon-320/KIDE/util"
+)
+
+type ErrWrongPassphrase struct {
+	path string
+}
+
+func (e ErrWrongPassphrase) Error() string {
+	return util.PrefixError + fmt.Sprintf("Failed to decrypt `%s` : wrong passphrase or broken file", e.path)
+}
+
+//-----------------
+
+type ErrPassphraseMismatch struct {
+}
+
+func (e ErrPassphraseMismatch) Error() string {
+	return util.PrefixError + "Passphrases do not match (or empty)."
+}
+
+//-----------------
+
+type ErrHelperFailed struct {
+	command string
+	action  string
+	message string
+}
+
+func (e ErrHelperFailed) Error() string {
+	return util.PrefixError + fmt.Sprintf("Credential helper `%s %s` failed : %s", e.command, e.action, e.message)
+}
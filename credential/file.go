@@ -0,0 +1,196 @@
+package credential
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/algon-320/KIDE/util"
+	"golang.org/x/crypto/scrypt"
+)
+
+// scryptのパラメータ (ファイルにも書いておき、読むときはそれを使う)
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// encryptedFile ... 暗号化したファイルの中身
+// Data はAES-256-GCMで暗号化した `judge/handle` -> パスワード のJSON
+type encryptedFile struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// fileStore ... パスフレーズから導出した鍵で暗号化したファイルに保存する
+// パスフレーズは環境変数`KIDE_CREDENTIAL_PASSPHRASE`、無ければユーザに入力させる (1回の実行で1度だけ)
+type fileStore struct {
+	mu      sync.Mutex
+	path    string
+	file    encryptedFile
+	key     []byte
+	entries map[string]string
+
+	// askPassphrase ... パスフレーズを入力させる (テストで差し替える)
+	askPassphrase func(message string) string
+}
+
+func newFileStore(path string) *fileStore {
+	return &fileStore{path: path, askPassphrase: util.AskPassword}
+}
+
+func entryKey(judge, handle string) string {
+	return judge + "/" + handle
+}
+
+func (fs *fileStore) passphrase(create bool) (string, error) {
+	if v, ok := os.LookupEnv("KIDE_CREDENTIAL_PASSPHRASE"); ok {
+		return v, nil
+	}
+	if !create {
+		return fs.askPassphrase("Passphrase of the credential store"), nil
+	}
+	p1 := fs.askPassphrase("New passphrase to encrypt your passwords")
+	p2 := fs.askPassphrase("Retype the passphrase")
+	if p1 == "" || p1 != p2 {
+		return "", &ErrPassphraseMismatch{}
+	}
+	return p1, nil
+}
+
+// load ... ファイルを読んで復号する (ファイルが無ければ新しく鍵を作る)
+func (fs *fileStore) load() error {
+	if fs.entries != nil {
+		return nil
+	}
+
+	if !util.FileExists(fs.path) {
+		pass, err := fs.passphrase(true)
+		if err != nil {
+			return err
+		}
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		file := encryptedFile{N: scryptN, R: scryptR, P: scryptP, Salt: salt}
+		key, err := scrypt.Key([]byte(pass), salt, file.N, file.R, file.P, scryptKeyLen)
+		if err != nil {
+			return err
+		}
+		fs.file, fs.key, fs.entries = file, key, make(map[string]string)
+		return nil
+	}
+
+	bytes, err := ioutil.ReadFile(fs.path)
+	if err != nil {
+		return err
+	}
+	var file encryptedFile
+	if err := json.Unmarshal(bytes, &file); err != nil {
+		return &ErrWrongPassphrase{path: fs.path}
+	}
+	pass, err := fs.passphrase(false)
+	if err != nil {
+		return err
+	}
+	key, err := scrypt.Key([]byte(pass), file.Salt, file.N, file.R, file.P, scryptKeyLen)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return &ErrWrongPassphrase{path: fs.path}
+	}
+	entries := make(map[string]string)
+	if err := json.Unmarshal(plain, &entries); err != nil {
+		return &ErrWrongPassphrase{path: fs.path}
+	}
+	fs.file, fs.key, fs.entries = file, key, entries
+	return nil
+}
+
+// save ... 新しいnonceで暗号化して書き込む (一時ファイルに書いてから置き換える)
+func (fs *fileStore) save() error {
+	plain, err := json.Marshal(fs.entries)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(fs.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	fs.file.Nonce = nonce
+	fs.file.Data = gcm.Seal(nil, nonce, plain, nil)
+
+	bytes, err := json.Marshal(fs.file)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(fs.path, bytes, 0600)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (fs *fileStore) Get(judge, handle string) (string, bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if !util.FileExists(fs.path) {
+		return "", false, nil
+	}
+	if err := fs.load(); err != nil {
+		return "", false, err
+	}
+	password, ok := fs.entries[entryKey(judge, handle)]
+	return password, ok, nil
+}
+
+func (fs *fileStore) Set(judge, handle, password string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.load(); err != nil {
+		return err
+	}
+	fs.entries[entryKey(judge, handle)] = password
+	return fs.save()
+}
+
+func (fs *fileStore) Delete(judge, handle string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if !util.FileExists(fs.path) {
+		return nil
+	}
+	if err := fs.load(); err != nil {
+		return err
+	}
+	if _, ok := fs.entries[entryKey(judge, handle)]; !ok {
+		return nil
+	}
+	delete(fs.entries, entryKey(judge, handle))
+	return fs.save()
+}
@@ -0,0 +1,65 @@
+package credential
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/algon-320/KIDE/util"
+)
+
+// helperStore ... 外部コマンドにパスワードの保存を任せる
+// コマンドの引数の最後に`get`・`store`・`erase`のどれかが付けられ、標準入力に`judge=...`、`handle=...`
+// (`store`の場合は`password=...`も)が1行ずつ渡される。
+// `get`ではパスワードが見つかった場合に`password=...`を標準出力に書く。
+type helperStore struct {
+	command string
+}
+
+func (h *helperStore) run(action string, fields [][2]string) (map[string]string, error) {
+	var in bytes.Buffer
+	for _, f := range fields {
+		in.WriteString(f[0] + "=" + f[1] + "\n")
+	}
+	var out, stderr bytes.Buffer
+	cmd := util.Command(h.command + " " + action)
+	cmd.Stdin = &in
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return nil, &ErrHelperFailed{command: h.command, action: action, message: message}
+	}
+
+	ret := make(map[string]string)
+	sc := bufio.NewScanner(&out)
+	for sc.Scan() {
+		kv := strings.SplitN(sc.Text(), "=", 2)
+		if len(kv) == 2 {
+			ret[kv[0]] = kv[1]
+		}
+	}
+	return ret, nil
+}
+
+func (h *helperStore) Get(judge, handle string) (string, bool, error) {
+	ret, err := h.run("get", [][2]string{{"judge", judge}, {"handle", handle}})
+	if err != nil {
+		return "", false, err
+	}
+	password, ok := ret["password"]
+	return password, ok, nil
+}
+
+func (h *helperStore) Set(judge, handle, password string) error {
+	_, err := h.run("store", [][2]string{{"judge", judge}, {"handle", handle}, {"password", password}})
+	return err
+}
+
+func (h *helperStore) Delete(judge, handle string) error {
+	_, err := h.run("erase", [][2]string{{"judge", judge}, {"handle", handle}})
+	return err
+}
@@ -0,0 +1,13 @@
+package credential
+
+import (
+	"os"
+	"testing"
+
+	"github.com/algon-320/KIDE/internal/testenv"
+)
+
+// TestMain ... 設定やパスワードを本来の保存先から読み書きしないように、一時ディレクトリを KIDE_HOME にしてテストする
+func TestMain(m *testing.M) {
+	os.Exit(testenv.Run(m))
+}
@@ -0,0 +1,35 @@
+// Package testenv ... テストで設定やデータを本来の保存先 (~/.config/kide など) に読み書きしないようにする
+//
+// テストファイルで `_ "github.com/algon-320/KIDE/internal/testenv"` のように読み込む。
+// このパッケージは他の依存を持たず、インポートパスが setting、language、util より前なので、
+// それらのパッケージの初期化 (設定ファイルの読み込み) より前に初期化される。
+package testenv
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Home ... テストの間 KIDE_HOME にする一時ディレクトリ
+var Home string
+
+func init() {
+	dir, err := ioutil.TempDir("", "kide_test")
+	if err != nil {
+		panic(err)
+	}
+	Home = dir
+	os.Setenv("KIDE_HOME", Home)
+	for _, key := range []string{"KIDE_CONFIG_DIR", "KIDE_DATA_DIR", "KIDE_CACHE_DIR"} {
+		os.Unsetenv(key)
+	}
+}
+
+// Run ... テストを実行して一時ディレクトリを削除する (TestMain から呼ぶ)
+// return: 終了コード
+func Run(m *testing.M) int {
+	code := m.Run()
+	os.RemoveAll(Home)
+	return code
+}
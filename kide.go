@@ -1,11 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/algon-320/KIDE/setting"
 	"github.com/algon-320/KIDE/snippet_manager"
@@ -16,57 +19,128 @@ import (
 	"github.com/urfave/cli"
 )
 
+// getProfile ... --profile で指定されたプロファイル名を返す (指定されていない場合は defaultProfile)
+func getProfile(c *cli.Context, lang language.Language, defaultProfile string) (string, error) {
+	if !c.IsSet("profile") {
+		return defaultProfile, nil
+	}
+	profile := c.String("profile")
+	if err := language.CheckProfile(lang, profile); err != nil {
+		return "", err
+	}
+	return profile, nil
+}
+
 func cmdRun(c *cli.Context) error {
 	lang := language.GetLanguage(c.String("language"))
-	if err := run(lang); err != nil {
+	profile, err := getProfile(c, lang, "")
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if err := run(lang, profile); err != nil {
 		return cli.NewExitError(err, 1)
 	}
 	return nil
 }
 
 func cmdTester(c *cli.Context) error {
-	if c.NArg() < 1 {
-		return cli.NewExitError(util.PrefixError+"few args", 1)
+	lang := language.GetLanguage(c.String("language"))
+	profile, err := getProfile(c, lang, language.ProfileDebug)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	problemID, err := chooseProblem(c.Args(), lang)
+	if err != nil {
+		return cli.NewExitError(err, 1)
 	}
+	if err := tester(lang, problemID, c.Int("case"), profile); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
 
+func cmdBench(c *cli.Context) error {
 	lang := language.GetLanguage(c.String("language"))
-	problemID := c.Args().First()
-	if err := tester(lang, problemID, c.Int("case")); err != nil {
+	profile, err := getProfile(c, lang, language.ProfileRelease)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	problemID, err := chooseProblem(c.Args(), lang)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if err := bench(lang, problemID, profile, c.Int("repeat")); err != nil {
 		return cli.NewExitError(err, 1)
 	}
 	return nil
 }
 
 func cmdDl(c *cli.Context) error {
+	if c.Bool("retry") {
+		if err := online_judge.RetryFailedDownloads(); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		return nil
+	}
 	if c.NArg() < 1 {
 		return cli.NewExitError(util.PrefixError+"few args", 1)
 	}
 
 	url := c.Args().First()
+	if c.Bool("wait") {
+		// コンテストの開始を待ってダウンロードする (--new なら問題ごとのディレクトリも作る)
+		if c.Bool("new") {
+			lang := language.GetLanguage(c.String("language"))
+			if err := newWorkspace(url, lang, c.String("dir"), true); err != nil {
+				return cli.NewExitError(err, 1)
+			}
+			return nil
+		}
+		if _, err := online_judge.WaitAndDownloadContest(url); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		return nil
+	}
 	if err := downloadSampleCase(url); err != nil {
 		return cli.NewExitError(err, 1)
 	}
 	return nil
 }
 
-func cmdSubmit(c *cli.Context) error {
+func cmdNew(c *cli.Context) error {
 	if c.NArg() < 1 {
 		return cli.NewExitError(util.PrefixError+"few args", 1)
 	}
 
 	lang := language.GetLanguage(c.String("language"))
+	if err := newWorkspace(c.Args().First(), lang, c.String("dir"), c.Bool("wait")); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
+
+func cmdSubmit(c *cli.Context) error {
+	lang := language.GetLanguage(c.String("language"))
+	profile, err := getProfile(c, lang, "")
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	problemID, err := chooseProblem(c.Args(), lang)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
 
 	filename, err := language.FindSourceCode(lang)
 	if err != nil {
 		return cli.NewExitError(err, 1)
 	}
 
-	p, err := online_judge.LoadProblem(c.Args().First())
+	p, err := online_judge.LoadProblem(problemID)
 	if err != nil {
 		return cli.NewExitError(err, 1)
 	}
 
-	err = submit(filename, lang, p)
+	err = submit(filename, lang, profile, p)
 	if err != nil {
 		return cli.NewExitError(err, 1)
 	}
@@ -74,19 +148,56 @@ func cmdSubmit(c *cli.Context) error {
 }
 
 func cmdView(c *cli.Context) error {
+	if c.Bool("judges") {
+		// 登録されているオンラインジャッジを表示
+		title := []string{"name", "aliases", "hosts", "capabilities"}
+		data := [][]string{}
+		for _, r := range online_judge.Registered() {
+			data = append(data, []string{
+				r.Name(),
+				strings.Join(r.Aliases, ", "),
+				strings.Join(r.Hosts, ", "),
+				strings.Join(r.CapabilityLabels(), ", "),
+			})
+		}
+		util.PrintTable(title, data, true)
+		return nil
+	}
+
 	if c.NArg() < 1 {
-		// 引数が無い場合はすべて表示
-		list := online_judge.GetAllProblemID()
+		// 引数が無い場合はインデックスから条件に合う問題を表示
+		filter := &online_judge.IndexFilter{
+			Contest:  c.String("contest"),
+			Unsolved: c.Bool("unsolved"),
+			Search:   c.String("search"),
+		}
+		if c.IsSet("oj") {
+			oj, err := online_judge.FromName(c.String("oj"))
+			if err != nil {
+				return cli.NewExitError(err, 1)
+			}
+			filter.Judge = oj.Name()
+		}
+
+		entries, err := online_judge.ProblemIndex()
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		entries = online_judge.FilterIndex(entries, filter)
+		if err := online_judge.SortIndex(entries, c.String("sort"), c.Bool("reverse")); err != nil {
+			return cli.NewExitError(err, 1)
+		}
 
 		// make table
-		title := []string{"id", "name", "oj name", "url"}
+		title := []string{"id", "name", "oj name", "tags", "downloaded", "local", "judge"}
 		data := [][]string{}
-		for _, v := range list {
-			p, err := online_judge.LoadProblem(v)
-			if err != nil {
-				return cli.NewExitError(err, 1)
+		for _, e := range entries {
+			judge := e.JudgeVerdict
+			if e.Solved() && judge != "AC" {
+				judge += " (solved)"
 			}
-			data = append(data, []string{v, p.Name, p.Oj.Name(), p.URL})
+			data = append(data, []string{e.Key, e.Name, e.Judge, strings.Join(e.Tags, ", "),
+				e.DownloadedAt.Format("2006-01-02"), e.LocalVerdict, judge})
 		}
 
 		util.PrintTable(title, data, true)
@@ -102,6 +213,194 @@ func cmdView(c *cli.Context) error {
 	return nil
 }
 
+func cmdStatement(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return cli.NewExitError(util.PrefixError+"few args", 1)
+	}
+	lang := c.String("lang")
+	if lang != "ja" && lang != "en" {
+		return cli.NewExitError(util.PrefixError+"--lang must be `ja` or `en`", 1)
+	}
+
+	p, err := online_judge.LoadStatement(c.Args().First())
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if err := p.PrintStatement(lang); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
+
+func cmdProblemRm(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return cli.NewExitError(util.PrefixError+"few args", 1)
+	}
+	for _, id := range c.Args() {
+		key, err := online_judge.RemoveProblem(id)
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		fmt.Println(util.PrefixInfo + fmt.Sprintf("Removed `%s`", key))
+	}
+	return nil
+}
+
+func cmdProblemMv(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return cli.NewExitError(util.PrefixError+"few args", 1)
+	}
+	key, err := online_judge.ResolveProblemID(c.Args().Get(0))
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	p, err := online_judge.MoveProblem(key, c.Args().Get(1))
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	fmt.Println(util.PrefixInfo + fmt.Sprintf("Moved `%s` to `%s`", key, p.Key()))
+	return nil
+}
+
+func cmdProblemRefresh(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return cli.NewExitError(util.PrefixError+"few args", 1)
+	}
+	for _, id := range c.Args() {
+		p, kept, err := online_judge.RefreshProblem(id)
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		fmt.Println(util.PrefixInfo + fmt.Sprintf("Refreshed `%s` : %d samples, %d added cases kept", p.Key(), len(p.Cases)-kept, kept))
+	}
+	return nil
+}
+
+func cmdProblemPrune(c *cli.Context) error {
+	opt := online_judge.PruneOptions{
+		OlderThan: time.Duration(c.Int("older-than")) * 24 * time.Hour,
+		Finished:  c.Bool("finished"),
+	}
+	if opt.OlderThan <= 0 && !opt.Finished {
+		return cli.NewExitError(util.PrefixError+"specify --older-than or --finished", 1)
+	}
+
+	entries, err := online_judge.PruneCandidates(opt, time.Now())
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if len(entries) == 0 {
+		fmt.Println(util.PrefixInfo + "No problems to remove.")
+		return nil
+	}
+
+	title := []string{"id", "name", "oj name", "downloaded", "judge"}
+	data := [][]string{}
+	for _, e := range entries {
+		data = append(data, []string{e.Key, e.Name, e.Judge, e.DownloadedAt.Format("2006-01-02"), e.JudgeVerdict})
+	}
+	util.PrintTable(title, data, true)
+
+	if !c.Bool("yes") {
+		fmt.Fprintln(os.Stderr, util.PrefixQuestion+fmt.Sprintf("Remove these %d problems?", len(entries)))
+		if !util.AskYesNo() {
+			fmt.Println(util.PrefixInfo + "Prune cancelled.")
+			return nil
+		}
+	}
+	for _, e := range entries {
+		if _, err := online_judge.RemoveProblem(e.Key); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+	}
+	fmt.Println(util.PrefixInfo + fmt.Sprintf("Removed %d problems", len(entries)))
+	return nil
+}
+
+func cmdHistory(c *cli.Context) error {
+	var entries []*online_judge.HistoryEntry
+	var err error
+	if c.NArg() < 1 {
+		entries, err = online_judge.SubmissionHistory()
+	} else {
+		entries, err = online_judge.ProblemHistory(c.Args().First())
+	}
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if limit := c.Int("limit"); limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	title := []string{"#", "date", "problem", "oj name", "language", "verdict", "time", "memory", "url"}
+	data := [][]string{}
+	for _, e := range entries {
+		data = append(data, []string{
+			fmt.Sprintf("%d", e.N),
+			e.SubmittedAt.Local().Format("2006-01-02 15:04"),
+			e.ProblemKey,
+			e.Judge,
+			e.Language,
+			e.Status().GetColorESCS() + e.Verdict + util.ESCS_COL_OFF,
+			e.Time,
+			e.Memory,
+			e.URL,
+		})
+	}
+	util.PrintTable(title, data, true)
+	return nil
+}
+
+func cmdHistoryShow(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return cli.NewExitError(util.PrefixError+"few args", 1)
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(c.Args().First(), "#"))
+	if err != nil {
+		return cli.NewExitError(util.PrefixError+"the submission number must be an integer", 1)
+	}
+	e, err := online_judge.HistoryEntryAt(n)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if err := e.Print(); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
+
+func cmdStats(c *cli.Context) error {
+	stats, err := online_judge.LoadStats()
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if c.Bool("json") {
+		jsonBytes, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		fmt.Println(string(jsonBytes))
+		return nil
+	}
+	stats.Print()
+	return nil
+}
+
+func cmdLangs(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return cli.NewExitError(util.PrefixError+"few args", 1)
+	}
+
+	oj, err := online_judge.FromName(c.Args().First())
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if err := showLanguages(oj, c.Bool("refresh")); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
+
 func cmdProcesser(c *cli.Context) error {
 	lang := language.GetLanguage(c.String("language"))
 
@@ -116,6 +415,10 @@ func cmdProcesser(c *cli.Context) error {
 	}
 	sourceCodeStr := string(sourceCode)
 
+	sourceCodeStr, err = bundleSource(sourceCodeStr, lang)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
 	sourceCodeStr = processSource(sourceCodeStr)
 	fmt.Print(sourceCodeStr)
 	return nil
@@ -141,16 +444,86 @@ func cmdAtCoderConv(c *cli.Context) error {
 	return nil
 }
 
+func cmdStatus(c *cli.Context) error {
+	if err := online_judge.WatchSubmissions(c.Args().First()); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
+
+// cmdCodeforcesMySubmissionsViewer ... 以前の cf-mysubmissions (Codeforces のコンテストを指定した status と同じ)
 func cmdCodeforcesMySubmissionsViewer(c *cli.Context) error {
 	if c.NArg() < 1 {
 		return cli.NewExitError(util.PrefixError+"few args", 1)
 	}
+	if _, err := strconv.Atoi(c.Args().First()); err != nil {
+		return cli.NewExitError(util.PrefixError+"designate contest id like\"1038\"", 1)
+	}
+
+	fmt.Fprintln(os.Stderr, util.PrefixCaution+"`cf-mysubmissions` is deprecated. Use `kide status codeforces/"+c.Args().First()+"` instead.")
+	if err := online_judge.WatchSubmissions("codeforces/" + c.Args().First()); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
 
-	contestID, err := strconv.Atoi(c.Args().First())
+func cmdLogin(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return cli.NewExitError(util.PrefixError+"few args", 1)
+	}
+	oj, err := online_judge.FromName(c.Args().First())
 	if err != nil {
-		return cli.NewExitError(util.PrefixError+"designate contest id like\"1038\"", 1)
+		return cli.NewExitError(err, 1)
+	}
+	if err := online_judge.Login(oj); err != nil {
+		return cli.NewExitError(err, 1)
 	}
-	online_judge.Codeforces.ShowMySubmissions(contestID)
+	return nil
+}
+
+func cmdLogout(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return cli.NewExitError(util.PrefixError+"few args", 1)
+	}
+	oj, err := online_judge.FromName(c.Args().First())
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if err := online_judge.Logout(oj, c.Bool("forget")); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
+
+func cmdWhoami(c *cli.Context) error {
+	title := []string{"oj name", "handle", "session", "expires", "session file"}
+	data := [][]string{}
+	for _, st := range online_judge.Accounts(c.Bool("check")) {
+		handle := st.Handle
+		if handle == "" {
+			handle = "-"
+		}
+		session := "none"
+		if st.HasSession {
+			session = "saved"
+			if !st.Expires.IsZero() && st.Expires.Before(time.Now()) {
+				session = "expired"
+			}
+			if st.Valid != nil {
+				if *st.Valid {
+					session = "valid"
+				} else {
+					session = "rejected"
+				}
+			}
+		}
+		expires := "-"
+		if !st.Expires.IsZero() {
+			expires = st.Expires.Local().Format("2006-01-02 15:04")
+		}
+		data = append(data, []string{st.Judge, handle, session, expires, st.SessionPath})
+	}
+	util.PrintTable(title, data, true)
 	return nil
 }
 
@@ -201,6 +574,10 @@ func main() {
 					Value: defaultLangName,
 					Usage: "runnig as `LANGUAGE`",
 				},
+				cli.StringFlag{
+					Name:  "profile",
+					Usage: "compiling with `PROFILE` (default: the default commands of the language)",
+				},
 			},
 		},
 		{
@@ -220,14 +597,88 @@ func main() {
 					Value: -1,
 					Usage: "testing only one case. `INDEX` is index of samples (1-indexed value)",
 				},
+				cli.StringFlag{
+					Name:  "profile",
+					Usage: "compiling with `PROFILE` (default: \"" + language.ProfileDebug + "\")",
+				},
+			},
+		},
+		{
+			Name:      "bench",
+			Aliases:   []string{"b"},
+			Usage:     "Measures the running time of samplecases",
+			UsageText: "bench [problem id] [command options]",
+			Action:    cmdBench,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "language, l",
+					Value: defaultLangName,
+					Usage: "running in `LANGUAGE`",
+				},
+				cli.StringFlag{
+					Name:  "profile",
+					Usage: "compiling with `PROFILE` (default: \"" + language.ProfileRelease + "\")",
+				},
+				cli.IntFlag{
+					Name:  "repeat, n",
+					Value: 1,
+					Usage: "running each case `N` times",
+				},
 			},
 		},
 		{
 			Name:      "dl",
 			Aliases:   []string{"d"},
 			Usage:     "Downloads samplecases of the problem",
-			UsageText: "dl [problem page url]",
+			UsageText: "dl [problem page url] [command options]",
 			Action:    cmdDl,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "retry",
+					Usage: "Retry downloading the problems that failed in the last contest download",
+				},
+				cli.BoolFlag{
+					Name:  "wait",
+					Usage: "waiting for the contest to start and downloading all problems",
+				},
+				cli.BoolFlag{
+					Name:  "new",
+					Usage: "creating a directory for each problem like `new` (with --wait)",
+				},
+				cli.StringFlag{
+					Name:  "language, l",
+					Value: defaultLangName,
+					Usage: "creating source files of `LANGUAGE` (with --new)",
+				},
+				cli.StringFlag{
+					Name:  "dir",
+					Value: ".",
+					Usage: "creating the contest directory in `DIR` (with --new)",
+				},
+			},
+		},
+		{
+			Name:      "new",
+			Aliases:   []string{"n"},
+			Usage:     "Downloads the contest and creates a directory for each problem",
+			UsageText: "new [contest page url] [command options]",
+			Action:    cmdNew,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "language, l",
+					Value: defaultLangName,
+					Usage: "creating source files of `LANGUAGE`",
+				},
+				cli.StringFlag{
+					Name:  "dir",
+					Value: ".",
+					Usage: "creating the contest directory in `DIR`",
+				},
+				cli.BoolFlag{
+					Name:  "wait",
+					Usage: "waiting for the contest to start",
+				},
+			},
 		},
 		{
 			Name:      "submit",
@@ -241,13 +692,150 @@ func main() {
 					Value: defaultLangName,
 					Usage: "submit as `LANGUAGE`",
 				},
+				cli.StringFlag{
+					Name:  "profile",
+					Usage: "choosing the language id of the judge mapped to `PROFILE`",
+				},
+			},
+		},
+		{
+			Name:      "view",
+			Aliases:   []string{"v"},
+			Usage:     "Shows problems",
+			UsageText: "view [problem id] [command options]",
+			Action:    cmdView,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "judges",
+					Usage: "showing the supported online judges",
+				},
+				cli.StringFlag{
+					Name:  "oj",
+					Usage: "showing only the problems of the online judge",
+				},
+				cli.StringFlag{
+					Name:  "contest",
+					Usage: "showing only the problems whose contest id contains the string",
+				},
+				cli.BoolFlag{
+					Name:  "unsolved",
+					Usage: "showing only the problems which have not been accepted",
+				},
+				cli.StringFlag{
+					Name:  "search",
+					Usage: "showing only the problems whose id, name, tags or url contain all the words",
+				},
+				cli.StringFlag{
+					Name:  "sort",
+					Value: "id",
+					Usage: "sort key (" + strings.Join(online_judge.IndexSortKeys, ", ") + ")",
+				},
+				cli.BoolFlag{
+					Name:  "reverse, r",
+					Usage: "sorting in reverse order",
+				},
+			},
+		},
+		{
+			Name:      "statement",
+			Usage:     "Shows the problem statement saved by `dl`",
+			UsageText: "statement [problem id] [command options]",
+			Action:    cmdStatement,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "lang",
+					Value: "ja",
+					Usage: "language of the statement (`ja` or `en`) for the judges which have both",
+				},
+			},
+		},
+		{
+			Name:  "problem",
+			Usage: "Manages the saved problems",
+			Subcommands: []cli.Command{
+				{
+					Name:      "rm",
+					Usage:     "Removes the problems",
+					UsageText: "problem rm [problem id...]",
+					Action:    cmdProblemRm,
+				},
+				{
+					Name:      "mv",
+					Usage:     "Changes the contest id and the problem id of the problem",
+					UsageText: "problem mv [problem id] [[contest id/]new problem id]",
+					Action:    cmdProblemMv,
+				},
+				{
+					Name:      "refresh",
+					Usage:     "Downloads the problems again keeping the test cases you added",
+					UsageText: "problem refresh [problem id...]",
+					Action:    cmdProblemRefresh,
+				},
+				{
+					Name:      "prune",
+					Usage:     "Removes old problems or the problems of finished contests",
+					UsageText: "problem prune [command options]",
+					Action:    cmdProblemPrune,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "older-than",
+							Usage: "removing the problems downloaded more than `DAYS` days ago",
+						},
+						cli.BoolFlag{
+							Name:  "finished",
+							Usage: "removing the problems of finished contests",
+						},
+						cli.BoolFlag{
+							Name:  "yes, y",
+							Usage: "removing without confirmation",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:      "history",
+			Usage:     "Shows your submissions recorded by `submit`",
+			UsageText: "history [problem id] [command options]",
+			Action:    cmdHistory,
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "limit, n",
+					Usage: "showing only the last `N` submissions",
+				},
+			},
+			Subcommands: []cli.Command{
+				{
+					Name:      "show",
+					Usage:     "Shows the source code and the result of the submission",
+					UsageText: "history show [submission number]",
+					Action:    cmdHistoryShow,
+				},
 			},
 		},
 		{
-			Name:    "view",
-			Aliases: []string{"v"},
-			Usage:   "Shows problems",
-			Action:  cmdView,
+			Name:      "stats",
+			Usage:     "Shows the statistics of your submissions recorded by `submit`",
+			UsageText: "stats [command options]",
+			Action:    cmdStats,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "json",
+					Usage: "printing the statistics as JSON",
+				},
+			},
+		},
+		{
+			Name:      "langs",
+			Usage:     "Shows languages available on the online judge",
+			UsageText: "langs [online judge name] [command options]",
+			Action:    cmdLangs,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "refresh",
+					Usage: "ignoring the cached list",
+				},
+			},
 		},
 		{
 			Name:      "processer",
@@ -263,11 +851,49 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:      "status",
+			Aliases:   []string{"st"},
+			Usage:     "Shows your submissions of the contest and waits for the judge",
+			UsageText: "status [contest]",
+			Action:    cmdStatus,
+		},
 		{
 			Name:      "cf-mysubmissions",
-			Usage:     "Shows mysubmissions of codeforces contest",
+			Usage:     "Shows mysubmissions of codeforces contest (deprecated: use status)",
 			UsageText: "cf-mysubmissions [contest id]",
 			Action:    cmdCodeforcesMySubmissionsViewer,
+			Hidden:    true,
+		},
+		{
+			Name:      "login",
+			Usage:     "Logs in to the online judge again (asks the password again)",
+			UsageText: "login [online judge name]",
+			Action:    cmdLogin,
+		},
+		{
+			Name:      "logout",
+			Usage:     "Removes the saved session of the online judge",
+			UsageText: "logout [online judge name] [command options]",
+			Action:    cmdLogout,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "forget",
+					Usage: "also removing the saved password",
+				},
+			},
+		},
+		{
+			Name:      "whoami",
+			Usage:     "Shows the account and the session of each online judge",
+			UsageText: "whoami [command options]",
+			Action:    cmdWhoami,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "check",
+					Usage: "checking whether the sessions are still accepted",
+				},
+			},
 		},
 		{
 			Name:   "snippet",
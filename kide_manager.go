@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/algon-320/KIDE/language"
 	"github.com/algon-320/KIDE/online_judge"
@@ -28,7 +29,7 @@ func downloadSampleCase(problemURL string) error {
 	return nil
 }
 
-func run(lang language.Language) error {
+func run(lang language.Language, profile string) error {
 	filename, err := language.FindSourceCode(lang)
 	if err != nil {
 		return err
@@ -36,7 +37,7 @@ func run(lang language.Language) error {
 
 	util.DebugPrint(fmt.Sprintf("running --> %s", filename))
 
-	_, err = lang.Run(filename, "", true) // 結果を表示しながら実行
+	_, err = lang.Run(filename, profile, "", true) // 結果を表示しながら実行
 	if err != nil {
 		return err
 	}
@@ -44,7 +45,8 @@ func run(lang language.Language) error {
 }
 
 // caseID : 負ならすべてのサンプルケースをテスト
-func tester(lang language.Language, problemID string, caseID int) error {
+// profile : コンパイル・実行に使うプロファイル
+func tester(lang language.Language, problemID string, caseID int, profile string) error {
 	fd := int(os.Stdout.Fd())
 	termWidth, _, err := terminal.GetSize(fd)
 	if err != nil {
@@ -65,7 +67,7 @@ func tester(lang language.Language, problemID string, caseID int) error {
 		// すべてのサンプルケースをテスト
 		samplePassed := true
 		for _, c := range p.Cases {
-			out, err := lang.Run(filename, c.Input, false) // 画面出力しないで実行
+			out, err := lang.Run(filename, profile, c.Input, false) // 画面出力しないで実行
 			if err != nil {
 				return err
 			}
@@ -84,9 +86,12 @@ func tester(lang language.Language, problemID string, caseID int) error {
 				samplePassed = false
 			}
 		}
+		if err := p.RecordLocalVerdict(samplePassed); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
 		if samplePassed {
 			fmt.Println(util.ESCS_COL_GREEN_B + "Samplecases passed" + util.ESCS_COL_OFF)
-			return submit(filename, lang, p) // 確認して提出
+			return submit(filename, lang, profile, p) // 確認して提出
 		}
 	} else if 0 < caseID && caseID <= len(p.Cases) {
 		c := p.Cases[caseID-1]
@@ -94,7 +99,7 @@ func tester(lang language.Language, problemID string, caseID int) error {
 		util.PrintTitle(termWidth, 4, "=", "input")
 		fmt.Print(c.Input)
 		util.PrintTitle(termWidth, 4, "=", "output")
-		out, err := lang.Run(filename, c.Input, true) // 画面出力しながら実行
+		out, err := lang.Run(filename, profile, c.Input, true) // 画面出力しながら実行
 		fmt.Println(strings.Repeat("=", termWidth))
 
 		if err != nil {
@@ -117,7 +122,63 @@ func tester(lang language.Language, problemID string, caseID int) error {
 	return nil
 }
 
-func submit(souceFilename string, lang language.Language, p *online_judge.Problem) error {
+// bench ... サンプルケースを repeat 回ずつ実行して実行時間を計測する
+// profile : コンパイル・実行に使うプロファイル
+func bench(lang language.Language, problemID string, profile string, repeat int) error {
+	filename, err := language.FindSourceCode(lang)
+	if err != nil {
+		return err
+	}
+
+	p, err := online_judge.LoadProblem(problemID)
+	if err != nil {
+		return err
+	}
+
+	// コンパイル時間を含めないように先にコンパイルしておく
+	if err := lang.Compile(filename, profile); err != nil {
+		return err
+	}
+
+	if repeat < 1 {
+		repeat = 1
+	}
+
+	title := []string{"case", "result", "time (avg)", "time (max)"}
+	data := [][]string{}
+	for i, c := range p.Cases {
+		var total, max time.Duration
+		result := "OK"
+		for k := 0; k < repeat; k++ {
+			start := time.Now()
+			out, err := lang.Run(filename, profile, c.Input, false) // 画面出力しないで実行
+			elapsed := time.Since(start)
+			if err != nil {
+				result = "RE"
+			} else if out != c.Output && result == "OK" {
+				result = "WA"
+			}
+
+			total += elapsed
+			if max < elapsed {
+				max = elapsed
+			}
+		}
+		avg := total / time.Duration(repeat)
+		data = append(data, []string{
+			fmt.Sprintf("%d", i+1),
+			result,
+			fmt.Sprintf("%d ms", avg/time.Millisecond),
+			fmt.Sprintf("%d ms", max/time.Millisecond),
+		})
+	}
+
+	util.PrintTable(title, data, true)
+	return nil
+}
+
+// profile : 提出する言語IDを選ぶのに使うプロファイル
+func submit(souceFilename string, lang language.Language, profile string, p *online_judge.Problem) error {
 	fmt.Printf("Do you really submit the solution `%s` to problem `%s` ?\n", souceFilename, p.Name)
 	yes := util.AskYesNo()
 	if !yes {
@@ -132,19 +193,37 @@ func submit(souceFilename string, lang language.Language, p *online_judge.Proble
 	}
 	sourceCodeStr := string(sourceCodeBytes)
 
+	// bundle
+	sourceCodeStr, err = bundleSource(sourceCodeStr, lang)
+	if err != nil {
+		return err
+	}
+
 	// process
 	sourceCodeStr = processSource(sourceCodeStr)
 
+	// rename the main class
+	sourceCodeStr, err = renameMainClass(sourceCodeStr, lang, p.Oj)
+	if err != nil {
+		return err
+	}
+
 	if sourceCodeStr == "" {
 		fmt.Println(util.PrefixInfo + "Submit cancelled.")
 		return nil
 	}
 
-	res, err := p.Oj.Submit(p, sourceCodeStr, lang)
+	res, err := p.Oj.Submit(p, sourceCodeStr, lang, profile)
 	if err != nil {
 		return err
 	}
 	res.Print() // ジャッジ結果を表示
+	if err := p.RecordJudgeVerdict(res.Status, res.Date); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if _, err := online_judge.RecordSubmission(res, profile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
 
 	// 保存
 	var saveSourceFileAfterAccepted bool
@@ -175,6 +254,23 @@ func submit(souceFilename string, lang language.Language, p *online_judge.Proble
 	return nil
 }
 
+// showLanguages ... oj で選択できる言語の一覧と、設定で対応付けられている言語を表示する
+func showLanguages(oj online_judge.OnlineJudge, refresh bool) error {
+	langs, err := oj.Languages(refresh)
+	if err != nil {
+		return err
+	}
+	mappings := oj.LanguageMappings()
+
+	title := []string{"id", "name", "mapped"}
+	data := [][]string{}
+	for _, l := range langs {
+		data = append(data, []string{l.ID, l.Name, strings.Join(mappings[l.ID], ", ")})
+	}
+	util.PrintTable(title, data, true)
+	return nil
+}
+
 // ソースコードを General.SaveSourceFileDirectory 以下に保存する
 func saveSourceFile(sourceFilename string, sourceCode []byte, p *online_judge.Problem) error {
 	exeDir, _ := os.Executable()
@@ -208,30 +304,55 @@ func saveSourceFile(sourceFilename string, sourceCode []byte, p *online_judge.Pr
 		setting.Set("General.SaveSourceFileDirectory", saveSourceFileDir)
 	}
 
-	// 現在のディレクトリを保持しておいて、抜けるときに復元する
-	prev, _ := os.Getwd()
-	defer os.Chdir(prev)
-
 	expanded := strings.Replace(saveSourceFileDir, "{EXE_DIR}", exeDir, 1)
-	os.Chdir(expanded)
-
-	if !util.FileExists(p.Oj.Name()) {
-		if err := os.Mkdir(p.Oj.Name(), 0775); err != nil {
+	ojDir, _ := filepath.Abs(filepath.Join(expanded, p.Oj.Name()))
+	if !util.FileExists(ojDir) {
+		if err := os.MkdirAll(ojDir, 0775); err != nil {
 			return fmt.Errorf(util.PrefixError+"%s", err)
 		}
-		absPath, _ := filepath.Abs(p.Oj.Name())
-		fmt.Println(fmt.Sprintf(util.PrefixInfo+"Created directory `%s`", absPath))
+		fmt.Println(fmt.Sprintf(util.PrefixInfo+"Created directory `%s`", ojDir))
 	}
-	os.Chdir(p.Oj.Name())
 
-	if err := ioutil.WriteFile(sourceFilename, sourceCode, 0644); err != nil {
+	path := filepath.Join(ojDir, sourceFilename)
+	if err := util.WriteFileAtomic(path, sourceCode, 0644); err != nil {
 		return fmt.Errorf(util.PrefixError+"%s", err)
 	}
 
-	fmt.Println(util.PrefixInfo + fmt.Sprintf("Saved the source file as `%s`", sourceFilename))
+	fmt.Println(util.PrefixInfo + fmt.Sprintf("Saved the source file as `%s`", path))
 	return nil
 }
 
+// ライブラリなどを埋め込んで提出用の1つのソースコードにまとめる
+// 言語が language.Bundler を実装していない場合はそのまま返す
+func bundleSource(sourceCode string, lang language.Language) (string, error) {
+	if b, ok := lang.(language.Bundler); ok {
+		return b.Bundle(sourceCode)
+	}
+	return sourceCode, nil
+}
+
+// メインクラスの名前を oj が要求するものに変更する
+// Language.{言語名}.RenameMainClass が false の場合や、言語が language.MainClassRenamer を実装していない場合はそのまま返す
+func renameMainClass(sourceCode string, lang language.Language, oj online_judge.OnlineJudge) (string, error) {
+	r, ok := lang.(language.MainClassRenamer)
+	if !ok || sourceCode == "" {
+		return sourceCode, nil
+	}
+
+	rename := true
+	if tmp, ok := setting.Get("Language."+lang.Name()+".RenameMainClass", ""); ok {
+		rename = tmp.(bool)
+	} else {
+		setting.Set("Language."+lang.Name()+".RenameMainClass", rename)
+	}
+
+	className := online_judge.MainClassName(oj)
+	if !rename || className == "" {
+		return sourceCode, nil
+	}
+	return r.RenameMainClass(sourceCode, className)
+}
+
 // ソースコードを整形する
 // 設定で実行コマンドが指定されていた場合に、標準入力にソースコードを投げ、標準出力から読み取ったものを返す
 // {EXE_DIR}を実行ファイルのあるディレクトリのパスとして使える
@@ -6,23 +6,49 @@ import "github.com/algon-320/KIDE/setting"
 var CPP Language
 
 const (
-	defaultCompileCommandCPP = "g++ -std=c++11 -o a.out {SOURCEFILE_PATH}"
-	defaultRunningCommandCPP = "./a.out"
+	defaultCompileCommandCPP = "g++ -std=c++11 -o {OUTPUT_DIR}/a.out {SOURCEFILE_PATH}"
+	defaultRunningCommandCPP = "{OUTPUT_DIR}/a.out"
+
+	// 以前のデフォルト (プロファイルごとに実行ファイルを分けられない)
+	oldCompileCommandCPP = "g++ -std=c++11 -o a.out {SOURCEFILE_PATH}"
+	oldRunningCommandCPP = "./a.out"
 )
 
+// defaultProfilesCPP ... デフォルトのプロファイル
+var defaultProfilesCPP = map[string]interface{}{
+	ProfileDebug: map[string]interface{}{
+		"CompileCommand": "g++ -std=c++11 -g -fsanitize=address,undefined -D_GLIBCXX_DEBUG -DLOCAL -o {OUTPUT_DIR}/a.out {SOURCEFILE_PATH}",
+		"RunningCommand": "{OUTPUT_DIR}/a.out",
+	},
+	ProfileRelease: map[string]interface{}{
+		"CompileCommand": "g++ -std=c++11 -O2 -o {OUTPUT_DIR}/a.out {SOURCEFILE_PATH}",
+		"RunningCommand": "{OUTPUT_DIR}/a.out",
+	},
+}
+
 func init() {
 	var compileCmd, runningCmd string
 	if v, ok := setting.Get("Language.C++.CompileCommand", ""); ok {
 		compileCmd = v.(string)
 	} else {
 		compileCmd = defaultCompileCommandCPP
-		setting.Set("Language.C++.CompileCommand", compileCmd)
+		setting.SetDefault("Language.C++.CompileCommand", compileCmd)
 	}
 	if v, ok := setting.Get("Language.C++.RunningCommand", ""); ok {
 		runningCmd = v.(string)
 	} else {
 		runningCmd = defaultRunningCommandCPP
-		setting.Set("Language.C++.RunningCommand", runningCmd)
+		setting.SetDefault("Language.C++.RunningCommand", runningCmd)
+	}
+	// 以前のデフォルトのままなら新しいデフォルトを使う (設定ファイルは書き換えない)
+	if compileCmd == oldCompileCommandCPP && runningCmd == oldRunningCommandCPP {
+		compileCmd = defaultCompileCommandCPP
+		runningCmd = defaultRunningCommandCPP
+	}
+	// コンパイルコマンドを変更している場合はデフォルトのプロファイルを使わない
+	// (プロファイルが無ければ tester や bench でも変更したコマンドが使われる)
+	if _, ok := setting.Get("Language.C++.Profiles", ""); !ok && compileCmd == defaultCompileCommandCPP {
+		setting.SetDefault("Language.C++.Profiles", defaultProfilesCPP)
 	}
 
 	CPP = &languageBase{
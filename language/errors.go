@@ -35,3 +35,27 @@ type ErrNoSourceCode struct {
 func (e ErrNoSourceCode) Error() string {
 	return util.PrefixError + fmt.Sprintf("No %s source file found.", e.name)
 }
+
+type ErrFailedToBundle struct {
+	message string
+}
+
+func (e ErrFailedToBundle) Error() string {
+	return util.PrefixError + fmt.Sprintf("Failed to bundle the source file : %s", e.message)
+}
+
+type ErrNoMainClass struct {
+}
+
+func (e ErrNoMainClass) Error() string {
+	return util.PrefixError + fmt.Sprintf("No class with the main method found.")
+}
+
+type ErrNoSuchProfile struct {
+	lang    string
+	profile string
+}
+
+func (e ErrNoSuchProfile) Error() string {
+	return util.PrefixError + fmt.Sprintf("No such profile `%s` of %s.", e.profile, e.lang)
+}
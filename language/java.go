@@ -1,13 +1,25 @@
 package language
 
-import "github.com/algon-320/KIDE/setting"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/algon-320/KIDE/setting"
+	"github.com/algon-320/KIDE/util"
+)
 
 // JAVA ... Java
 var JAVA Language
 
 const (
-	defaultCompileCommandJAVA = "javac {SOURCEFILE_PATH}"
-	defaultRunningCommandJAVA = "java Main"
+	defaultCompileCommandJAVA = "javac -d {OUTPUT_DIR} {SOURCEFILE_PATH}"
+	defaultRunningCommandJAVA = "java -cp {OUTPUT_DIR} {MAIN_CLASS}"
+
+	// 以前のデフォルト (作業ディレクトリにクラスファイルが出来てしまう)
+	oldCompileCommandJAVA = "javac {SOURCEFILE_PATH}"
+	oldRunningCommandJAVA = "java Main"
 )
 
 func init() {
@@ -16,25 +28,267 @@ func init() {
 		compileCmd = v.(string)
 	} else {
 		compileCmd = defaultCompileCommandJAVA
-		setting.Set("Language.Java.CompileCommand", compileCmd)
+		setting.SetDefault("Language.Java.CompileCommand", compileCmd)
 	}
 	if v, ok := setting.Get("Language.Java.RunningCommand", ""); ok {
 		runningCmd = v.(string)
 	} else {
 		runningCmd = defaultRunningCommandJAVA
-		setting.Set("Language.Java.RunningCommand", runningCmd)
+		setting.SetDefault("Language.Java.RunningCommand", runningCmd)
+	}
+	// 以前のデフォルトのままなら新しいデフォルトを使う (設定ファイルは書き換えない)
+	if compileCmd == oldCompileCommandJAVA && runningCmd == oldRunningCommandJAVA {
+		compileCmd = defaultCompileCommandJAVA
+		runningCmd = defaultRunningCommandJAVA
 	}
 
-	JAVA = &languageBase{
-		name:           "Java",
-		fileExtension:  ".java",
-		compileCommand: defaultCompileCommandJAVA,
-		runningCommand: defaultRunningCommandJAVA,
-		commentBegin:   "// ",
-		commentEnd:     "",
+	JAVA = &java{
+		languageBase: languageBase{
+			name:           "Java",
+			fileExtension:  ".java",
+			compileCommand: compileCmd,
+			runningCommand: runningCmd,
+			commentBegin:   "// ",
+			commentEnd:     "",
+		},
 	}
 }
 
 type java struct {
 	languageBase
 }
+
+// javaToken ... Java のソースコードの字句 (コメントは含まない)
+type javaToken struct {
+	text       string
+	start, end int  // source 中の位置
+	ident      bool // 識別子またはキーワード
+	literal    bool // 文字列・文字・数値リテラル
+}
+
+// tokenizeJava ... source を字句に分ける (コメントは読み飛ばす)
+func tokenizeJava(source string) []javaToken {
+	isIdentStart := func(c byte) bool {
+		return c == '_' || c == '$' || c >= 0x80 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+	}
+	isIdentPart := func(c byte) bool {
+		return isIdentStart(c) || ('0' <= c && c <= '9')
+	}
+
+	tokens := []javaToken{}
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case strings.HasPrefix(source[i:], "//"):
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(source[i:], "/*"):
+			end := strings.Index(source[i+2:], "*/")
+			if end < 0 {
+				i = len(source)
+			} else {
+				i += end + 4
+			}
+		case c == '"' || c == '\'':
+			start := i
+			if strings.HasPrefix(source[i:], `"""`) { // テキストブロック
+				end := strings.Index(source[i+3:], `"""`)
+				if end < 0 {
+					i = len(source)
+				} else {
+					i += end + 6
+				}
+			} else {
+				for i++; i < len(source) && source[i] != c && source[i] != '\n'; i++ {
+					if source[i] == '\\' {
+						i++
+					}
+				}
+				i++
+			}
+			if i > len(source) {
+				i = len(source)
+			}
+			tokens = append(tokens, javaToken{text: source[start:i], start: start, end: i, literal: true})
+		case isIdentStart(c):
+			start := i
+			for i < len(source) && isIdentPart(source[i]) {
+				i++
+			}
+			tokens = append(tokens, javaToken{text: source[start:i], start: start, end: i, ident: true})
+		case '0' <= c && c <= '9':
+			start := i
+			for i < len(source) && (isIdentPart(source[i]) || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, javaToken{text: source[start:i], start: start, end: i, literal: true})
+		case strings.HasPrefix(source[i:], "::"):
+			tokens = append(tokens, javaToken{text: "::", start: i, end: i + 2})
+			i += 2
+		default:
+			tokens = append(tokens, javaToken{text: source[i : i+1], start: i, end: i + 1})
+			i++
+		}
+	}
+	return tokens
+}
+
+// MainClass ... source の中で main メソッドを持つトップレベルのクラスの名前を返す
+func (l *java) MainClass(source string) (string, error) {
+	tokens := tokenizeJava(source)
+	text := func(i int) string {
+		if i < 0 || i >= len(tokens) {
+			return ""
+		}
+		return tokens[i].text
+	}
+
+	type scope struct {
+		name  string // クラスの名前 (クラス以外のブロックは空)
+		depth int    // クラスの本体の深さ
+	}
+	classes := []scope{} // 今いるクラス (外側から順)
+	pending := ""        // 宣言されて本体の `{` をまだ読んでいないクラス
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.ident && (t.text == "class" || t.text == "interface" || t.text == "enum") && text(i-1) != "." && i+1 < len(tokens) && tokens[i+1].ident:
+			pending = text(i + 1)
+		case t.text == "{":
+			depth++
+			if pending != "" {
+				classes = append(classes, scope{name: pending, depth: depth})
+				pending = ""
+			}
+		case t.text == "}":
+			if n := len(classes); n > 0 && classes[n-1].depth == depth {
+				classes = classes[:n-1]
+			}
+			depth--
+		case t.text == "void" && text(i+1) == "main" && text(i+2) == "(" && len(classes) > 0:
+			args := text(i + 3)
+			if args == "final" {
+				args = text(i + 4)
+			}
+			if args == "String" {
+				return classes[0].name, nil
+			}
+		}
+	}
+	return "", &ErrNoMainClass{}
+}
+
+// javaModifiers ... コンストラクタの宣言の前に来るもの
+var javaModifiers = map[string]bool{"public": true, "protected": true, "private": true, "{": true, "}": true, ";": true}
+
+// isJavaClassReference ... tokens[i] (クラス名と同じ識別子) がクラスの宣言、型、コンストラクタとして使われているか
+// (同じ名前の変数やメソッドは置き換えない)
+func isJavaClassReference(tokens []javaToken, i int) bool {
+	text := func(i int) string {
+		if i < 0 || i >= len(tokens) {
+			return ""
+		}
+		return tokens[i].text
+	}
+	isValue := func(i int) bool {
+		return i < len(tokens) && (tokens[i].ident || tokens[i].literal || tokens[i].text == "(")
+	}
+	prev, next := text(i-1), text(i+1)
+	switch {
+	case prev == "class" || prev == "new" || prev == "extends" || prev == "implements" || prev == "instanceof" || prev == "throws":
+		return true // 宣言、コンストラクタの呼び出しなど
+	case i+1 < len(tokens) && tokens[i+1].ident:
+		return true // `A a`、`A solve(...)`
+	case next == "[" && text(i+2) == "]":
+		return true // `A[] a`
+	case next == "." || next == "::":
+		return true // `A.solve()`、`A::new`
+	case prev == "<" || next == ">" || (prev == "," && next == ","):
+		return true // `List<A>`、`Map<String, A>`
+	case next == "(" && javaModifiers[prev]:
+		return true // コンストラクタの宣言
+	case prev == "(" && next == ")" && isValue(i+2):
+		return true // キャスト
+	}
+	return false
+}
+
+// RenameMainClass ... main メソッドを持つクラスの名前を className に変更したソースコードを返す
+// クラスの宣言と、型・コンストラクタとして使われている所だけを置き換える (文字列、コメント、同じ名前の変数はそのまま)
+func (l *java) RenameMainClass(source string, className string) (string, error) {
+	name, err := l.MainClass(source)
+	if err != nil {
+		return "", err
+	}
+	if name == className {
+		return source, nil
+	}
+	util.DebugPrint("rename main class : " + name + " -> " + className)
+
+	var buf strings.Builder
+	last := 0
+	tokens := tokenizeJava(source)
+	for i, t := range tokens {
+		if t.ident && t.text == name && isJavaClassReference(tokens, i) {
+			buf.WriteString(source[last:t.start])
+			buf.WriteString(className)
+			last = t.end
+		}
+	}
+	buf.WriteString(source[last:])
+	return buf.String(), nil
+}
+
+// prepare ... ソースファイルを出力先に `{クラス名}.java` としてコピーし、コマンドのプレースホルダを置換する Replacer を返す
+// {SOURCEFILE_PATH} はコピーしたソースファイルのパスに置換される
+func (l *java) prepare(sourcePath string, profile string) (*strings.Replacer, error) {
+	source, err := ioutil.ReadFile(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	mainClass, err := l.MainClass(string(source))
+	if err != nil {
+		return nil, err
+	}
+
+	outDir := l.buildDir(sourcePath, profile)
+	srcDir := filepath.Join(outDir, "src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		return nil, err
+	}
+	copied := filepath.Join(srcDir, mainClass+l.fileExtension)
+	if err := ioutil.WriteFile(copied, source, 0644); err != nil {
+		return nil, err
+	}
+
+	return strings.NewReplacer(
+		"{SOURCEFILE_PATH}", copied,
+		"{OUTPUT_DIR}", outDir,
+		"{MAIN_CLASS}", mainClass,
+	), nil
+}
+
+// Compile ... main メソッドを持つクラスを探し、ソースファイル・プロファイルごとの出力先にコンパイルする
+// ソースファイルは `{クラス名}.java` としてコピーしてからコンパイルするので、ファイル名は何でもよい
+func (l *java) Compile(sourcePath string, profile string) error {
+	r, err := l.prepare(sourcePath, profile)
+	if err != nil {
+		return err
+	}
+	return l.compile(sourcePath, profile, r)
+}
+
+// Run ... コンパイルして main メソッドを持つクラスを実行する
+func (l *java) Run(sourcePath string, profile string, input string, print bool) (string, error) {
+	r, err := l.prepare(sourcePath, profile)
+	if err != nil {
+		return "", err
+	}
+	if err := l.compile(sourcePath, profile, r); err != nil {
+		return "", err
+	}
+	return l.execute(profile, r, input, print)
+}
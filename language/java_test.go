@@ -0,0 +1,108 @@
+package language
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestJavaMainClass(t *testing.T) {
+	fmt.Println("testing : java.go > MainClass")
+
+	l := JAVA.(*java)
+	testcase := map[string]string{
+		"public class Main {\n\tpublic static void main(String[] args) {}\n}\n": "Main",
+		// main より前にネストしたクラスがある
+		`import java.util.*;
+public class Main {
+	static class Pair {
+		int a, b;
+	}
+	public static void main(final String... args) {}
+}`: "Main",
+		// コメントや文字列の中の class は無視する
+		`// class Dummy { void main(String[] a) }
+class Helper { int x; }
+/* class Other */
+final class Solution {
+	static String s = "class Fake";
+	static class Inner { }
+	public static void main(String[] args) { System.out.println(Helper.class); }
+}`: "Solution",
+	}
+	for source, expect := range testcase {
+		got, err := l.MainClass(source)
+		if err != nil || got != expect {
+			t.Errorf("MainClass returned %q, %v, expected %q\n%s", got, err, expect, source)
+		}
+	}
+
+	if _, err := l.MainClass("class A { void run() {} }"); err == nil {
+		t.Error("main メソッドの無いソースコードでエラーになりません")
+	}
+}
+
+func TestJavaRenameMainClass(t *testing.T) {
+	fmt.Println("testing : java.go > RenameMainClass")
+
+	l := JAVA.(*java)
+	source := `import java.util.*;
+
+// A is the main class
+public class A {
+	static class Pair { int a; }
+	static A instance = new A();
+	private A() {}
+	A(int x) {}
+
+	public static void main(String[] args) {
+		int A = 1;
+		String s = "A";
+		char c = 'A';
+		A[] arr = new A[A];
+		List<A> list = new ArrayList<>();
+		Map<String, A> m = new HashMap<String, A>();
+		Object o = (A) instance;
+		A.solve(A);
+		Runnable r = A::solve0;
+	}
+	static void solve(int x) {}
+	static void solve0() {}
+}
+`
+	expect := `import java.util.*;
+
+// A is the main class
+public class Main {
+	static class Pair { int a; }
+	static Main instance = new Main();
+	private Main() {}
+	Main(int x) {}
+
+	public static void main(String[] args) {
+		int A = 1;
+		String s = "A";
+		char c = 'A';
+		Main[] arr = new Main[A];
+		List<Main> list = new ArrayList<>();
+		Map<String, Main> m = new HashMap<String, Main>();
+		Object o = (Main) instance;
+		Main.solve(A);
+		Runnable r = Main::solve0;
+	}
+	static void solve(int x) {}
+	static void solve0() {}
+}
+`
+	got, err := l.RenameMainClass(source, "Main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != expect {
+		t.Errorf("RenameMainClass returned\n%s", got)
+	}
+
+	// 既に同じ名前ならそのまま
+	if got, err := l.RenameMainClass(expect, "Main"); err != nil || got != expect {
+		t.Errorf("RenameMainClass changed the source : %v", err)
+	}
+}
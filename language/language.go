@@ -15,11 +15,32 @@ type Language interface {
 	Name() string
 	String() string
 	FileExtension() string
-	Run(sourcePath string, input string, print bool) (string, error)
+	Profiles() []string
+	Compile(sourcePath string, profile string) error
+	Run(sourcePath string, profile string, input string, print bool) (string, error)
 	CommentOut(line string) string
 	UnComment(line string) string
 }
 
+const (
+	// ProfileDebug ... テスト時に使うプロファイル名
+	ProfileDebug = "debug"
+	// ProfileRelease ... 実行時間を計測する時に使うプロファイル名
+	ProfileRelease = "release"
+)
+
+// Bundler ... 提出用に1つのソースファイルにまとめる処理を持つ言語が実装するインターフェース
+type Bundler interface {
+	// Bundle ... ソースコード source を提出用にまとめたものを返す
+	Bundle(source string) (string, error)
+}
+
+// MainClassRenamer ... メインクラスの名前を変更出来る言語が実装するインターフェース
+type MainClassRenamer interface {
+	// RenameMainClass ... source のメインクラスの名前を className に変更したものを返す
+	RenameMainClass(source string, className string) (string, error)
+}
+
 var languageList = []*Language{
 	&CPP,
 	&PYTHON2,
@@ -50,6 +71,16 @@ func GetLanguage(name string) Language {
 	return GetLanguage(defaultLangName)
 }
 
+// CheckProfile ... lang に profile という名前のプロファイルが設定されているか確認する
+func CheckProfile(lang Language, profile string) error {
+	for _, p := range lang.Profiles() {
+		if p == profile {
+			return nil
+		}
+	}
+	return &ErrNoSuchProfile{lang: lang.Name(), profile: profile}
+}
+
 // FindSourceCode ... 実行する対象のソースコードを決めて、ファイル名を返す(複数ある場合はユーザに問う)
 func FindSourceCode(lang Language) (string, error) {
 	files, err := ioutil.ReadDir(".")
@@ -9,8 +9,10 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 
+	"github.com/algon-320/KIDE/setting"
 	"github.com/algon-320/KIDE/util"
 )
 
@@ -18,8 +20,8 @@ import (
 type languageBase struct {
 	name           string
 	fileExtension  string
-	compileCommand string // {SOURCEFILE_PATH} の部分がすべてソースコードのパスに置換される
-	runningCommand string // {SOURCEFILE_PATH} の部分がすべてソースコードのパスに置換される
+	compileCommand string // {SOURCEFILE_PATH} の部分がソースコードのパスに、{OUTPUT_DIR} の部分がコンパイル結果の出力先に置換される
+	runningCommand string // {SOURCEFILE_PATH} の部分がソースコードのパスに、{OUTPUT_DIR} の部分がコンパイル結果の出力先に置換される
 	commentBegin   string
 	commentEnd     string
 }
@@ -49,9 +51,84 @@ func (l *languageBase) UnComment(commentedLine string) string {
 	return commentedLine[lenBegin : len(commentedLine)-lenEnd]
 }
 
-// compile ... sourcePath で与えられたパスのソースコードをコンパイルする(変更がない場合は何もしない)
-func (l *languageBase) compile(sourcePath string) error {
-	skip, err := checkSkipCompile(sourcePath) // 変更があるか確認
+// Profiles ... Language.{言語名}.Profiles に設定されたプロファイル名の一覧
+func (l *languageBase) Profiles() []string {
+	ret := []string{}
+	if v, ok := setting.Get("Language."+l.name+".Profiles", ""); ok {
+		if m, ok := v.(map[string]interface{}); ok {
+			for k := range m {
+				ret = append(ret, k)
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// commands ... profile のコンパイルコマンドと実行コマンドを返す
+// profile が空文字列の場合や設定されていない場合は、言語のデフォルトのコマンドを返す
+// プロファイルに実行コマンドが無い場合はデフォルトの実行コマンドを使う
+func (l *languageBase) commands(profile string) (string, string) {
+	if profile == "" {
+		return l.compileCommand, l.runningCommand
+	}
+	prefix := "Language." + l.name + ".Profiles." + profile
+	v, ok := setting.Get(prefix, "")
+	if !ok {
+		util.DebugPrint("profile `" + profile + "` is not defined. use the default commands.")
+		return l.compileCommand, l.runningCommand
+	}
+	compileCmd, runningCmd := l.compileCommand, l.runningCommand
+	if m, ok := v.(map[string]interface{}); ok {
+		if s, ok := m["CompileCommand"].(string); ok {
+			compileCmd = s
+		}
+		if s, ok := m["RunningCommand"].(string); ok {
+			runningCmd = s
+		}
+	}
+	return compileCmd, runningCmd
+}
+
+// buildDir ... sourcePath を profile でコンパイルした結果を置くディレクトリ (ソースファイル・プロファイルごとに別)
+func (l *languageBase) buildDir(sourcePath string, profile string) string {
+	abs, _ := filepath.Abs(sourcePath)
+	base := strings.TrimSuffix(filepath.Base(abs), l.fileExtension)
+	if profile == "" {
+		profile = "default"
+	}
+	return util.CachePath(buildCacheDir, l.name,
+		base+"_"+util.Sha256SumStr([]byte(abs))[:8], profile)
+}
+
+// replacer ... コンパイルコマンド・実行コマンドの {SOURCEFILE_PATH}, {OUTPUT_DIR} を置換する
+func (l *languageBase) replacer(sourcePath string, profile string) *strings.Replacer {
+	return strings.NewReplacer(
+		"{SOURCEFILE_PATH}", sourcePath,
+		"{OUTPUT_DIR}", l.buildDir(sourcePath, profile),
+	)
+}
+
+// Compile ... sourcePath で与えられたパスのソースコードを profile でコンパイルする(変更がない場合は何もしない)
+func (l *languageBase) Compile(sourcePath string, profile string) error {
+	return l.compile(sourcePath, profile, l.replacer(sourcePath, profile))
+}
+
+// compile ... Compile の本体
+// r: コンパイルコマンドのプレースホルダを置換する
+func (l *languageBase) compile(sourcePath string, profile string, r *strings.Replacer) error {
+	compileCmd, _ := l.commands(profile)
+	if compileCmd == "" {
+		return nil
+	}
+
+	outDir := l.buildDir(sourcePath, profile)
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	hashPath := filepath.Join(outDir, prevSourceHash)
+
+	skip, err := checkSkipCompile(sourcePath, compileCmd, hashPath) // 変更があるか確認
 	if err != nil {
 		return err
 	}
@@ -62,7 +139,7 @@ func (l *languageBase) compile(sourcePath string) error {
 
 	util.DebugPrint("Source file is changed. Compiling ...")
 
-	cmd := util.Command(strings.Replace(l.compileCommand, "{SOURCEFILE_PATH}", sourcePath, -1))
+	cmd := util.Command(r.Replace(compileCmd))
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -70,22 +147,29 @@ func (l *languageBase) compile(sourcePath string) error {
 		return &ErrCompileError{}
 	}
 	util.DebugPrint("Successfully compiled!")
-	return saveSourceHash(sourcePath)
+	return saveSourceHash(sourcePath, compileCmd, hashPath)
 }
 
 // Run ... 実行
+// profile : コンパイル・実行に使うプロファイル (空文字列ならデフォルト)
 // input : 標準入力として与える文字列
 // print : 標準出力、標準エラー出力を画面に出力するかどうか
 // return : 実行結果の標準出力, この関数のエラー
-func (l *languageBase) Run(sourcePath string, input string, print bool) (string, error) {
-	if l.compileCommand != "" {
-		if err := l.compile(sourcePath); err != nil {
-			return "", err
-		}
+func (l *languageBase) Run(sourcePath string, profile string, input string, print bool) (string, error) {
+	r := l.replacer(sourcePath, profile)
+	if err := l.compile(sourcePath, profile, r); err != nil {
+		return "", err
 	}
+	return l.execute(profile, r, input, print)
+}
+
+// execute ... 実行コマンドを実行する (コンパイルはしない)
+// r: 実行コマンドのプレースホルダを置換する
+func (l *languageBase) execute(profile string, r *strings.Replacer, input string, print bool) (string, error) {
+	_, runningCmd := l.commands(profile)
 
 	ret := new(bytes.Buffer)
-	cmd := util.Command(strings.Replace(l.runningCommand, "{SOURCEFILE_PATH}", sourcePath, -1))
+	cmd := util.Command(r.Replace(runningCmd))
 
 	var stdin io.Reader
 	var stdout io.Writer
@@ -117,13 +201,25 @@ func (l *languageBase) Run(sourcePath string, input string, print bool) (string,
 }
 
 // utility ---------------------------------------------------------------------
-const prevSourceHash = "previous.dat" // TODO : previous.dat の名前をsettingで変えられるようにするべき?
-func checkSkipCompile(sourcePath string) (bool, error) {
-	sourcePathAbs, _ := filepath.Abs(sourcePath)
-	edir, _ := os.Executable()
-	dir := filepath.Dir(edir)
-	prevHashPath := filepath.Join(dir, prevSourceHash)
+const (
+	// buildCacheDir ... util.CacheDir 以下のコンパイル結果の出力先
+	buildCacheDir = "build"
+	// prevSourceHash ... 前回コンパイルしたソースコードとコンパイルコマンドのハッシュ (出力先ごとに保存する)
+	prevSourceHash = "previous.dat"
+)
 
+// sourceHash ... ソースコードとコンパイルコマンドのハッシュ (フラグが変わった場合もコンパイルし直す)
+func sourceHash(sourcePath string, compileCmd string) ([]byte, error) {
+	sourcePathAbs, _ := filepath.Abs(sourcePath)
+	sourceBytes, err := ioutil.ReadFile(sourcePathAbs)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(append(append(sourceBytes, 0), compileCmd...))
+	return hash[:], nil
+}
+func checkSkipCompile(sourcePath string, compileCmd string, prevHashPath string) (bool, error) {
+	sourcePathAbs, _ := filepath.Abs(sourcePath)
 	if !util.FileExists(sourcePathAbs) {
 		return false, fmt.Errorf(util.PrefixError + "No such source file.")
 	}
@@ -133,28 +229,22 @@ func checkSkipCompile(sourcePath string) (bool, error) {
 		if err != nil {
 			return false, err
 		}
-		sourceBytes, err := ioutil.ReadFile(sourcePathAbs)
+		hash, err := sourceHash(sourcePath, compileCmd)
 		if err != nil {
 			return false, err
 		}
-		hash := sha256.Sum256(sourceBytes)
-		if reflect.DeepEqual(prevHash, hash[:]) {
+		if reflect.DeepEqual(prevHash, hash) {
 			return true, nil
 		}
 	}
 	return false, nil
 }
-func saveSourceHash(sourcePath string) error {
-	p, _ := os.Executable()
-	dir := filepath.Dir(p)
-
-	sourcePathAbs, _ := filepath.Abs(sourcePath)
-	sourceBytes, err := ioutil.ReadFile(sourcePathAbs)
+func saveSourceHash(sourcePath string, compileCmd string, prevHashPath string) error {
+	hash, err := sourceHash(sourcePath, compileCmd)
 	if err != nil {
 		return err
 	}
-	hash := sha256.Sum256(sourceBytes)
-	ioutil.WriteFile(filepath.Join(dir, prevSourceHash), hash[:], 0666)
-	fmt.Fprintln(os.Stderr, fmt.Sprintf(util.PrefixInfo+"Saved the hash of souce file to `%s`", prevSourceHash))
+	ioutil.WriteFile(prevHashPath, hash, 0666)
+	fmt.Fprintln(os.Stderr, fmt.Sprintf(util.PrefixInfo+"Saved the hash of souce file to `%s`", prevHashPath))
 	return nil
 }
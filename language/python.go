@@ -16,21 +16,23 @@ func init() {
 		compileCmd = v.(string)
 	} else {
 		compileCmd = defaultCompileCommandPYTHON
-		setting.Set("Language.Python2.CompileCommand", compileCmd)
+		setting.SetDefault("Language.Python2.CompileCommand", compileCmd)
 	}
 	if v, ok := setting.Get("Language.Python2.RunningCommand", ""); ok {
 		runningCmd = v.(string)
 	} else {
 		runningCmd = defaultRunningCommandPYTHON
-		setting.Set("Language.Python2.RunningCommand", runningCmd)
+		setting.SetDefault("Language.Python2.RunningCommand", runningCmd)
 	}
-	PYTHON2 = &languageBase{
-		name:           "Python2",
-		fileExtension:  ".py",
-		compileCommand: defaultCompileCommandPYTHON,
-		runningCommand: defaultRunningCommandPYTHON,
-		commentBegin:   "# ",
-		commentEnd:     "",
+	PYTHON2 = &python{
+		languageBase: languageBase{
+			name:           "Python2",
+			fileExtension:  ".py",
+			compileCommand: defaultCompileCommandPYTHON,
+			runningCommand: defaultRunningCommandPYTHON,
+			commentBegin:   "# ",
+			commentEnd:     "",
+		},
 	}
 
 	compileCmd = ""
@@ -39,24 +41,51 @@ func init() {
 		compileCmd = v.(string)
 	} else {
 		compileCmd = defaultCompileCommandPYTHON
-		setting.Set("Language.Python3.CompileCommand", compileCmd)
+		setting.SetDefault("Language.Python3.CompileCommand", compileCmd)
 	}
 	if v, ok := setting.Get("Language.Python3.RunningCommand", ""); ok {
 		runningCmd = v.(string)
 	} else {
 		runningCmd = defaultRunningCommandPYTHON
-		setting.Set("Language.Python3.RunningCommand", runningCmd)
+		setting.SetDefault("Language.Python3.RunningCommand", runningCmd)
 	}
-	PYTHON3 = &languageBase{
-		name:           "Python3",
-		fileExtension:  ".py",
-		compileCommand: defaultCompileCommandPYTHON,
-		runningCommand: defaultRunningCommandPYTHON,
-		commentBegin:   "# ",
-		commentEnd:     "",
+	PYTHON3 = &python{
+		languageBase: languageBase{
+			name:           "Python3",
+			fileExtension:  ".py",
+			compileCommand: defaultCompileCommandPYTHON,
+			runningCommand: defaultRunningCommandPYTHON,
+			commentBegin:   "# ",
+			commentEnd:     "",
+		},
 	}
 }
 
 type python struct {
 	languageBase
 }
+
+// libraryRoots ... Language.{言語名}.LibraryRoots に設定されたライブラリのルートディレクトリの一覧
+func (l *python) libraryRoots() []string {
+	roots := []string{}
+	if v, ok := setting.Get("Language."+l.name+".LibraryRoots", ""); ok {
+		if list, ok := v.([]interface{}); ok {
+			for _, r := range list {
+				if s, ok := r.(string); ok {
+					roots = append(roots, s)
+				}
+			}
+		}
+	}
+	return roots
+}
+
+// Bundle ... ライブラリから import しているモジュールを埋め込んで1つのソースコードにする
+// ローカルで実行するソースファイル自体は変更しない
+func (l *python) Bundle(source string) (string, error) {
+	roots := l.libraryRoots()
+	if len(roots) == 0 {
+		return source, nil
+	}
+	return bundlePython(source, roots)
+}
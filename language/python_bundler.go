@@ -0,0 +1,282 @@
+package language
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/algon-320/KIDE/util"
+)
+
+// pythonModule ... ライブラリのルート以下で見つかったモジュール
+type pythonModule struct {
+	name      string // `mylib.sub.x` のようなドット区切りの名前
+	path      string // ソースファイルのパス (__init__.py の無いパッケージの場合は空)
+	isPackage bool
+}
+
+var (
+	rePythonImport     = regexp.MustCompile(`^\s*import\s+(.+)$`)
+	rePythonFromImport = regexp.MustCompile(`^\s*from\s+(\.*[\w.]*)\s+import\s+(.+)$`)
+)
+
+// pythonBundler ... ライブラリのモジュールを1つのソースコードにまとめる
+type pythonBundler struct {
+	roots   []string
+	visited map[string]bool
+	ordered []*pythonModule // 読み込む順番 (依存されるものが先)
+}
+
+// bundlePython ... source から import されているライブラリのモジュールを roots 以下から探して埋め込んだソースコードを返す
+// ライブラリのモジュールを使っていない場合は source をそのまま返す
+func bundlePython(source string, roots []string) (string, error) {
+	b := &pythonBundler{roots: roots, visited: map[string]bool{}}
+	if err := b.collect(source, ""); err != nil {
+		return "", err
+	}
+	if len(b.ordered) == 0 {
+		return source, nil
+	}
+
+	// shebang・エンコーディング指定・`from __future__` は先頭に残す
+	var buf strings.Builder
+	lines := strings.SplitAfter(source, "\n")
+	head := 0
+	for head < len(lines) {
+		l := strings.TrimSpace(lines[head])
+		if l != "" && !strings.HasPrefix(l, "#") && !strings.HasPrefix(l, "from __future__") {
+			break
+		}
+		head++
+	}
+	buf.WriteString(strings.Join(lines[:head], ""))
+
+	buf.WriteString("# ---- modules bundled by KIDE ----\n")
+	buf.WriteString("import sys as _kide_sys, types as _kide_types\n")
+	// 先にすべてのモジュールを登録してから依存順に実行する
+	buf.WriteString("def _kide_new(name, is_pkg):\n")
+	buf.WriteString("    m = _kide_types.ModuleType(name)\n")
+	buf.WriteString("    m.__file__ = '<kide:' + name + '>'\n")
+	buf.WriteString("    if is_pkg:\n")
+	buf.WriteString("        m.__path__ = []\n")
+	buf.WriteString("        m.__package__ = name\n")
+	buf.WriteString("    else:\n")
+	buf.WriteString("        m.__package__ = name.rpartition('.')[0]\n")
+	buf.WriteString("    _kide_sys.modules[name] = m\n")
+	buf.WriteString("    if '.' in name:\n")
+	buf.WriteString("        parent, _, child = name.rpartition('.')\n")
+	buf.WriteString("        setattr(_kide_sys.modules[parent], child, m)\n")
+	buf.WriteString("def _kide_exec(name, src):\n")
+	buf.WriteString("    m = _kide_sys.modules[name]\n")
+	buf.WriteString("    exec(compile(src, m.__file__, 'exec'), m.__dict__)\n")
+
+	// 親パッケージが先に登録されるように名前の短い順に並べる
+	byDepth := make([]*pythonModule, len(b.ordered))
+	copy(byDepth, b.ordered)
+	sort.SliceStable(byDepth, func(i, j int) bool {
+		return strings.Count(byDepth[i].name, ".") < strings.Count(byDepth[j].name, ".")
+	})
+	for _, m := range byDepth {
+		isPkg := "False"
+		if m.isPackage {
+			isPkg = "True"
+		}
+		fmt.Fprintf(&buf, "_kide_new('%s', %s)\n", m.name, isPkg)
+	}
+	for _, m := range b.ordered {
+		if m.path == "" {
+			continue
+		}
+		src, err := ioutil.ReadFile(m.path)
+		if err != nil {
+			return "", &ErrFailedToBundle{message: err.Error()}
+		}
+		fmt.Fprintf(&buf, "_kide_exec('%s', '''%s''')\n", m.name, escapePythonString(string(src)))
+	}
+	buf.WriteString("# ---- end of bundled modules ----\n\n")
+	buf.WriteString(strings.Join(lines[head:], ""))
+	return buf.String(), nil
+}
+
+// collect ... source 中の import を解決して、見つかったモジュールを依存順に b.ordered に追加する
+// pkg: source が属するパッケージ名 (相対importの解決に使う, 提出するソースコードの場合は空)
+func (b *pythonBundler) collect(source string, pkg string) error {
+	for _, name := range parsePythonImports(source, pkg) {
+		if err := b.addWithParents(name.module); err != nil {
+			return err
+		}
+		// `from pkg import sub` の sub がサブモジュールの場合
+		for _, sub := range name.fromlist {
+			if m := b.find(name.module + "." + sub); m != nil {
+				if err := b.add(m); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addWithParents ... `a.b.c` なら a, a.b, a.b.c の順に追加する
+func (b *pythonBundler) addWithParents(name string) error {
+	parts := strings.Split(name, ".")
+	for i := range parts {
+		m := b.find(strings.Join(parts[:i+1], "."))
+		if m == nil {
+			return nil // ライブラリのモジュールではない (標準ライブラリなど)
+		}
+		if err := b.add(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *pythonBundler) add(m *pythonModule) error {
+	if b.visited[m.name] {
+		return nil
+	}
+	b.visited[m.name] = true
+	if i := strings.LastIndex(m.name, "."); i >= 0 {
+		if err := b.addWithParents(m.name[:i]); err != nil {
+			return err
+		}
+	}
+
+	if m.path != "" {
+		src, err := ioutil.ReadFile(m.path)
+		if err != nil {
+			return &ErrFailedToBundle{message: err.Error()}
+		}
+		pkg := m.name
+		if !m.isPackage {
+			pkg = ""
+			if i := strings.LastIndex(m.name, "."); i >= 0 {
+				pkg = m.name[:i]
+			}
+		}
+		if err := b.collect(string(src), pkg); err != nil {
+			return err
+		}
+	}
+	util.DebugPrint("bundle python module : " + m.name)
+	b.ordered = append(b.ordered, m)
+	return nil
+}
+
+// find ... ドット区切りのモジュール名に対応するファイルを roots から探す
+func (b *pythonBundler) find(name string) *pythonModule {
+	rel := filepath.Join(strings.Split(name, ".")...)
+	for _, root := range b.roots {
+		dir := filepath.Join(root, rel)
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			init := filepath.Join(dir, "__init__.py")
+			if !util.FileExists(init) {
+				init = "" // namespace package
+			}
+			return &pythonModule{name: name, path: init, isPackage: true}
+		}
+		if util.FileExists(dir + ".py") {
+			return &pythonModule{name: name, path: dir + ".py"}
+		}
+	}
+	return nil
+}
+
+type pythonImport struct {
+	module   string   // 絶対名に直したモジュール名
+	fromlist []string // `from module import a, b` の a, b
+}
+
+// parsePythonImports ... source の import 文を列挙する
+// pkg: 相対importの基準となるパッケージ名
+func parsePythonImports(source string, pkg string) []pythonImport {
+	ret := []pythonImport{}
+	sc := bufio.NewScanner(strings.NewReader(source))
+	for sc.Scan() {
+		line := stripPythonComment(sc.Text())
+
+		if g := rePythonFromImport.FindStringSubmatch(line); g != nil {
+			names := g[2]
+			// `from x import (a,\n b)` の形式
+			if strings.HasPrefix(strings.TrimSpace(names), "(") {
+				for !strings.Contains(names, ")") && sc.Scan() {
+					names += " " + stripPythonComment(sc.Text())
+				}
+			}
+			names = strings.Trim(strings.TrimSpace(names), "()")
+			module := resolvePythonRelative(g[1], pkg)
+			if module == "" {
+				continue
+			}
+			imp := pythonImport{module: module}
+			for _, n := range strings.Split(names, ",") {
+				if f := strings.Fields(n); len(f) > 0 && f[0] != "*" {
+					imp.fromlist = append(imp.fromlist, f[0])
+				}
+			}
+			ret = append(ret, imp)
+		} else if g := rePythonImport.FindStringSubmatch(line); g != nil {
+			for _, n := range strings.Split(g[1], ",") {
+				if f := strings.Fields(n); len(f) > 0 {
+					ret = append(ret, pythonImport{module: f[0]})
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// resolvePythonRelative ... `..sub` のような相対的なモジュール名を pkg を基準に絶対名に直す
+func resolvePythonRelative(module string, pkg string) string {
+	level := len(module) - len(strings.TrimLeft(module, "."))
+	if level == 0 {
+		return module
+	}
+	parts := []string{}
+	if pkg != "" {
+		parts = strings.Split(pkg, ".")
+	}
+	if level-1 > len(parts) {
+		return ""
+	}
+	base := strings.Join(parts[:len(parts)-(level-1)], ".")
+	rest := module[level:]
+	if base == "" {
+		return rest
+	}
+	if rest == "" {
+		return base
+	}
+	return base + "." + rest
+}
+
+// stripPythonComment ... 行の `#` から後ろのコメントを取り除く (文字列リテラルの中の `#` はそのまま)
+func stripPythonComment(line string) string {
+	var quote byte // 文字列リテラルの中なら開いた引用符
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0 && c == '\\':
+			i++ // エスケープされた文字を飛ばす
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// escapePythonString ... 三重引用符で囲む文字列リテラルに埋め込めるようにエスケープする
+func escapePythonString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `'`, `\'`, -1)
+	return s
+}
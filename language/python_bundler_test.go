@@ -0,0 +1,88 @@
+package language
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPythonBundle(t *testing.T) {
+	root, err := ioutil.TempDir("", "kide_bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"mylib/__init__.py":       "from .math import gcd\n",
+		"mylib/math.py":           "def gcd(a, b):\n    return a if b == 0 else gcd(b, a % b)\n",
+		"mylib/graph/__init__.py": "",
+		"mylib/graph/uf.py":       "from ..math import gcd\nclass UF(object):\n    '''union find'''\n    pass\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := "# -*- coding: utf-8 -*-\nfrom __future__ import print_function\nimport sys\nfrom mylib.graph import uf\nfrom mylib import gcd\nprint(gcd(4, 6))\n"
+	bundled, err := bundlePython(source, []string{root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(bundled, "# -*- coding: utf-8 -*-\nfrom __future__ import print_function\n# ---- modules bundled by KIDE ----\n") {
+		t.Error("`from __future__` が先頭に残っていません")
+	}
+	if !strings.HasSuffix(bundled, "import sys\nfrom mylib.graph import uf\nfrom mylib import gcd\nprint(gcd(4, 6))\n") {
+		t.Error("元のソースコードが末尾に残っていません")
+	}
+
+	order := []string{
+		"_kide_exec('mylib.math'",
+		"_kide_exec('mylib'",
+		"_kide_exec('mylib.graph'",
+		"_kide_exec('mylib.graph.uf'",
+	}
+	prev := -1
+	for _, s := range order {
+		i := strings.Index(bundled, s)
+		if i < 0 {
+			t.Fatalf("%s が埋め込まれていません", s)
+		}
+		if i < prev {
+			t.Errorf("%s の順番が正しくありません", s)
+		}
+		prev = i
+	}
+	if strings.Contains(bundled, "_kide_new('sys'") {
+		t.Error("標準ライブラリが埋め込まれています")
+	}
+
+	// 文字列リテラルの中の `#` はコメントではない
+	testcase := map[string]string{
+		`import mylib  # comment`:             `import mylib  `,
+		`print("#"); import mylib # "x"`:      `print("#"); import mylib `,
+		`s = 'it\'s #1'  # note`:              `s = 'it\'s #1'  `,
+		`x = "a\"#" + '"#'`:                   `x = "a\"#" + '"#'`,
+		`from mylib import (gcd,  # "quoted"`: `from mylib import (gcd,  `,
+	}
+	for line, expect := range testcase {
+		if got := stripPythonComment(line); got != expect {
+			t.Errorf("stripPythonComment(%q) = %q, expected %q", line, got, expect)
+		}
+	}
+	hash := "print(\"#\")\nfrom mylib import gcd\n"
+	if bundled, err := bundlePython(hash, []string{root}); err != nil || !strings.HasSuffix(bundled, hash) || !strings.Contains(bundled, "_kide_exec('mylib.math'") {
+		t.Errorf("bundlePython returned %q, %v", bundled, err)
+	}
+
+	// ライブラリを使っていない場合はそのまま
+	plain := "print(1)\n"
+	if res, _ := bundlePython(plain, []string{root}); res != plain {
+		t.Error("ライブラリを使わないソースコードが変更されています")
+	}
+}
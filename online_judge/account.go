@@ -0,0 +1,265 @@
+package online_judge
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/algon-320/KIDE/credential"
+	"github.com/algon-320/KIDE/setting"
+	"github.com/algon-320/KIDE/util"
+	"github.com/headzoo/surf/browser"
+)
+
+// accountJudge ... ログインが必要なオンラインジャッジが実装するインターフェース
+type accountJudge interface {
+	OnlineJudge
+	login() (*browser.Browser, error)
+	checkLoggedin(br *browser.Browser) bool
+	accountInfo() accountInfo
+}
+
+// sessionRejecter ... ログインページへのリダイレクト以外の方法でセッションの拒否を判定したいオンラインジャッジが実装するインターフェース
+type sessionRejecter interface {
+	sessionRejected(br *browser.Browser) bool
+}
+
+// accountInfo ... オンラインジャッジのアカウントとセッションの情報
+type accountInfo struct {
+	settingKey  string        // 設定ファイルでの名前 (OnlineJudge.{settingKey}.Handle)
+	envPrefix   string        // 環境変数 {envPrefix}_HANDLE、{envPrefix}_PASSWORD
+	sessionFile string        // セッションファイルの名前
+	cookieURL   string        // セッションのクッキーのURL
+	loginURL    string        // ログインページ (セッションが拒否されるとここに飛ばされる)
+	session     *sessionCache // ログイン済みのブラウザを使い回している場合はそのキャッシュ (無ければnil)
+}
+
+// handle ... 設定されているハンドル (聞かずに返す。設定されていない場合は空文字列)
+func (info accountInfo) handle() string {
+	if tmp, ok := setting.Get("OnlineJudge."+info.settingKey+".Handle", info.envPrefix+"_HANDLE"); ok {
+		if h, ok := tmp.(string); ok {
+			return h
+		}
+	}
+	return ""
+}
+
+// discardSession ... 保存しているセッションと使い回しているブラウザを捨てる
+func (info accountInfo) discardSession() error {
+	if info.session != nil {
+		info.session.set(nil)
+	}
+	return util.RemoveLoginSession(info.sessionFile)
+}
+
+func asAccountJudge(oj OnlineJudge) (accountJudge, error) {
+	j, ok := oj.(accountJudge)
+	if !ok {
+		return nil, &ErrLoginNotSupported{oj_name: oj.Name()}
+	}
+	return j, nil
+}
+
+// Login ... 保存しているセッションを捨ててログインし直す
+// パスワードは入力し直させる (環境変数で指定されている場合を除く)。ログインに失敗した場合は入力したパスワードを保存しない
+func Login(oj OnlineJudge) error {
+	j, err := asAccountJudge(oj)
+	if err != nil {
+		return err
+	}
+	info := j.accountInfo()
+	if err := info.discardSession(); err != nil {
+		return err
+	}
+
+	store := credential.Open()
+	if handle := info.handle(); handle != "" {
+		if err := store.Delete(info.settingKey, handle); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if _, err := j.login(); err != nil {
+		if handle := info.handle(); handle != "" {
+			store.Delete(info.settingKey, handle)
+		}
+		return err
+	}
+	fmt.Fprintf(os.Stderr, util.PrefixInfo+"Logged in to %s as `%s`.\n", oj.Name(), info.handle())
+	return nil
+}
+
+// Logout ... 保存しているセッションを削除する
+// forget: 保存しているパスワードも削除する
+func Logout(oj OnlineJudge, forget bool) error {
+	j, err := asAccountJudge(oj)
+	if err != nil {
+		return err
+	}
+	info := j.accountInfo()
+	if err := info.discardSession(); err != nil {
+		return err
+	}
+	if forget {
+		if handle := info.handle(); handle != "" {
+			if err := credential.Open().Delete(info.settingKey, handle); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Fprintf(os.Stderr, util.PrefixInfo+"Logged out from %s.\n", oj.Name())
+	return nil
+}
+
+// AccountStatus ... オンラインジャッジのログイン状態
+type AccountStatus struct {
+	Judge       string
+	Handle      string    // 設定されているハンドル (無い場合は空文字列)
+	SessionPath string    // セッションファイルのパス
+	HasSession  bool      // セッションファイルがあるか
+	Expires     time.Time // クッキーの有効期限 (分からない場合はゼロ値)
+	Valid       *bool     // オンラインジャッジに確認した結果 (確認していない場合はnil)
+}
+
+// sessionExpiry ... セッションのクッキーの有効期限のうち一番早いもの (どれが切れてもログインが切れる可能性がある)
+func sessionExpiry(cookies []*http.Cookie) time.Time {
+	var ret time.Time
+	for _, c := range cookies {
+		if c.Expires.IsZero() {
+			continue
+		}
+		if ret.IsZero() || c.Expires.Before(ret) {
+			ret = c.Expires
+		}
+	}
+	return ret
+}
+
+// Accounts ... 登録されているオンラインジャッジのログイン状態の一覧
+// check: 保存しているセッションが使えるかオンラインジャッジに確認する
+func Accounts(check bool) []*AccountStatus {
+	ret := []*AccountStatus{}
+	for _, r := range Registered() {
+		j, ok := r.Judge.(accountJudge)
+		if !ok {
+			continue
+		}
+		info := j.accountInfo()
+		st := &AccountStatus{
+			Judge:       j.Name(),
+			Handle:      info.handle(),
+			SessionPath: util.LoginSessionPath(info.sessionFile),
+		}
+		cookies, err := util.ReadLoginSession(info.sessionFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, util.PrefixCaution+err.Error())
+		}
+		st.HasSession = cookies != nil
+		st.Expires = sessionExpiry(cookies)
+
+		if check && st.HasSession {
+			valid := false
+			if u, err := url.Parse(info.cookieURL); err == nil {
+				jar, _ := cookiejar.New(nil)
+				jar.SetCookies(u, cookies)
+				br := newBrowser()
+				br.SetCookieJar(jar)
+				valid = j.checkLoggedin(br)
+			}
+			st.Valid = &valid
+		}
+		ret = append(ret, st)
+	}
+	return ret
+}
+
+// sessionRejected ... br が開いたページがセッションを拒否されたものかどうか
+func sessionRejected(j accountJudge, br *browser.Browser) bool {
+	if r, ok := j.(sessionRejecter); ok && r.sessionRejected(br) {
+		return true
+	}
+	if code := br.StatusCode(); code == 401 || code == 403 {
+		return true
+	}
+	loginURL, err := url.Parse(j.accountInfo().loginURL)
+	if err != nil || br.Url() == nil {
+		return false
+	}
+	return br.Url().Host == loginURL.Host && br.Url().Path == loginURL.Path
+}
+
+// relogin ... 拒否されたセッションを捨ててログインし直す
+func relogin(j accountJudge) (*browser.Browser, error) {
+	fmt.Fprintln(os.Stderr, util.PrefixCaution+"The session of "+j.Name()+" was rejected. Logging in again ...")
+	info := j.accountInfo()
+	if err := info.discardSession(); err != nil {
+		return nil, err
+	}
+	br, err := j.login()
+	if err != nil {
+		return nil, err
+	}
+	if info.session != nil {
+		info.session.set(br)
+	}
+	return br, nil
+}
+
+// openAuthorized ... ログインが必要なページを br で開く
+// セッションが拒否された場合 (期限切れなど) はセッションを捨ててログインし直し、新しいブラウザで開き直して返す
+func openAuthorized(j accountJudge, br *browser.Browser, pageURL string) (*browser.Browser, error) {
+	if err := br.Open(pageURL); err != nil {
+		return nil, err
+	}
+	if !sessionRejected(j, br) {
+		return br, checkStatus(br, pageURL)
+	}
+
+	br, err := relogin(j)
+	if err != nil {
+		return nil, err
+	}
+	if err := br.Open(pageURL); err != nil {
+		return nil, err
+	}
+	if sessionRejected(j, br) {
+		return nil, &ErrSessionRejected{oj_name: j.Name()}
+	}
+	return br, checkStatus(br, pageURL)
+}
+
+// doAuthorized ... ログインが必要な API へのリクエストを br のクッキーで送る (openAuthorized の API 版)
+// 401、403 が返ってきた場合 (セッションの期限切れなど) はセッションを捨ててログインし直し、もう一度だけ送る
+// newRequest: 送るリクエストを作る (送り直す時にも呼ばれる)
+func doAuthorized(j accountJudge, br *browser.Browser, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	send := func(br *browser.Browser) (*http.Response, error) {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		return httpClient(br.CookieJar()).Do(req)
+	}
+	rejected := func(resp *http.Response) bool {
+		return resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden
+	}
+
+	resp, err := send(br)
+	if err != nil || !rejected(resp) {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	if br, err = relogin(j); err != nil {
+		return nil, err
+	}
+	if resp, err = send(br); err != nil {
+		return nil, err
+	}
+	if rejected(resp) {
+		resp.Body.Close()
+		return nil, &ErrSessionRejected{oj_name: j.Name()}
+	}
+	return resp, nil
+}
@@ -0,0 +1,131 @@
+package online_judge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/algon-320/KIDE/util"
+)
+
+func TestOpenAuthorized(t *testing.T) {
+	fmt.Println("testing : account.go > openAuthorized")
+	defer serveFixtures(t, atcoderRoutes(t))()
+
+	os.Setenv("ATCODER_HANDLE", "kide")
+	os.Setenv("ATCODER_PASSWORD", "password")
+	defer os.Unsetenv("ATCODER_HANDLE")
+	defer os.Unsetenv("ATCODER_PASSWORD")
+	defer util.RemoveLoginSession(AtCoder.sessionFile)
+	defer AtCoder.session.set(nil)
+
+	// ログインしていないブラウザで開くとログインページに飛ばされるので、ログインし直してから開き直す
+	listURL := "https://atcoder.jp/contests/practice/submissions/me"
+	br, err := openAuthorized(AtCoder, newBrowser(), listURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if br.Url().String() != listURL {
+		t.Errorf("opened %s, expected %s", br.Url(), listURL)
+	}
+	if cached, _ := AtCoder.session.get(AtCoder.login); cached != br {
+		t.Errorf("the browser logged in again is not cached")
+	}
+
+	// ログインの時の Max-Age が有効期限として保存される
+	cookies, err := util.ReadLoginSession(AtCoder.sessionFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := sessionExpiry(cookies)
+	if d := time.Until(expires); d < 59*time.Minute || time.Hour < d {
+		t.Errorf("session expires at %v", expires)
+	}
+
+	os.Setenv("ATCODER_PASSWORD", "wrong password")
+	if _, err := openAuthorized(AtCoder, newBrowser(), listURL); err == nil {
+		t.Errorf("opened with a wrong password")
+	}
+}
+
+func TestDoAuthorized(t *testing.T) {
+	fmt.Println("testing : account.go > doAuthorized")
+
+	loggedIn := func(r *http.Request) bool {
+		c, err := r.Cookie("JSESSIONID")
+		return err == nil && c.Value == "valid"
+	}
+	posts := 0
+	routes := map[string]http.HandlerFunc{
+		"judgeapi.u-aizu.ac.jp/session": func(w http.ResponseWriter, r *http.Request) {
+			var payload struct {
+				ID       string `json:"id"`
+				Password string `json:"password"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			if payload.ID != "kide" || payload.Password != "password" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "valid", Path: "/"})
+		},
+		"judgeapi.u-aizu.ac.jp/self": func(w http.ResponseWriter, r *http.Request) {
+			if !loggedIn(r) {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		},
+		"judgeapi.u-aizu.ac.jp/submissions": func(w http.ResponseWriter, r *http.Request) {
+			posts++
+			if !loggedIn(r) {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		},
+	}
+	defer serveFixtures(t, routes)()
+
+	os.Setenv("AOJ_HANDLE", "kide")
+	os.Setenv("AOJ_PASSWORD", "password")
+	defer os.Unsetenv("AOJ_HANDLE")
+	defer os.Unsetenv("AOJ_PASSWORD")
+	defer util.RemoveLoginSession(AOJ.sessionFile)
+
+	newRequest := func() (*http.Request, error) {
+		return http.NewRequest("POST", "https://judgeapi.u-aizu.ac.jp/submissions", strings.NewReader("{}"))
+	}
+
+	// ログインしていないブラウザで送ると 401 が返ってくるので、ログインし直してから送り直す
+	resp, err := doAuthorized(AOJ, newBrowser(), newRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || posts != 2 {
+		t.Errorf("status %d after %d requests", resp.StatusCode, posts)
+	}
+
+	os.Setenv("AOJ_PASSWORD", "wrong password")
+	if _, err := doAuthorized(AOJ, newBrowser(), newRequest); err == nil {
+		t.Errorf("sent with a wrong password")
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	fmt.Println("testing : account.go > sessionExpiry")
+
+	now := time.Now()
+	cookies := []*http.Cookie{
+		{Name: "a"},
+		{Name: "b", Expires: now.Add(2 * time.Hour)},
+		{Name: "c", Expires: now.Add(time.Hour)},
+	}
+	if got := sessionExpiry(cookies); !got.Equal(now.Add(time.Hour)) {
+		t.Errorf("sessionExpiry returned %v", got)
+	}
+	if got := sessionExpiry(cookies[:1]); !got.IsZero() {
+		t.Errorf("sessionExpiry of session cookies returned %v", got)
+	}
+}
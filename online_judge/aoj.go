@@ -10,33 +10,54 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/algon-320/KIDE/credential"
 	"github.com/algon-320/KIDE/language"
 	"github.com/algon-320/KIDE/setting"
 	"github.com/algon-320/KIDE/util"
-	"github.com/headzoo/surf"
 	"github.com/headzoo/surf/browser"
 )
 
 type aoj struct {
-	name        string
-	url         string
-	loginURL    string
-	sessionFile string
+	name              string
+	url               string
+	loginURL          string
+	sessionFile       string
+	settingKey        string // 設定ファイルでの名前 (OnlineJudge.{settingKey})
+	languageCacheFile string
 }
 
 // AOJ ... オンラインジャッジ: Aizu Online Judge
 var AOJ = &aoj{
-	name:        "Aizu Online Judge",
-	url:         "http://judge.u-aizu.ac.jp/onlinejudge/index.jsp",
-	loginURL:    "http://judge.u-aizu.ac.jp/onlinejudge/signin.jsp",
-	sessionFile: "session_aoj.dat",
+	name:              "Aizu Online Judge",
+	url:               "http://judge.u-aizu.ac.jp/onlinejudge/index.jsp",
+	loginURL:          "http://judge.u-aizu.ac.jp/onlinejudge/signin.jsp",
+	sessionFile:       "session_aoj.dat",
+	settingKey:        "AOJ",
+	languageCacheFile: "languages_aoj.json",
 }
 
-func (a *aoj) getLangID(lang language.Language) (string, error) {
+func init() {
+	Register(&Registration{
+		Key:          "aoj",
+		Judge:        AOJ,
+		Aliases:      []string{AOJ.settingKey},
+		Hosts:        []string{"judge.u-aizu.ac.jp", "onlinejudge.u-aizu.ac.jp"},
+		Capabilities: CapabilityAPI,
+		MainClass:    "Main",
+	})
+}
+
+// getLangID ... lang (profile) で提出する時の言語ID
+// 設定ファイルの OnlineJudge.AOJ.LanguageID に指定されていればそれを、無ければデフォルトのものを返す
+func (a *aoj) getLangID(lang language.Language, profile string) (string, error) {
+	if id, ok := langIDFromSetting(a.settingKey, lang, profile); ok {
+		return id, nil
+	}
 	switch lang {
 	case language.CPP:
 		return "C++14", nil // C++14
@@ -51,31 +72,25 @@ func (a *aoj) getLangID(lang language.Language) (string, error) {
 	}
 }
 
-func (a *aoj) loadAccount() (string, string) {
-	var handle string
+// loadHandle ... アカウントのIDを読み込む (パスワードは読み込まないので、保存したパスワードの復号は求められない)
+func (a *aoj) loadHandle() string {
 	if tmp, ok := setting.Get("OnlineJudge.AOJ.Handle", "AOJ_HANDLE"); ok {
-		handle = tmp.(string)
-	} else {
-		handle = util.AskString("What is your AOJ account id ?")
-		setting.Set("OnlineJudge.AOJ.Handle", handle)
+		return tmp.(string)
 	}
+	handle := util.AskString("What is your AOJ account id ?")
+	setting.Set("OnlineJudge.AOJ.Handle", handle)
+	return handle
+}
 
-	var password string
-	if tmp, ok := setting.Get("OnlineJudge.AOJ.Password", "AOJ_PASSWORD"); ok {
-		password = tmp.(string)
-	} else {
-		password = util.AskString("What is your AOJ account password ?")
-		setting.Set("OnlineJudge.AOJ.Password", password)
-	}
+func (a *aoj) loadAccount() (string, string) {
+	handle := a.loadHandle()
+	password := credential.LoadPassword("AOJ", handle, "AOJ_PASSWORD", "What is your AOJ account password ?")
 
 	return handle, password
 }
 
 func (a *aoj) login() (*browser.Browser, error) {
-	handle, password := a.loadAccount()
-	data := map[string]string{"userID": handle, "password": password}
-
-	br := surf.NewBrowser()
+	br, rec := newSessionBrowser()
 
 	cjar := util.LoadLoginSession(a.sessionFile, a.url)
 	if cjar != nil {
@@ -86,8 +101,10 @@ func (a *aoj) login() (*browser.Browser, error) {
 		}
 	}
 
-	// 新たにログイン
+	// 新たにログイン (保存されたセッションが使えない場合だけアカウントとパスワードを読み込む)
 	fmt.Fprintln(os.Stderr, util.PrefixInfo+"login to AOJ ...")
+	handle, password := a.loadAccount()
+	data := map[string]string{"userID": handle, "password": password}
 
 	type Payload struct {
 		UserID   string `json:"id"`
@@ -105,7 +122,8 @@ func (a *aoj) login() (*browser.Browser, error) {
 	}
 	req.Header.Set("Content-Type", "application/JSON")
 
-	client := &http.Client{Jar: br.CookieJar()}
+	client := httpClient(br.CookieJar())
+	client.Transport = rec
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -115,13 +133,24 @@ func (a *aoj) login() (*browser.Browser, error) {
 	if a.checkLoggedin(br) {
 		cookieURL, _ := url.Parse("judge.u-aizu.ac.jp")
 		cookies := br.CookieJar().Cookies(cookieURL)
-		util.SaveLoginSession(a.sessionFile, cookies)
+		util.SaveLoginSession(a.sessionFile, rec.withExpiry(cookies))
 		return br, nil
 	}
 
 	return nil, &ErrFailedToLogin{oj_name: a.Name(), message: "Failed to login."}
 }
 
+// accountInfo ... アカウントとセッションの情報
+func (a *aoj) accountInfo() accountInfo {
+	return accountInfo{
+		settingKey:  a.settingKey,
+		envPrefix:   "AOJ",
+		sessionFile: a.sessionFile,
+		cookieURL:   a.url,
+		loginURL:    a.loginURL,
+	}
+}
+
 func (a *aoj) checkLoggedin(br *browser.Browser) bool {
 	br.Open("https://judgeapi.u-aizu.ac.jp/self")
 	if br.StatusCode() != 200 {
@@ -147,13 +176,13 @@ func (a *aoj) Name() string {
 	return a.name
 }
 
-func (a *aoj) Submit(p *Problem, sourceCode string, lang language.Language) (*JudgeResult, error) {
-	langID, err := a.getLangID(lang)
+func (a *aoj) Submit(p *Problem, sourceCode string, lang language.Language, profile string) (*JudgeResult, error) {
+	langID, err := a.getLangID(lang, profile)
 	if err != nil {
 		return nil, err
 	}
 
-	doc, err := goquery.NewDocument(p.URL)
+	doc, err := getDocument(p.URL)
 	if err != nil {
 		return nil, err
 	}
@@ -186,18 +215,22 @@ func (a *aoj) Submit(p *Problem, sourceCode string, lang language.Language) (*Ju
 
 	br, err := a.login()
 	if err != nil {
-		return nil, &ErrFailedToSubmit{message: err.(*ErrFailedToLogin).message}
+		if e, ok := err.(*ErrFailedToLogin); ok {
+			return nil, &ErrFailedToSubmit{message: e.message}
+		}
+		return nil, err // タイムアウトや通信のエラー
 	}
 
-	// submit
+	// submit (セッションが拒否された場合はログインし直して送り直す)
 	{
-		req, err := http.NewRequest("POST", postURL, strings.NewReader(string(bytes)))
-		if err != nil {
-			return nil, err
-		}
-		req.Header.Set("Content-Type", "application/JSON")
-		client := &http.Client{Jar: br.CookieJar()}
-		resp, err := client.Do(req)
+		resp, err := doAuthorized(a, br, func() (*http.Request, error) {
+			req, err := http.NewRequest("POST", postURL, strings.NewReader(string(bytes)))
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Content-Type", "application/JSON")
+			return req, nil
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -208,74 +241,33 @@ func (a *aoj) Submit(p *Problem, sourceCode string, lang language.Language) (*Ju
 		}
 	}
 
-	result := struct {
-		Status []struct {
-			RunID          string `xml:"run_id"`
-			UserID         string `xml:"user_id"`
-			ProblemID      string `xml:"problem_id"`
-			SubmissionDate string `xml:"submission_date"`
-			Status         string `xml:"status"`
-			Language       string `xml:"language"`
-			Cputime        string `xml:"cputime"`
-			Memory         string `xml:"memory"`
-			CodeSize       string `xml:"code_size"`
-		} `xml:"status"`
-	}{}
-	handle, _ := a.loadAccount()
+	handle := a.loadHandle()
 	// 結果を取る
-	{
-		time.Sleep(1 * time.Second)
-		resp, err := http.Get("http://judge.u-aizu.ac.jp/onlinejudge/webservice/status_log?user_id=" + handle + "&limit=1")
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-		xmldata, _ := ioutil.ReadAll(resp.Body)
-		if err := xml.Unmarshal(xmldata, &result); err != nil {
-			return nil, err
-		}
+	time.Sleep(1 * time.Second)
+	log, err := a.statusLog(handle, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(log) == 0 {
+		return nil, &ErrFailedToSubmit{message: "submission is not found."}
 	}
 
-	submissionID := strings.Trim(result.Status[0].RunID, "\n")
-	resultURL := "http://judge.u-aizu.ac.jp/onlinejudge/review.jsp?rid=" + submissionID
 	var judgeRes JudgeResult
 	judgeRes.Date = time.Now()
 	judgeRes.Problem = p
 	judgeRes.Code = sourceCode
 	judgeRes.Language = lang
 	judgeRes.Status = JudgeStatusUNK
-	judgeRes.URL = resultURL
+	judgeRes.URL = log[0].submission().URL
 
-	waiting := true
 	watingCnt := 0
 	for {
-		status := strings.Trim(result.Status[0].Status, "\n")
-		if strings.HasSuffix(status, "Accepted") {
-			judgeRes.Status = JudgeStatusAC
-			waiting = false
-		} else if strings.HasSuffix(status, "Wrong Answer") {
-			judgeRes.Status = JudgeStatusWA
-			waiting = false
-		} else if strings.HasSuffix(status, "Compile Error") {
-			judgeRes.Status = JudgeStatusCE
-			waiting = false
-		} else if strings.HasSuffix(status, "Runtime Error") {
-			judgeRes.Status = JudgeStatusRE
-			waiting = false
-		} else if strings.HasSuffix(status, "Time Limit Exceeded") {
-			judgeRes.Status = JudgeStatusTLE
-			waiting = false
-		} else if strings.HasSuffix(status, "Memory Limit Exceeded") {
-			judgeRes.Status = JudgeStatusMLE
-			waiting = false
-		} else if strings.HasSuffix(status, "Output Limit Exceeded") {
-			judgeRes.Status = JudgeStatusOLE
-			waiting = false
-		} else if strings.HasSuffix(status, "-") {
-			judgeRes.Status = JudgeStatusUNK
+		s, err := a.GetSubmission(judgeRes.URL)
+		if err != nil {
+			return nil, err
 		}
-
-		if !waiting {
+		if !s.Waiting {
+			judgeRes.Status = s.Status
 			break
 		}
 
@@ -293,10 +285,16 @@ func (a *aoj) Submit(p *Problem, sourceCode string, lang language.Language) (*Ju
 	}
 	fmt.Fprint(os.Stderr, "\n")
 
+	// 提出の詳細 (実行時間、メモリ、テストケースごとの結果など)
+	if err := a.judgeDetail(log[0].submission().ID, &judgeRes); err != nil {
+		util.DebugPrint(err.Error())
+	}
+
 	return &judgeRes, nil
 }
 
-func (a *aoj) NewProblem(url string) error {
+// fetchProblem ... problemURL の問題をダウンロードする (保存はしない)
+func (a *aoj) fetchProblem(problemURL string) (*Problem, error) {
 
 	// ---------------- メモ ----------------
 	// 入力例
@@ -309,58 +307,65 @@ func (a *aoj) NewProblem(url string) error {
 	// Output for Sample Input
 	// --------------------------------------
 
-	isValid, _ := a.IsValidURL(url)
-	if !isValid {
-		return &ErrInvalidProblemURL{url: url}
-	}
+	var p Problem
+	p.Oj = AOJ
+	p.URL = problemURL
 
-	downloadProblem := func(problemURL string) error {
-		var p Problem
-		p.Oj = AOJ
-		p.URL = problemURL
+	re := regexp.MustCompile("http://judge.u-aizu.ac.jp/onlinejudge/description.jsp\\?id=(.+?)(?:&.*)?$")
+	group := re.FindSubmatch([]byte(problemURL))
+	if group == nil {
+		return nil, &ErrInvalidProblemURL{url: problemURL}
+	}
+	p.ID = string(group[1])
+	p.Name = string(group[1])
+	p.ContestID = ""
 
-		re := regexp.MustCompile("http://judge.u-aizu.ac.jp/onlinejudge/description.jsp\\?id=(.+?)(?:&.*)?$")
-		group := re.FindSubmatch([]byte(problemURL))
-		if group == nil {
-			fmt.Fprintln(os.Stderr, group)
-			return &ErrInvalidProblemURL{url: problemURL}
-		}
-		p.ID = string(group[1])
-		p.Name = string(group[1])
-		p.ContestID = ""
+	doc, err := getDocument(problemURL)
+	if err != nil {
+		return nil, err
+	}
 
-		doc, err := goquery.NewDocument(problemURL)
-		if err != nil {
-			return err
+	fillLimits(&p, doc.Text())
+	fillStatementMetadata(&p, doc.Find(".description"))
+
+	var testCase TestCase
+	doc.Find("h2,h3").Each(func(_ int, s *goquery.Selection) {
+		utfText, _ := util.ShiftJIS2UTF8(s.Text())
+
+		if strings.HasPrefix(utfText, "入力例") ||
+			strings.HasPrefix(utfText, "サンプル入力") ||
+			strings.HasPrefix(utfText, "Sample Input") {
+			testCase.Input = s.Next().Text()
+			testCase.Input = html.UnescapeString(testCase.Input)
+			testCase.Input = util.AddBR(testCase.Input)
+
+		} else if strings.HasPrefix(utfText, "出力例") ||
+			strings.HasPrefix(utfText, "サンプル出力") ||
+			strings.HasPrefix(utfText, "Sample Output") ||
+			strings.HasPrefix(utfText, "Output for") {
+			testCase.Output = s.Next().Text()
+			testCase.Output = html.UnescapeString(testCase.Output)
+			testCase.Output = util.AddBR(testCase.Output)
+			testCase.Sample = true
+
+			p.Cases = append(p.Cases, testCase)
 		}
+	})
+	return &p, nil
+}
 
-		var testCase TestCase
-		doc.Find("h2,h3").Each(func(_ int, s *goquery.Selection) {
-			utfText, _ := util.ShiftJIS2UTF8(s.Text())
-
-			if strings.HasPrefix(utfText, "入力例") ||
-				strings.HasPrefix(utfText, "サンプル入力") ||
-				strings.HasPrefix(utfText, "Sample Input") {
-				testCase.Input = s.Next().Text()
-				testCase.Input = html.UnescapeString(testCase.Input)
-				testCase.Input = util.AddBR(testCase.Input)
-
-			} else if strings.HasPrefix(utfText, "出力例") ||
-				strings.HasPrefix(utfText, "サンプル出力") ||
-				strings.HasPrefix(utfText, "Sample Output") ||
-				strings.HasPrefix(utfText, "Output for") {
-				testCase.Output = s.Next().Text()
-				testCase.Output = html.UnescapeString(testCase.Output)
-				testCase.Output = util.AddBR(testCase.Output)
-				p.Cases = append(p.Cases, testCase)
-			}
-		})
-
-		p.Print()
-		return p.Save()
+func (a *aoj) NewProblem(url string) error {
+	isValid, _ := a.IsValidURL(url)
+	if !isValid {
+		return &ErrInvalidProblemURL{url: url}
 	}
 
-	return downloadProblem(url)
+	p, err := a.fetchProblem(url)
+	if err != nil {
+		return err
+	}
+	p.Print()
+	return p.Save()
 }
 
 func (a *aoj) IsValidURL(url string) (bool, bool) {
@@ -370,6 +375,199 @@ func (a *aoj) IsValidURL(url string) (bool, bool) {
 	return false, false
 }
 
-func (a *aoj) MarshalJSON() ([]byte, error) {
-	return []byte(`"` + a.Name() + `"`), nil
+// aojLanguages ... AOJ で提出できる言語
+// AOJ は API で提出するため提出フォームが無いので、固定の一覧を返す
+var aojLanguages = []JudgeLanguage{
+	{ID: "C", Name: "C"},
+	{ID: "C++", Name: "C++"},
+	{ID: "C++11", Name: "C++11"},
+	{ID: "C++14", Name: "C++14"},
+	{ID: "C++17", Name: "C++17"},
+	{ID: "JAVA", Name: "JAVA"},
+	{ID: "C#", Name: "C#"},
+	{ID: "D", Name: "D"},
+	{ID: "Go", Name: "Go"},
+	{ID: "Ruby", Name: "Ruby"},
+	{ID: "Rust", Name: "Rust"},
+	{ID: "Python", Name: "Python"},
+	{ID: "Python3", Name: "Python3"},
+	{ID: "PyPy3", Name: "PyPy3"},
+	{ID: "JavaScript", Name: "JavaScript"},
+	{ID: "Scala", Name: "Scala"},
+	{ID: "Haskell", Name: "Haskell"},
+	{ID: "OCaml", Name: "OCaml"},
+	{ID: "PHP", Name: "PHP"},
+	{ID: "Kotlin", Name: "Kotlin"},
+}
+
+func (a *aoj) scrapeLanguages() ([]JudgeLanguage, error) {
+	return aojLanguages, nil
+}
+
+// Languages ... 提出時に選択できる言語の一覧
+func (a *aoj) Languages(refresh bool) ([]JudgeLanguage, error) {
+	return getLanguages(a.languageCacheFile, refresh, a.scrapeLanguages)
+}
+
+// LanguageMappings ... 設定ファイルで言語IDに対応付けられた言語
+func (a *aoj) LanguageMappings() map[string][]string {
+	return languageMappings(a.settingKey)
+}
+
+// aojStatus ... status_log API の提出1件
+type aojStatus struct {
+	RunID          string `xml:"run_id"`
+	UserID         string `xml:"user_id"`
+	ProblemID      string `xml:"problem_id"`
+	SubmissionDate string `xml:"submission_date"` // UNIX時間 (ミリ秒)
+	Status         string `xml:"status"`
+	Language       string `xml:"language"`
+	Cputime        string `xml:"cputime"` // 1/100 秒
+	Memory         string `xml:"memory"`  // KB
+	CodeSize       string `xml:"code_size"`
+}
+
+// statusLog ... handle の最近の提出 (新しい順に limit 件)
+func (a *aoj) statusLog(handle string, limit int) ([]aojStatus, error) {
+	resp, err := httpClient(nil).Get(fmt.Sprintf("http://judge.u-aizu.ac.jp/onlinejudge/webservice/status_log?user_id=%s&limit=%d", url.QueryEscape(handle), limit))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	xmldata, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	result := struct {
+		Status []aojStatus `xml:"status"`
+	}{}
+	if err := xml.Unmarshal(xmldata, &result); err != nil {
+		return nil, err
+	}
+	return result.Status, nil
+}
+
+// aojVerdicts ... status_log の判定の文字列と JudgeStatus の対応
+var aojVerdicts = []struct {
+	suffix string
+	status JudgeStatus
+}{
+	{"Accepted", JudgeStatusAC},
+	{"Wrong Answer", JudgeStatusWA},
+	{"Presentation Error", JudgeStatusWA},
+	{"Compile Error", JudgeStatusCE},
+	{"Runtime Error", JudgeStatusRE},
+	{"Time Limit Exceeded", JudgeStatusTLE},
+	{"Memory Limit Exceeded", JudgeStatusMLE},
+	{"Output Limit Exceeded", JudgeStatusOLE},
+}
+
+// submission ... Submission にする
+func (s *aojStatus) submission() *Submission {
+	trim := func(str string) string { return strings.TrimSpace(str) }
+	ret := &Submission{
+		ID:       trim(s.RunID),
+		URL:      "http://judge.u-aizu.ac.jp/onlinejudge/review.jsp?rid=" + trim(s.RunID),
+		Problem:  trim(s.ProblemID),
+		Language: trim(s.Language),
+		Verdict:  trim(s.Status),
+		Status:   JudgeStatusUNK,
+	}
+	found := false
+	for _, v := range aojVerdicts {
+		if strings.HasSuffix(ret.Verdict, v.suffix) {
+			ret.Status = v.status
+			found = true
+			break
+		}
+	}
+	if !found {
+		// ジャッジ中の表示以外の知らない判定は JudgeStatusUNK のまま終わったものとして扱う
+		ret.Status = statusFromLabel(ret.Verdict)
+		ret.Waiting = isWaitingLabel(ret.Verdict)
+	}
+	if ms, err := strconv.ParseInt(trim(s.SubmissionDate), 10, 64); err == nil {
+		ret.Date = time.Unix(0, ms*int64(time.Millisecond))
+	}
+	if cs, err := strconv.Atoi(trim(s.Cputime)); err == nil {
+		ret.Time = fmt.Sprintf("%d ms", cs*10)
+	}
+	if m := trim(s.Memory); m != "" {
+		ret.Memory = m + " KB"
+	}
+	return ret
+}
+
+// ListSubmissions ... 自分の最近の提出の一覧 (AOJ の問題はコンテストに属さないので contest は使わない)
+func (a *aoj) ListSubmissions(contest string) ([]*Submission, error) {
+	handle := a.loadHandle()
+	log, err := a.statusLog(handle, statusRows)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Submission, len(log))
+	for i := range log {
+		ret[i] = log[i].submission()
+	}
+	return ret, nil
+}
+
+// GetSubmission ... submissionURL の提出 (最近の提出から探す)
+func (a *aoj) GetSubmission(submissionURL string) (*Submission, error) {
+	subs, err := a.ListSubmissions("")
+	if err != nil {
+		return nil, err
+	}
+	return findSubmission(subs, submissionURL)
+}
+
+// judgeDetail ... API (verdicts) から提出 runID の詳細を res に読み込む
+func (a *aoj) judgeDetail(runID string, res *JudgeResult) error {
+	resp, err := httpClient(nil).Get("https://judgeapi.u-aizu.ac.jp/verdicts/" + runID)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var detail struct {
+		SubmissionRecord struct {
+			CPUTime int `json:"cpuTime"` // 1/100 秒
+			Memory  int `json:"memory"`  // KB
+		} `json:"submissionRecord"`
+		CompileError string `json:"compileError"`
+		CaseVerdicts []struct {
+			Status   string `json:"status"`
+			Label    string `json:"label"`
+			CPUTime  int    `json:"cpuTime"`
+			Memory   int    `json:"memory"`
+			CaseName string `json:"caseName"`
+		} `json:"caseVerdicts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&detail); err != nil {
+		return err
+	}
+
+	res.Time = fmt.Sprintf("%d ms", detail.SubmissionRecord.CPUTime*10)
+	res.Memory = fmt.Sprintf("%d KB", detail.SubmissionRecord.Memory)
+	res.CompileMessage = strings.TrimSpace(detail.CompileError)
+	for _, c := range detail.CaseVerdicts {
+		cr := CaseResult{
+			Name:    c.CaseName,
+			Verdict: c.Status,
+			Time:    fmt.Sprintf("%d ms", c.CPUTime*10),
+			Memory:  fmt.Sprintf("%d KB", c.Memory),
+			Status:  statusFromLabel(c.Status), // `AC` のような略称か `Accepted` のような文字列
+		}
+		if cr.Name == "" {
+			cr.Name = c.Label
+		}
+		for _, v := range aojVerdicts {
+			if cr.Status == JudgeStatusUNK && strings.HasSuffix(c.Status, v.suffix) {
+				cr.Status = v.status
+				break
+			}
+		}
+		res.Cases = append(res.Cases, cr)
+	}
+	return nil
 }
@@ -2,60 +2,97 @@ package online_judge
 
 import (
 	"fmt"
+	"net/http"
+	"os"
 	"testing"
 )
 
-func TestAojNewProblem(t *testing.T) {
-	fmt.Println("testing : aoj.go > AOJ.NewProblem")
+// aojRoutes ... AOJ の記録しておいたページとAPIの結果を返すハンドラ
+func aojRoutes(t *testing.T) map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"judge.u-aizu.ac.jp/onlinejudge/description.jsp": fixture(t, "aoj_ITP1_1_A.html"),
+		"judge.u-aizu.ac.jp/onlinejudge/webservice/status_log": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("user_id") != "kide" {
+				t.Errorf("status_log is requested with user_id=%s", r.URL.Query().Get("user_id"))
+			}
+			fixture(t, "aoj_status_log.xml")(w, r)
+		},
+		"judgeapi.u-aizu.ac.jp/verdicts/4310002": fixture(t, "aoj_verdict_4310002.json"),
+	}
+}
+
+func TestAojFetchProblem(t *testing.T) {
+	fmt.Println("testing : aoj.go > AOJ.fetchProblem")
+	defer serveFixtures(t, aojRoutes(t))()
 
-	type tmp struct {
-		url      string
-		id       string
-		name     string
-		oj       string
-		num_case int
+	problemURL := "http://judge.u-aizu.ac.jp/onlinejudge/description.jsp?id=ITP1_1_A&lang=jp"
+	p, err := AOJ.fetchProblem(problemURL)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	test := func(testcase *tmp) {
-		if err := AOJ.NewProblem(testcase.url); err != nil {
-			t.Error(err)
-		}
+	if p.ID != "ITP1_1_A" {
+		t.Error("id検出エラー")
+	}
+	if p.Name != "ITP1_1_A" {
+		t.Error("問題名検出エラー")
+	}
+	if p.URL != problemURL {
+		t.Error("URLエラー")
+	}
+	if p.Oj != AOJ {
+		t.Error("OJエラー")
+	}
+	if len(p.Cases) != 1 {
+		t.Fatal("サンプルケースを抽出出来ていません！")
+	}
+	if p.Cases[0].Output != "Hello World\n" {
+		t.Errorf("サンプルケースの内容が違います : %+v", p.Cases[0])
+	}
+	if p.TimeLimitMs != 1000 || p.MemoryLimitMB != 128 {
+		t.Errorf("問題の情報が違います : %v", p.Metadata())
+	}
+}
 
-		p, err := LoadProblem(testcase.id)
-		if err != nil {
-			t.Error(err)
-			return
-		}
+func TestAojSubmissions(t *testing.T) {
+	fmt.Println("testing : aoj.go > AOJ.ListSubmissions")
+	defer serveFixtures(t, aojRoutes(t))()
 
-		if p.ID != testcase.id {
-			t.Error("id検出エラー")
-		}
-		if p.Name != testcase.name {
-			t.Error("問題名検出エラー")
-		}
-		if p.URL != testcase.url {
-			t.Error("URLエラー")
-		}
-		if p.Oj.Name() != testcase.oj {
-			t.Error("OJエラー")
-		}
-		if len(p.Cases) != testcase.num_case {
-			t.Error("サンプルケースを抽出出来ていません！")
-		}
+	// 提出の一覧にはハンドルだけを使う (パスワードは読み込まない)
+	os.Setenv("AOJ_HANDLE", "kide")
+	defer os.Unsetenv("AOJ_HANDLE")
+
+	subs, err := AOJ.ListSubmissions("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 {
+		t.Fatalf("%d submissions, expected 2", len(subs))
+	}
+	if s := subs[0]; s.Status != JudgeStatusTLE || s.Problem != "ITP1_1_B" || s.Time != "2000 ms" || s.Memory != "3100 KB" {
+		t.Errorf("unexpected submission : %+v", *s)
+	}
+	if s := subs[1]; s.Status != JudgeStatusAC || s.Date.Unix() != 1585706400 {
+		t.Errorf("unexpected submission : %+v", *s)
 	}
 
-	testcases := []*tmp{
-		&tmp{
-			url:      "http://judge.u-aizu.ac.jp/onlinejudge/description.jsp?id=ITP1_1_A&lang=jp",
-			id:       "ITP1_1_A",
-			name:     "ITP1_1_A",
-			oj:       AOJ.Name(),
-			num_case: 1,
-		},
+	s, err := AOJ.GetSubmission("http://judge.u-aizu.ac.jp/onlinejudge/review.jsp?rid=4310002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != "4310002" {
+		t.Errorf("GetSubmission returned %+v", *s)
 	}
 
-	for _, t := range testcases {
-		test(t)
+	var res JudgeResult
+	if err := AOJ.judgeDetail(s.ID, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Time != "2000 ms" || res.Memory != "3100 KB" {
+		t.Errorf("unexpected detail : time %q, memory %q", res.Time, res.Memory)
+	}
+	if len(res.Cases) != 2 || res.Cases[1].Status != JudgeStatusTLE || res.Cases[1].Name != "in2.txt" {
+		t.Errorf("unexpected cases : %+v", res.Cases)
 	}
 }
 
@@ -9,29 +9,50 @@ import (
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/algon-320/KIDE/credential"
 	"github.com/algon-320/KIDE/language"
 	"github.com/algon-320/KIDE/setting"
 	"github.com/algon-320/KIDE/util"
 	"github.com/headzoo/surf/browser"
-	"gopkg.in/headzoo/surf.v1"
 )
 
 type atcoder struct {
-	name        string
-	url         string
-	loginURL    string
-	sessionFile string
+	name              string
+	url               string
+	loginURL          string
+	sessionFile       string
+	settingKey        string // 設定ファイルでの名前 (OnlineJudge.{settingKey})
+	languageCacheFile string
+	session           sessionCache // 提出の一覧を取得する時のログイン済みのブラウザ
 }
 
 // AtCoder ... オンラインジャッジ: AtCoder
 var AtCoder = &atcoder{
-	name:        "AtCoder",
-	url:         "https://atcoder.jp/",
-	loginURL:    "https://atcoder.jp/login",
-	sessionFile: "session_atcoder.dat",
+	name:              "AtCoder",
+	url:               "https://atcoder.jp/",
+	loginURL:          "https://atcoder.jp/login",
+	sessionFile:       "session_atcoder.dat",
+	settingKey:        "AtCoder",
+	languageCacheFile: "languages_atcoder.json",
 }
 
-func (ac *atcoder) getLangID(lang language.Language) (string, error) {
+func init() {
+	Register(&Registration{
+		Key:          "atcoder",
+		Judge:        AtCoder,
+		Aliases:      []string{AtCoder.settingKey},
+		Hosts:        []string{"atcoder.jp"},
+		Capabilities: CapabilityContestDownload | CapabilityCustomTest,
+		MainClass:    "Main",
+	})
+}
+
+// getLangID ... lang (profile) で提出する時の言語ID
+// 設定ファイルの OnlineJudge.AtCoder.LanguageID に指定されていればそれを、無ければデフォルトのものを返す
+func (ac *atcoder) getLangID(lang language.Language, profile string) (string, error) {
+	if id, ok := langIDFromSetting(ac.settingKey, lang, profile); ok {
+		return id, nil
+	}
 	switch lang {
 	case language.CPP:
 		return "4003", nil // C++17 (GCC 9.2.1)
@@ -53,22 +74,13 @@ func (ac *atcoder) loadAccount() (string, string) {
 		setting.Set("OnlineJudge.AtCoder.Handle", handle)
 	}
 
-	var password string
-	if tmp, ok := setting.Get("OnlineJudge.AtCoder.Password", "ATCODER_PASSWORD"); ok {
-		password = tmp.(string)
-	} else {
-		password = util.AskString("What is your AtCoder account password ?")
-		setting.Set("OnlineJudge.AtCoder.Password", password)
-	}
+	password := credential.LoadPassword("AtCoder", handle, "ATCODER_PASSWORD", "What is your AtCoder account password ?")
 
 	return handle, password
 }
 
 func (ac *atcoder) login() (*browser.Browser, error) {
-	handle, password := ac.loadAccount()
-	data := map[string]string{"username": handle, "password": password}
-
-	br := surf.NewBrowser()
+	br, rec := newSessionBrowser()
 
 	cjar := util.LoadLoginSession(ac.sessionFile, ac.url)
 	if cjar != nil {
@@ -79,8 +91,10 @@ func (ac *atcoder) login() (*browser.Browser, error) {
 		}
 	}
 
-	// 新たにログイン
+	// 新たにログイン (保存されたセッションが使えない場合だけアカウントとパスワードを読み込む)
 	fmt.Fprintln(os.Stderr, util.PrefixInfo+"login to AtCoder ...")
+	handle, password := ac.loadAccount()
+	data := map[string]string{"username": handle, "password": password}
 
 	if err := br.Open(ac.loginURL); err != nil {
 		return nil, &ErrFailedToLogin{oj_name: ac.Name(), message: "Failed to open login page."}
@@ -103,7 +117,7 @@ serch_form:
 
 		if ac.checkLoggedin(br) {
 			cookies := br.SiteCookies()
-			util.SaveLoginSession(ac.sessionFile, cookies)
+			util.SaveLoginSession(ac.sessionFile, rec.withExpiry(cookies))
 			return br, nil
 		}
 		return nil, &ErrFailedToLogin{oj_name: ac.Name(), message: "Incorrect username or password."}
@@ -112,6 +126,18 @@ serch_form:
 	return nil, &ErrFailedToLogin{oj_name: ac.Name(), message: "No form found."}
 }
 
+// accountInfo ... アカウントとセッションの情報
+func (ac *atcoder) accountInfo() accountInfo {
+	return accountInfo{
+		settingKey:  ac.settingKey,
+		envPrefix:   "ATCODER",
+		sessionFile: ac.sessionFile,
+		cookieURL:   ac.url,
+		loginURL:    ac.loginURL,
+		session:     &ac.session,
+	}
+}
+
 func (ac *atcoder) checkLoggedin(br *browser.Browser) bool {
 	prevURL := br.Url()
 	if prevURL != nil {
@@ -129,18 +155,18 @@ func (ac *atcoder) Name() string {
 	return ac.name
 }
 
-func (ac *atcoder) Submit(p *Problem, sourceCode string, lang language.Language) (*JudgeResult, error) {
+func (ac *atcoder) Submit(p *Problem, sourceCode string, lang language.Language, profile string) (*JudgeResult, error) {
 	br, err := ac.login()
 	if err != nil {
 		return nil, err
 	}
 
 	submitURL := ac.url + fmt.Sprintf("contests/%s/submit", p.ContestID)
-	if err := br.Open(submitURL); err != nil {
+	if br, err = openAuthorized(ac, br, submitURL); err != nil {
 		return nil, err
 	}
 
-	langID, err := ac.getLangID(lang)
+	langID, err := ac.getLangID(lang, profile)
 	if err != nil {
 		return nil, err
 	}
@@ -189,8 +215,12 @@ func (ac *atcoder) Submit(p *Problem, sourceCode string, lang language.Language)
 	res.Code = sourceCode
 	res.Language = lang
 	res.Status = JudgeStatusUNK
-	res.URL, _ = br.Dom().Find("tbody > tr:nth-of-type(1) > td:last-of-type").Find("a").Attr("href")
-	res.URL, _ = br.ResolveStringUrl(res.URL)
+	href, ok := br.Dom().Find("tbody > tr:nth-of-type(1) > td:last-of-type").Find("a").Attr("href")
+	if !ok {
+		// 提出一覧に提出が見つからない
+		return nil, &ErrFailedToSubmit{message: "no submit form found."}
+	}
+	res.URL, _ = br.ResolveStringUrl(href)
 
 	// get Judge Status
 	var status string
@@ -225,6 +255,9 @@ waiting:
 		case strings.Contains(status, "IE"):
 			res.Status = JudgeStatusIE
 			break waiting
+		case !strings.Contains(status, "/") && !isWaitingLabel(status):
+			// 知らない判定 (QLE など) は JudgeStatusUNK のまま終わる
+			break waiting
 		}
 
 		util.SaveCursorPos()
@@ -241,10 +274,130 @@ waiting:
 	}
 	fmt.Fprint(os.Stderr, "\n")
 
-	if res.Status != JudgeStatusUNK {
-		return &res, nil
+	// 提出の詳細 (実行時間、メモリ、テストケースごとの結果など)
+	if err := openPage(br, res.URL); err == nil {
+		scrapeJudgeDetail(br.Dom(), &res)
+	}
+
+	// 提出は出来ているので、知らない判定 (JudgeStatusUNK) でも結果として返す
+	return &res, nil
+}
+
+// fetchProblem ... problemURL の問題を br でダウンロードする (保存はしない)
+func (ac *atcoder) fetchProblem(br *browser.Browser, problemURL string) (*Problem, error) {
+	var p Problem
+	p.Oj = AtCoder
+	p.URL = problemURL
+
+	re := regexp.MustCompile(ac.url + "contests/(.+)/tasks/(.+)")
+	group := re.FindSubmatch([]byte(problemURL))
+	if group == nil {
+		return nil, &ErrInvalidProblemURL{url: problemURL}
+	}
+	p.ContestID = string(group[1])
+	p.Name = string(group[2])
+
+	if err := openPage(br, problemURL); err != nil {
+		return nil, err
+	}
+	doc := br.Dom()
+	title := doc.Find("#main-container > div > div:nth-child(2) > span").Text()
+	if title == "" {
+		return nil, fmt.Errorf("no problem title found")
+	}
+	p.ID = title[0:1]
+
+	fillLimits(&p, doc.Find("#main-container p").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return strings.Contains(s.Text(), "実行時間制限") || strings.Contains(s.Text(), "Time Limit")
+	}).First().Text())
+	statement := doc.Find("#task-statement")
+	p.Score = parseScore(statement.Text())
+	fillStatementMetadata(&p, statement)
+
+	var testCase TestCase
+	japanese := false
+	doc.Find("div.part").Each(func(_ int, s *goquery.Selection) {
+		h3Text := s.Find("h3").Text()
+		switch {
+		case strings.HasPrefix(h3Text, "入力例"):
+			japanese = true
+			testCase.Input = s.Find("pre").Text()
+			testCase.Input = html.UnescapeString(testCase.Input)
+			testCase.Input = util.AddBR(testCase.Input)
+		case strings.HasPrefix(h3Text, "出力例"):
+			testCase.Output = s.Find("pre").Text()
+			testCase.Output = html.UnescapeString(testCase.Output)
+			testCase.Output = util.AddBR(testCase.Output)
+			testCase.Sample = true
+
+			p.Cases = append(p.Cases, testCase)
+
+		case strings.HasPrefix(h3Text, "Sample Input") && !japanese:
+			testCase.Input = s.Find("pre").Text()
+			testCase.Input = html.UnescapeString(testCase.Input)
+			testCase.Input = util.AddBR(testCase.Input)
+		case strings.HasPrefix(h3Text, "Sample Output") && !japanese:
+			testCase.Output = s.Find("pre").Text()
+			testCase.Output = html.UnescapeString(testCase.Output)
+			testCase.Output = util.AddBR(testCase.Output)
+			testCase.Sample = true
+
+			p.Cases = append(p.Cases, testCase)
+		}
+	})
+	return &p, nil
+}
+
+// problemURLs ... コンテストの問題一覧ページから問題のURLの一覧を取得する
+func (ac *atcoder) problemURLs(br *browser.Browser, url string) ([]string, error) {
+	if err := openPage(br, url); err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	br.Dom().Find("tbody > tr").Each(func(_ int, tr *goquery.Selection) {
+		problemURL, ok := tr.Find("td:first-of-type > a").Attr("href")
+		if !ok {
+			return
+		}
+		problemURL, _ = br.ResolveStringUrl(problemURL)
+		ret = append(ret, problemURL)
+	})
+	return ret, nil
+}
+
+// contestStartTime ... コンテストのトップページから開始時刻を取得する
+func (ac *atcoder) contestStartTime(contestURL string) (time.Time, error) {
+	group := regexp.MustCompile(ac.url + "contests/([^/]+)").FindStringSubmatch(contestURL)
+	if group == nil {
+		return time.Time{}, &ErrInvalidContestURL{url: contestURL}
+	}
+	doc, err := getDocument(ac.url + "contests/" + group[1])
+	if err != nil {
+		return time.Time{}, err
+	}
+	text := strings.TrimSpace(doc.Find("time.fixtime-full").First().Text())
+	t, err := time.Parse("2006-01-02 15:04:05-0700", text)
+	if err != nil {
+		return time.Time{}, &ErrFailedToGetContestStartTime{message: "no start time found"}
+	}
+	return t, nil
+}
+
+// contestEndTime ... コンテストのトップページから終了時刻を取得する (開始時刻の次に書かれている)
+func (ac *atcoder) contestEndTime(contestID string) (time.Time, error) {
+	doc, err := getDocument(ac.url + "contests/" + contestID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	times := doc.Find("time.fixtime-full")
+	if times.Length() < 2 {
+		return time.Time{}, &ErrFailedToGetContestEndTime{message: "no end time found"}
+	}
+	t, err := time.Parse("2006-01-02 15:04:05-0700", strings.TrimSpace(times.Eq(1).Text()))
+	if err != nil {
+		return time.Time{}, &ErrFailedToGetContestEndTime{message: "no end time found"}
 	}
-	return nil, &ErrFailedToSubmit{message: "no submit form found."}
+	return t, nil
 }
 
 func (ac *atcoder) NewProblem(url string) error {
@@ -258,73 +411,19 @@ func (ac *atcoder) NewProblem(url string) error {
 		return err
 	}
 
-	downloadProblem := func(problem_url string) error {
-		var p Problem
-		p.Oj = AtCoder
-		p.URL = problem_url
-
-		re := regexp.MustCompile(ac.url + "contests/(.+)/tasks/(.+)")
-		group := re.FindSubmatch([]byte(problem_url))
-		if group == nil {
-			return &ErrInvalidProblemURL{url: problem_url}
-		}
-		p.ContestID = string(group[1])
-		p.Name = string(group[2])
-
-		br.Open(problem_url)
-		doc := br.Dom()
-		title := doc.Find("#main-container > div > div:nth-child(2) > span").Text()
-		p.ID = title[0:1]
-
-		var testCase TestCase
-		japanese := false
-		doc.Find("div.part").Each(func(_ int, s *goquery.Selection) {
-			h3Text := s.Find("h3").Text()
-			switch {
-			case strings.HasPrefix(h3Text, "入力例"):
-				japanese = true
-				testCase.Input = s.Find("pre").Text()
-				testCase.Input = html.UnescapeString(testCase.Input)
-				testCase.Input = util.AddBR(testCase.Input)
-			case strings.HasPrefix(h3Text, "出力例"):
-				testCase.Output = s.Find("pre").Text()
-				testCase.Output = html.UnescapeString(testCase.Output)
-				testCase.Output = util.AddBR(testCase.Output)
-				p.Cases = append(p.Cases, testCase)
-
-			case strings.HasPrefix(h3Text, "Sample Input") && !japanese:
-				testCase.Input = s.Find("pre").Text()
-				testCase.Input = html.UnescapeString(testCase.Input)
-				testCase.Input = util.AddBR(testCase.Input)
-			case strings.HasPrefix(h3Text, "Sample Output") && !japanese:
-				testCase.Output = s.Find("pre").Text()
-				testCase.Output = html.UnescapeString(testCase.Output)
-				testCase.Output = util.AddBR(testCase.Output)
-				p.Cases = append(p.Cases, testCase)
-			}
-		})
-
-		p.Print()
-		return p.Save()
-	}
-
 	if isSet {
 		util.DebugPrint("download [atcoder] problem set")
 
-		br.Open(url)
-		doc := br.Dom()
-		doc.Find("tbody > tr").Each(func(_ int, tr *goquery.Selection) {
-			problemURL, _ := tr.Find("td:first-of-type > a").Attr("href")
-			problemURL, _ = br.ResolveStringUrl(problemURL)
-			// fmt.Fprintln(os.Stderr, problemURL)
-			err := downloadProblem(problemURL)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, util.PrefixError+fmt.Sprintf("%s", err))
-			}
-		})
-		return nil
+		_, err := downloadProblemSet(ac, br, url)
+		return err
+	}
+
+	p, err := ac.fetchProblem(br, url)
+	if err != nil {
+		return err
 	}
-	return downloadProblem(url)
+	p.Print()
+	return p.Save()
 }
 
 func (ac *atcoder) IsValidURL(url string) (bool, bool) {
@@ -338,6 +437,61 @@ func (ac *atcoder) IsValidURL(url string) (bool, bool) {
 	}
 }
 
-func (ac *atcoder) MarshalJSON() ([]byte, error) {
-	return []byte(`"` + ac.Name() + `"`), nil
+// scrapeLanguages ... practice コンテストの提出フォームから言語の一覧を取得する
+func (ac *atcoder) scrapeLanguages() ([]JudgeLanguage, error) {
+	br, err := ac.login()
+	if err != nil {
+		return nil, err
+	}
+	if br, err = openAuthorized(ac, br, ac.url+"contests/practice/submit"); err != nil {
+		return nil, err
+	}
+	// 問題ごとに言語の select があるので最初のものを使う
+	sel := br.Dom().Find("div[id^='select-lang'] select").First()
+	langs := scrapeLanguageOptions(sel)
+	if len(langs) == 0 {
+		return nil, &ErrFailedToGetLanguages{oj_name: ac.Name()}
+	}
+	return langs, nil
+}
+
+// Languages ... 提出時に選択できる言語の一覧
+func (ac *atcoder) Languages(refresh bool) ([]JudgeLanguage, error) {
+	return getLanguages(ac.languageCacheFile, refresh, ac.scrapeLanguages)
+}
+
+// LanguageMappings ... 設定ファイルで言語IDに対応付けられた言語
+func (ac *atcoder) LanguageMappings() map[string][]string {
+	return languageMappings(ac.settingKey)
+}
+
+// ListSubmissions ... コンテストの自分の提出の一覧 (提出一覧ページの最初のページのみ)
+func (ac *atcoder) ListSubmissions(contest string) ([]*Submission, error) {
+	if contest == "" {
+		return nil, &ErrContestRequired{oj_name: ac.Name()}
+	}
+	br, err := ac.session.get(ac.login)
+	if err != nil {
+		return nil, err
+	}
+	if br, err = openAuthorized(ac, br, ac.url+fmt.Sprintf("contests/%s/submissions/me", contest)); err != nil {
+		return nil, err
+	}
+	return scrapeSubmissionTable(br.Dom(), func(href string) string {
+		u, _ := br.ResolveStringUrl(href)
+		return u
+	}), nil
+}
+
+// GetSubmission ... submissionURL の提出 (提出一覧ページから探す)
+func (ac *atcoder) GetSubmission(submissionURL string) (*Submission, error) {
+	group := regexp.MustCompile(ac.url + "contests/([^/]+)/submissions/[0-9]+").FindStringSubmatch(submissionURL)
+	if group == nil {
+		return nil, &ErrNoSuchSubmission{url: submissionURL}
+	}
+	subs, err := ac.ListSubmissions(group[1])
+	if err != nil {
+		return nil, err
+	}
+	return findSubmission(subs, submissionURL)
 }
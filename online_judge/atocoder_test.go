@@ -2,96 +2,183 @@ package online_judge
 
 import (
 	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/algon-320/KIDE/setting"
+	"github.com/algon-320/KIDE/util"
 )
 
+// atcoderRoutes ... AtCoder のログインの流れと記録しておいたページを返すハンドラ
+// ユーザ名 kide、パスワード password でログインすると REVEL_SESSION が設定される
+func atcoderRoutes(t *testing.T) map[string]http.HandlerFunc {
+	loggedin := func(r *http.Request) bool {
+		c, err := r.Cookie("REVEL_SESSION")
+		return err == nil && c.Value == "loggedin"
+	}
+	requireLogin := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !loggedin(r) {
+				http.Redirect(w, r, "/login?continue="+url.QueryEscape(r.URL.String()), http.StatusFound)
+				return
+			}
+			h(w, r)
+		}
+	}
+	return map[string]http.HandlerFunc{
+		"atcoder.jp/login": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "POST" {
+				r.ParseForm()
+				if r.Form.Get("csrf_token") == "dummy+csrf+token=" && r.Form.Get("username") == "kide" && r.Form.Get("password") == "password" {
+					http.SetCookie(w, &http.Cookie{Name: "REVEL_SESSION", Value: "loggedin", Path: "/", MaxAge: 3600})
+					http.Redirect(w, r, "/home", http.StatusFound)
+					return
+				}
+			}
+			fixture(t, "atcoder_login.html")(w, r)
+		},
+		"atcoder.jp/home":                                   fixture(t, "atcoder_practice_tasks.html"),
+		"atcoder.jp/contests/agc001/submit":                 requireLogin(fixture(t, "atcoder_practice_tasks.html")),
+		"atcoder.jp/contests/practice/tasks":                fixture(t, "atcoder_practice_tasks.html"),
+		"atcoder.jp/contests/practice/tasks/practice_1":     fixture(t, "atcoder_practice_1.html"),
+		"atcoder.jp/contests/practice/submissions/me":       requireLogin(fixture(t, "atcoder_submissions_me.html")),
+		"atcoder.jp/contests/practice/submissions/11000003": requireLogin(fixture(t, "atcoder_submission.html")),
+	}
+}
+
 func TestAtCoderLogin(t *testing.T) {
 	fmt.Println("testing : atcoder.go > AtCoder.login")
+	defer serveFixtures(t, atcoderRoutes(t))()
+
+	os.Setenv("ATCODER_HANDLE", "kide")
+	os.Setenv("ATCODER_PASSWORD", "password")
+	defer os.Unsetenv("ATCODER_HANDLE")
+	defer os.Unsetenv("ATCODER_PASSWORD")
+
 	br, err := AtCoder.login()
 	if err != nil {
 		t.Fatal("AtCoderのログイン処理でエラーが発生しました。", err)
 		return
 	}
-	br.Open("https://beta.atcoder.jp/settings")
-	if br.Title() != "General Settings - AtCoder" {
-		t.Fatalf("AtCoderにログイン出来ていません！ : %s", br.Title())
-		return
+	if !AtCoder.checkLoggedin(br) {
+		t.Fatal("AtCoderにログイン出来ていません！")
+	}
+
+	// 保存したセッションが使える間はアカウントとパスワードを読み込まない (尋ねない)
+	os.Unsetenv("ATCODER_HANDLE")
+	os.Unsetenv("ATCODER_PASSWORD")
+	if _, err := AtCoder.login(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := setting.Get("OnlineJudge.AtCoder.Handle", ""); ok {
+		t.Error("セッションが使えるのにアカウントを読み込んでいます")
+	}
+
+	os.Setenv("ATCODER_HANDLE", "kide")
+	os.Setenv("ATCODER_PASSWORD", "wrong password")
+	util.SaveLoginSession(AtCoder.sessionFile, nil)
+	if _, err := AtCoder.login(); err == nil {
+		t.Error("間違ったパスワードでログイン出来てしまいます")
 	}
 }
 
-func TestAtCoderNewProblem(t *testing.T) {
-	fmt.Println("testing : atcoder.go > AtCoder.NewProblem")
+func TestAtCoderFetchProblem(t *testing.T) {
+	fmt.Println("testing : atcoder.go > AtCoder.fetchProblem")
+	defer serveFixtures(t, atcoderRoutes(t))()
 
-	type tmp struct {
-		url      string
-		id       string
-		name     string
-		contest  string
-		oj       string
-		num_case int
+	problemURL := "https://atcoder.jp/contests/practice/tasks/practice_1"
+	p, err := AtCoder.fetchProblem(newBrowser(), problemURL)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	test := func(testcase *tmp) {
-		if err := AtCoder.NewProblem(testcase.url); err != nil {
-			t.Error(err)
-		}
+	if p.ID != "A" {
+		t.Error("id検出エラー")
+	}
+	if p.Name != "practice_1" {
+		t.Error("問題名検出エラー")
+	}
+	if p.ContestID != "practice" {
+		t.Error("コンテストid検出エラー")
+	}
+	if p.URL != problemURL {
+		t.Error("URLエラー")
+	}
+	if p.Oj != AtCoder {
+		t.Error("OJエラー")
+	}
+	// 日本語と英語の両方にサンプルがあるが、日本語のものだけを使う
+	if len(p.Cases) != 2 {
+		t.Fatalf("サンプルケースを抽出出来ていません！ (%d cases)", len(p.Cases))
+	}
+	if p.Cases[1].Input != "72\n128 256\nmyonmyon\n" || p.Cases[1].Output != "456 myonmyon\n" || !p.Cases[1].Sample {
+		t.Errorf("サンプルケースの内容が違います : %+v", p.Cases[1])
+	}
+	if p.TimeLimitMs != 2000 || p.MemoryLimitMB != 1024 || p.Score != 100 || p.Interactive {
+		t.Errorf("問題の情報が違います : %v", p.Metadata())
+	}
+}
 
-		p, err := LoadProblem(testcase.id)
-		if err != nil {
-			t.Error(err)
-			return
-		}
+func TestAtCoderProblemURLs(t *testing.T) {
+	fmt.Println("testing : atcoder.go > AtCoder.problemURLs")
+	defer serveFixtures(t, atcoderRoutes(t))()
 
-		if p.ID != testcase.id {
-			t.Error("id検出エラー")
-		}
-		if p.Name != testcase.name {
-			t.Error("問題名検出エラー")
-		}
-		if p.ContestID != testcase.contest {
-			t.Error("コンテストid検出エラー")
-		}
-		if p.URL != testcase.url {
-			t.Error("URLエラー")
-		}
-		if p.Oj.Name() != testcase.oj {
-			t.Error("OJエラー")
-		}
-		if len(p.Cases) != testcase.num_case {
-			t.Error("サンプルケースを抽出出来ていません！")
-		}
+	urls, err := AtCoder.problemURLs(newBrowser(), "https://atcoder.jp/contests/practice/tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"https://atcoder.jp/contests/practice/tasks/practice_1",
+		"https://atcoder.jp/contests/practice/tasks/practice_2",
+	}
+	if !reflect.DeepEqual(urls, expect) {
+		t.Errorf("problemURLs returned %v, expected %v", urls, expect)
 	}
+}
 
-	testcases := []*tmp{
-		&tmp{
-			url:      "https://beta.atcoder.jp/contests/chokudai_S001/tasks/chokudai_S001_a",
-			id:       "A",
-			name:     "chokudai_S001_a",
-			contest:  "chokudai_S001",
-			oj:       AtCoder.Name(),
-			num_case: 4,
-		},
-		&tmp{
-			url:      "https://beta.atcoder.jp/contests/arc002/tasks/arc002_1",
-			id:       "A",
-			name:     "arc002_1",
-			contest:  "arc002",
-			oj:       AtCoder.Name(),
-			num_case: 4,
-		},
-		// TODO: ARC001では失敗する
-		// &tmp{
-		//   url:"https://beta.atcoder.jp/contests/arc001/tasks/arc001_1",
-		//   id:"A",
-		//   name:"arc001_1",
-		//   contest:"arc001",
-		//   oj:AtCoder.Name(),
-		//   num_case:3,
-		// },
+func TestAtCoderSubmissions(t *testing.T) {
+	fmt.Println("testing : atcoder.go > AtCoder.ListSubmissions")
+	routes := atcoderRoutes(t)
+	defer serveFixtures(t, routes)()
+
+	// ログイン済みのブラウザを使う
+	br := newBrowser()
+	br.PostForm("https://atcoder.jp/login", url.Values{"csrf_token": {"dummy+csrf+token="}, "username": {"kide"}, "password": {"password"}})
+	AtCoder.session.br = br
+	defer func() { AtCoder.session.br = nil }()
+
+	subs, err := AtCoder.ListSubmissions("practice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 {
+		t.Fatalf("%d submissions, expected 2", len(subs))
+	}
+	if !subs[0].Waiting || subs[0].Verdict != "3/13 WJ" {
+		t.Errorf("unexpected judging submission : %+v", *subs[0])
+	}
+	if s := subs[1]; s.Waiting || s.Status != JudgeStatusAC || s.Time != "7 ms" || s.URL != "https://atcoder.jp/contests/practice/submissions/11000001" {
+		t.Errorf("unexpected submission : %+v", *s)
+	}
+	if _, err := AtCoder.ListSubmissions(""); err == nil {
+		t.Error("コンテストを指定しなくてもエラーになりません")
 	}
 
-	for _, t := range testcases {
-		test(t)
+	// 提出の詳細
+	if err := br.Open("https://atcoder.jp/contests/practice/submissions/11000003"); err != nil {
+		t.Fatal(err)
+	}
+	var res JudgeResult
+	scrapeJudgeDetail(br.Dom(), &res)
+	if res.Score != "0" || res.Time != "2205 ms" || res.Memory != "3588 KB" || !strings.Contains(res.CompileMessage, "unused variable") {
+		t.Errorf("unexpected detail : %+v", res)
+	}
+	if len(res.Cases) != 4 || res.Cases[3].Status != JudgeStatusTLE || res.Cases[3].Name != "subtask_1_2.txt" {
+		t.Errorf("unexpected cases : %+v", res.Cases)
 	}
 }
 
@@ -104,11 +191,11 @@ func TestAtCoderIsValidURL(t *testing.T) {
 	}
 
 	testcase := map[string]res{
-		"https://atcoder.jp/post/37":                             res{isVaild: false, isProblemSet: false},
-		"https://beta.atcoder.jp/contests/abc070":                res{isVaild: false, isProblemSet: false},
-		"https://beta.atcoder.jp/contests/abc070/tasks":          res{isVaild: true, isProblemSet: true},
-		"https://beta.atcoder.jp/contests/abc070/tasks/abc070_a": res{isVaild: true, isProblemSet: false},
-		"https://beta.atcoder.jp/contests/abc070/clarifications": res{isVaild: false, isProblemSet: false},
+		"https://atcoder.jp/post/37":                        res{isVaild: false, isProblemSet: false},
+		"https://atcoder.jp/contests/abc070":                res{isVaild: false, isProblemSet: false},
+		"https://atcoder.jp/contests/abc070/tasks":          res{isVaild: true, isProblemSet: true},
+		"https://atcoder.jp/contests/abc070/tasks/abc070_a": res{isVaild: true, isProblemSet: false},
+		"https://atcoder.jp/contests/abc070/clarifications": res{isVaild: false, isProblemSet: false},
 	}
 
 	for k, v := range testcase {
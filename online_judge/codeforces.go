@@ -3,37 +3,59 @@ package online_judge
 import (
 	"fmt"
 	"html"
+	neturl "net/url"
 	"os"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/algon-320/KIDE/credential"
 	"github.com/algon-320/KIDE/language"
 	"github.com/algon-320/KIDE/setting"
 	"github.com/algon-320/KIDE/util"
 	"github.com/headzoo/surf/browser"
-	"gopkg.in/headzoo/surf.v1"
 )
 
 type codeforces struct {
-	name        string
-	url         string
-	loginURL    string
-	sessionFile string
+	name              string
+	url               string
+	loginURL          string
+	sessionFile       string
+	settingKey        string // 設定ファイルでの名前 (OnlineJudge.{settingKey})
+	languageCacheFile string
+	session           sessionCache // 提出の一覧を取得する時のログイン済みのブラウザ
+	status            *cfStatusSource
 }
 
 // Codeforces ... オンラインジャッジ: Codeforces
 var Codeforces = &codeforces{
-	name:        "Codeforces",
-	url:         "https://codeforces.com/",
-	loginURL:    "https://codeforces.com/enter",
-	sessionFile: "session_codeforces.dat",
+	name:              "Codeforces",
+	url:               "https://codeforces.com/",
+	loginURL:          "https://codeforces.com/enter",
+	sessionFile:       "session_codeforces.dat",
+	settingKey:        "Codeforces",
+	languageCacheFile: "languages_codeforces.json",
 }
 
-func (cf *codeforces) getLangID(lang language.Language) (string, error) {
+func init() {
+	Register(&Registration{
+		Key:          "codeforces",
+		Judge:        Codeforces,
+		Aliases:      []string{Codeforces.settingKey},
+		Hosts:        []string{"codeforces.com"},
+		Capabilities: CapabilityContestDownload | CapabilityCustomTest | CapabilityAPI,
+		MainClass:    "",
+	})
+}
+
+// getLangID ... lang (profile) で提出する時の言語ID
+// 設定ファイルの OnlineJudge.Codeforces.LanguageID に指定されていればそれを、無ければデフォルトのものを返す
+func (cf *codeforces) getLangID(lang language.Language, profile string) (string, error) {
+	if id, ok := langIDFromSetting(cf.settingKey, lang, profile); ok {
+		return id, nil
+	}
 	switch lang {
 	case language.CPP:
 		return "50", nil // 50 : GNU G++14 6.2.0
@@ -48,31 +70,25 @@ func (cf *codeforces) getLangID(lang language.Language) (string, error) {
 	}
 }
 
-func (cf *codeforces) loadAccount() (string, string) {
-	var handle string
+// loadHandle ... アカウントのIDを読み込む (パスワードは読み込まないので、保存したパスワードの復号は求められない)
+func (cf *codeforces) loadHandle() string {
 	if tmp, ok := setting.Get("OnlineJudge.Codeforces.Handle", "CODEFORCES_HANDLE"); ok {
-		handle = tmp.(string)
-	} else {
-		handle = util.AskString("What is your Codeforces account id ?")
-		setting.Set("OnlineJudge.Codeforces.Handle", handle)
+		return tmp.(string)
 	}
+	handle := util.AskString("What is your Codeforces account id ?")
+	setting.Set("OnlineJudge.Codeforces.Handle", handle)
+	return handle
+}
 
-	var password string
-	if tmp, ok := setting.Get("OnlineJudge.Codeforces.Password", "CODEFORCES_PASSWORD"); ok {
-		password = tmp.(string)
-	} else {
-		password = util.AskString("What is your Codeforces account password ?")
-		setting.Set("OnlineJudge.Codeforces.Password", password)
-	}
+func (cf *codeforces) loadAccount() (string, string) {
+	handle := cf.loadHandle()
+	password := credential.LoadPassword("Codeforces", handle, "CODEFORCES_PASSWORD", "What is your Codeforces account password ?")
 
 	return handle, password
 }
 
 func (cf *codeforces) login() (*browser.Browser, error) {
-	handle, password := cf.loadAccount()
-	data := map[string]string{"handleOrEmail": handle, "password": password}
-
-	br := surf.NewBrowser()
+	br, rec := newSessionBrowser()
 
 	cjar := util.LoadLoginSession(cf.sessionFile, cf.url)
 	if cjar != nil {
@@ -83,8 +99,10 @@ func (cf *codeforces) login() (*browser.Browser, error) {
 		}
 	}
 
-	// 新たにログイン
+	// 新たにログイン (保存されたセッションが使えない場合だけアカウントとパスワードを読み込む)
 	fmt.Fprintln(os.Stderr, util.PrefixInfo+"Login to Codeforces...")
+	handle, password := cf.loadAccount()
+	data := map[string]string{"handleOrEmail": handle, "password": password}
 
 	if err := br.Open(cf.loginURL); err != nil {
 		return nil, &ErrFailedToLogin{oj_name: cf.Name(), message: "Failed to open login page."}
@@ -107,7 +125,7 @@ serch_form:
 
 		if cf.checkLoggedin(br) {
 			cookies := br.SiteCookies()
-			util.SaveLoginSession(cf.sessionFile, cookies)
+			util.SaveLoginSession(cf.sessionFile, rec.withExpiry(cookies))
 			return br, nil
 		}
 		return nil, &ErrFailedToLogin{oj_name: cf.Name(), message: "Incorrect username or password."}
@@ -116,6 +134,18 @@ serch_form:
 	return nil, &ErrFailedToLogin{oj_name: cf.Name(), message: "No form found."}
 }
 
+// accountInfo ... アカウントとセッションの情報
+func (cf *codeforces) accountInfo() accountInfo {
+	return accountInfo{
+		settingKey:  cf.settingKey,
+		envPrefix:   "CODEFORCES",
+		sessionFile: cf.sessionFile,
+		cookieURL:   cf.url,
+		loginURL:    cf.loginURL,
+		session:     &cf.session,
+	}
+}
+
 func (cf *codeforces) checkLoggedin(br *browser.Browser) bool {
 	prevURL := br.Url()
 	if prevURL != nil {
@@ -132,18 +162,18 @@ func (cf *codeforces) Name() string {
 	return cf.name
 }
 
-func (cf *codeforces) Submit(p *Problem, sourceCode string, lang language.Language) (*JudgeResult, error) {
+func (cf *codeforces) Submit(p *Problem, sourceCode string, lang language.Language, profile string) (*JudgeResult, error) {
 	br, err := cf.login()
 	if err != nil {
 		return nil, err
 	}
 
 	submitURL := cf.url + fmt.Sprintf("contest/%s/submit", p.ContestID)
-	if err := br.Open(submitURL); err != nil {
+	if br, err = openAuthorized(cf, br, submitURL); err != nil {
 		return nil, err
 	}
 
-	langID, err := cf.getLangID(lang)
+	langID, err := cf.getLangID(lang, profile)
 	if err != nil {
 		return nil, err
 	}
@@ -182,54 +212,196 @@ func (cf *codeforces) Submit(p *Problem, sourceCode string, lang language.Langua
 	res.Code = sourceCode
 	res.Language = lang
 	res.Status = JudgeStatusUNK
-	res.URL, _ = br.Dom().Find(".status-frame-datatable").Find("tr:nth-of-type(2) > td:nth-of-type(1) > a").Attr("href")
-	res.URL, _ = br.ResolveStringUrl(res.URL)
+
+	// 提出一覧の一番上が今の提出
+	tmp, _ := br.Dom().Find(".status-frame-datatable").Find("tr[data-submission-id]").First().Attr("data-submission-id")
+	submissionID, _ := strconv.ParseInt(tmp, 10, 64)
+	res.URL = cf.url + fmt.Sprintf("contest/%s/submission/%d", p.ContestID, submissionID)
 
 	// get Judge Status
-	var status string
+	src := cf.newStatusSource(br)
+	var sub *cfSubmission
 	for {
-		br.Open(mysubmissionsURL)
-		verdict := br.Dom().Find(".status-frame-datatable").Find("tr:nth-of-type(2) > td:nth-of-type(6)")
-
-		status = verdict.Find("span.submissionVerdictWrapper").First().Text()
-		v, _ := verdict.Attr("waiting")
-		if v == "false" {
+		subs, err := src.submissions(p.ContestID)
+		if err != nil {
+			return nil, err
+		}
+		sub = nil
+		for i := range subs {
+			if subs[i].ID == submissionID {
+				sub = &subs[i]
+				break
+			}
+		}
+		if sub == nil {
+			return nil, &ErrFailedToSubmit{message: fmt.Sprintf("submission %d is not found.", submissionID)}
+		}
+		if !sub.waiting() {
 			break
 		}
 
 		util.SaveCursorPos()
 		{
-			fmt.Fprint(os.Stderr, util.ESCS_COL_REVERSE+status+util.ESCS_COL_OFF)
+			fmt.Fprint(os.Stderr, util.ESCS_COL_REVERSE+sub.verdictText()+util.ESCS_COL_OFF)
 
-			time.Sleep(CheckInterval)
+			time.Sleep(src.checkInterval())
 
 			util.ClearCurrentLine()
 		}
 		util.RestoreCursorPos()
 	}
 
-	switch {
-	case strings.HasPrefix(status, "Accepted"):
-		res.Status = JudgeStatusAC
-	case strings.HasPrefix(status, "Pretests passed"):
-		res.Status = JudgeStatusPP
-	case strings.HasPrefix(status, "Wrong answer"):
-		res.Status = JudgeStatusWA
-	case strings.HasPrefix(status, "Compilation error"):
-		res.Status = JudgeStatusCE
-	case strings.HasPrefix(status, "Runtime error"):
-		res.Status = JudgeStatusRE
-	case strings.HasPrefix(status, "Time limit exceeded"):
-		res.Status = JudgeStatusTLE
-	case strings.HasPrefix(status, "Memory limit exceeded"):
-		res.Status = JudgeStatusMLE
-	default:
-		res.Status = JudgeStatusUNK
+	res.Status = sub.status()
+	res.Time = fmt.Sprintf("%d ms", sub.TimeConsumedMillis)
+	res.Memory = fmt.Sprintf("%d KB", sub.MemoryConsumedBytes/1024)
+	if sub.Points > 0 {
+		res.Score = strconv.FormatFloat(sub.Points, 'f', -1, 64)
 	}
+	fmt.Fprintln(os.Stderr, util.PrefixInfo+sub.verdictText()+" ("+sub.detailText()+")")
 
 	return &res, nil
 }
 
+// fetchProblem ... problemURL の問題を br でダウンロードする (保存はしない)
+func (cf *codeforces) fetchProblem(br *browser.Browser, problemURL string) (*Problem, error) {
+	var p Problem
+	p.Oj = Codeforces
+	p.URL = problemURL
+
+	re1 := regexp.MustCompile(cf.url + "contest/(.+)/problem/(.+)")
+	re2 := regexp.MustCompile(cf.url + "problemset/problem/(.+)/(.+)")
+	group := re1.FindSubmatch([]byte(problemURL))
+	if group == nil {
+		group = re2.FindSubmatch([]byte(problemURL))
+		if group == nil {
+			return nil, &ErrInvalidProblemURL{url: problemURL}
+		}
+	}
+
+	p.ContestID = string(group[1]) // contest no.
+	p.ID = string(group[2])        // A, B, C, and so on.
+	p.Name = string(group[1]) + "_" + string(group[2])
+
+	if err := openPage(br, problemURL); err != nil {
+		return nil, err
+	}
+	doc := br.Dom()
+
+	p.TimeLimitMs = parseTimeLimit(doc.Find(".problem-statement .time-limit").Text())
+	p.MemoryLimitMB = parseMemoryLimit(doc.Find(".problem-statement .memory-limit").Text())
+	fillStatementMetadata(&p, doc.Find(".problem-statement"))
+	doc.Find("span.tag-box").Each(func(_ int, s *goquery.Selection) {
+		// `*1600` のような難易度はタグにしない
+		if tag := strings.TrimSpace(s.Text()); tag != "" && !cfDifficultyTagRegexp.MatchString(tag) {
+			p.Tags = append(p.Tags, tag)
+		}
+	})
+
+	var testCase TestCase
+	doc.Find("div.sample-test > div").Each(func(_ int, s *goquery.Selection) {
+		if s.HasClass("input") {
+			pre, _ := goquery.OuterHtml(s.Find("pre"))
+			pre = strings.Replace(pre, "<br/>", "\n", -1)
+			testCase.Input = pre[5 : len(pre)-6] // <pre>と</pre>を取り除く
+			testCase.Input = html.UnescapeString(testCase.Input)
+			testCase.Input = util.AddBR(testCase.Input)
+
+		} else if s.HasClass("output") {
+			pre, _ := goquery.OuterHtml(s.Find("pre"))
+			pre = strings.Replace(pre, "<br/>", "\n", -1)
+			testCase.Output = pre[5 : len(pre)-6] // <pre>と</pre>を取り除く
+			testCase.Output = html.UnescapeString(testCase.Output)
+			testCase.Output = util.AddBR(testCase.Output)
+			testCase.Sample = true
+
+			p.Cases = append(p.Cases, testCase)
+		}
+	})
+	return &p, nil
+}
+
+// problemURLs ... コンテストの問題一覧ページから問題のURLの一覧を取得する
+func (cf *codeforces) problemURLs(br *browser.Browser, url string) ([]string, error) {
+	if err := openPage(br, url); err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	br.Dom().Find("table.problems").Find("tr").Each(func(i int, tr *goquery.Selection) {
+		if i == 0 {
+			return
+		}
+		problemURL, ok := tr.Find("td:first-of-type > a").Attr("href")
+		if !ok {
+			return
+		}
+		problemURL, _ = br.ResolveStringUrl(problemURL)
+		ret = append(ret, problemURL)
+	})
+	return ret, nil
+}
+
+// contestStartTime ... コンテスト一覧ページのコンテストの行から開始時刻を取得する
+// 開始時刻は timeanddate.com へのリンクにモスクワ時間 (UTC+3) で書かれている
+func (cf *codeforces) contestStartTime(contestURL string) (time.Time, error) {
+	group := regexp.MustCompile(cf.url + "contest/([0-9]+)").FindStringSubmatch(contestURL)
+	if group == nil {
+		return time.Time{}, &ErrInvalidContestURL{url: contestURL}
+	}
+	doc, err := getDocument(cf.url + "contests/" + group[1])
+	if err != nil {
+		return time.Time{}, err
+	}
+	href, ok := doc.Find("a[href*='timeanddate.com/worldclock/fixedtime.html']").First().Attr("href")
+	if !ok {
+		return time.Time{}, &ErrFailedToGetContestStartTime{message: "no start time found"}
+	}
+	u, err := neturl.Parse(href)
+	if err != nil {
+		return time.Time{}, &ErrFailedToGetContestStartTime{message: err.Error()}
+	}
+	q := u.Query()
+	v := map[string]int{}
+	for _, k := range []string{"year", "month", "day", "hour", "min", "sec"} {
+		n, err := strconv.Atoi(q.Get(k))
+		if err != nil {
+			return time.Time{}, &ErrFailedToGetContestStartTime{message: "invalid link `" + href + "`"}
+		}
+		v[k] = n
+	}
+	msk := time.FixedZone("MSK", 3*60*60)
+	return time.Date(v["year"], time.Month(v["month"]), v["day"], v["hour"], v["min"], v["sec"], 0, msk), nil
+}
+
+var cfContestIDRegexp = regexp.MustCompile(`^[0-9]+$`)
+
+// cfDifficultyTagRegexp ... 問題のタグの欄に表示される難易度 (`*1600`)
+var cfDifficultyTagRegexp = regexp.MustCompile(`^\*[0-9]+$`)
+
+// contestEndTime ... contest.standings で取得したコンテストの開始時刻と長さから終了時刻を返す
+func (cf *codeforces) contestEndTime(contestID string) (time.Time, error) {
+	if !cfContestIDRegexp.MatchString(contestID) {
+		return time.Time{}, &ErrInvalidContestURL{url: cf.url + "contest/" + contestID}
+	}
+	var standings struct {
+		Contest struct {
+			StartTimeSeconds int64 `json:"startTimeSeconds"`
+			DurationSeconds  int64 `json:"durationSeconds"`
+		} `json:"contest"`
+	}
+	params := neturl.Values{}
+	params.Set("contestId", contestID)
+	params.Set("from", "1")
+	params.Set("count", "1")
+	if err := newCodeforcesAPI().call("contest.standings", params, &standings); err != nil {
+		return time.Time{}, err
+	}
+	c := standings.Contest
+	if c.StartTimeSeconds == 0 {
+		return time.Time{}, &ErrFailedToGetContestEndTime{message: "contest `" + contestID + "` has not been scheduled"}
+	}
+	return time.Unix(c.StartTimeSeconds+c.DurationSeconds, 0), nil
+}
+
 func (cf *codeforces) NewProblem(url string) error {
 	isValid, isSet := cf.IsValidURL(url)
 	if !isValid {
@@ -241,71 +413,19 @@ func (cf *codeforces) NewProblem(url string) error {
 		return err
 	}
 
-	downloadProblem := func(problemURL string) error {
-		var p Problem
-		p.Oj = Codeforces
-		p.URL = problemURL
-
-		re1 := regexp.MustCompile(cf.url + "contest/(.+)/problem/(.+)")
-		re2 := regexp.MustCompile(cf.url + "problemset/problem/(.+)/(.+)")
-		group := re1.FindSubmatch([]byte(problemURL))
-		if group == nil {
-			group = re2.FindSubmatch([]byte(problemURL))
-			if group == nil {
-				return &ErrInvalidProblemURL{url: problemURL}
-			}
-		}
-
-		p.ContestID = string(group[1]) // contest no.
-		p.ID = string(group[2])        // A, B, C, and so on.
-		p.Name = string(group[1]) + "_" + string(group[2])
-
-		br.Open(problemURL)
-		doc := br.Dom()
-
-		var testCase TestCase
-		doc.Find("div.sample-test > div").Each(func(_ int, s *goquery.Selection) {
-			if s.HasClass("input") {
-				pre, _ := goquery.OuterHtml(s.Find("pre"))
-				pre = strings.Replace(pre, "<br/>", "\n", -1)
-				testCase.Input = pre[5 : len(pre)-6] // <pre>と</pre>を取り除く
-				testCase.Input = html.UnescapeString(testCase.Input)
-				testCase.Input = util.AddBR(testCase.Input)
-
-			} else if s.HasClass("output") {
-				pre, _ := goquery.OuterHtml(s.Find("pre"))
-				pre = strings.Replace(pre, "<br/>", "\n", -1)
-				testCase.Output = pre[5 : len(pre)-6] // <pre>と</pre>を取り除く
-				testCase.Output = html.UnescapeString(testCase.Output)
-				testCase.Output = util.AddBR(testCase.Output)
-				p.Cases = append(p.Cases, testCase)
-			}
-		})
-
-		p.Print()
-		return p.Save()
-	}
-
 	if isSet {
 		util.DebugPrint("download [codeforces] problem set")
 
-		br.Open(url)
-		doc := br.Dom()
-		doc.Find("table.problems").Find("tr").Each(func(i int, tr *goquery.Selection) {
-			if i == 0 {
-				return
-			}
-			problemURL, _ := tr.Find("td:first-of-type > a").Attr("href")
-			problemURL, _ = br.ResolveStringUrl(problemURL)
-			// fmt.Println(problemURL)
-			err := downloadProblem(problemURL)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, util.PrefixError+fmt.Sprintf("%s", err))
-			}
-		})
-		return nil
+		_, err := downloadProblemSet(cf, br, url)
+		return err
 	}
-	return downloadProblem(url)
+
+	p, err := cf.fetchProblem(br, url)
+	if err != nil {
+		return err
+	}
+	p.Print()
+	return p.Save()
 }
 
 func (cf *codeforces) IsValidURL(url string) (bool, bool) {
@@ -325,116 +445,88 @@ func (cf *codeforces) IsValidURL(url string) (bool, bool) {
 	}
 }
 
-func (cf *codeforces) MarshalJSON() ([]byte, error) {
-	return []byte(`"` + cf.Name() + `"`), nil
-}
-
-func (cf *codeforces) ShowMySubmissions(contestID int) {
+// scrapeLanguages ... 提出フォームから言語の一覧を取得する
+func (cf *codeforces) scrapeLanguages() ([]JudgeLanguage, error) {
 	br, err := cf.login()
 	if err != nil {
-		return
+		return nil, err
 	}
-
-	mysubmissionsURL := cf.url + fmt.Sprintf("contest/%d/my", contestID)
-	if err := br.Open(mysubmissionsURL); err != nil {
-		return
+	if br, err = openAuthorized(cf, br, cf.url+"contest/1/submit"); err != nil {
+		return nil, err
 	}
+	langs := scrapeLanguageOptions(br.Dom().Find("select[name='programTypeId']").First())
+	if len(langs) == 0 {
+		return nil, &ErrFailedToGetLanguages{oj_name: cf.Name()}
+	}
+	return langs, nil
+}
 
-	judgeFinished := make(map[int]struct{})
-	waitingTotal := -1
-
-	for {
-		br.Open(mysubmissionsURL)
-		trs := br.Dom().Find(".status-frame-datatable").Find("tr")
-
-		submissionIDs := []int{}
-		statuses := map[int]JudgeStatus{}
-		problems := map[int]string{}
-		results := map[int]string{}
-
-		currentWaitingCount := 0
-		trs.Each(func(i int, tr *goquery.Selection) {
-			if cl, ok := tr.Attr("class"); ok && cl == "first-row" {
-				return
-			}
-
-			var submissionID int
-			if tmp, ok := tr.Attr("data-submission-id"); ok {
-				submissionID, _ = strconv.Atoi(tmp)
-			} else {
-				return
-			}
-			problems[submissionID] = strings.TrimSpace(tr.Find("td:nth-of-type(4)").Text())
-
-			isWaiting, _ := tr.Find("td.status-verdict-cell").First().Attr("waiting")
-			if isWaiting == "true" {
-				currentWaitingCount++
-				statuses[submissionID] = JudgeStatusUNK
-				return
-			}
-
-			submissionIDs = append(submissionIDs, submissionID)
-
-			var status JudgeStatus
-			span := tr.Find("span.submissionVerdictWrapper").First()
-			result := strings.TrimSpace(span.Text())
-			switch {
-			case strings.HasPrefix(result, "Accepted"):
-				status = JudgeStatusAC
-			case strings.HasPrefix(result, "Pretests passed"):
-				status = JudgeStatusPP
-			case strings.HasPrefix(result, "Wrong answer"):
-				status = JudgeStatusWA
-			case strings.HasPrefix(result, "Compilation error"):
-				status = JudgeStatusCE
-			case strings.HasPrefix(result, "Runtime error"):
-				status = JudgeStatusRE
-			case strings.HasPrefix(result, "Time limit exceeded"):
-				status = JudgeStatusTLE
-			case strings.HasPrefix(result, "Memory limit exceeded"):
-				status = JudgeStatusMLE
-			default:
-				status = JudgeStatusUNK
-			}
-			statuses[submissionID] = status
-			results[submissionID] = result
-		})
-
-		sort.Slice(submissionIDs, func(i, j int) bool { return submissionIDs[i] < submissionIDs[j] })
-
-		for _, k := range submissionIDs {
-			v := statuses[k]
-
-			// ジャッジ待ちのものはスキップ
-			if _, ok := statuses[k]; !ok {
-				continue
-			}
+// Languages ... 提出時に選択できる言語の一覧
+func (cf *codeforces) Languages(refresh bool) ([]JudgeLanguage, error) {
+	return getLanguages(cf.languageCacheFile, refresh, cf.scrapeLanguages)
+}
 
-			if _, ok := judgeFinished[k]; !ok {
-				fmt.Println("url: " + cf.url + fmt.Sprintf("contest/%d/submission/%d", contestID, k))
-				fmt.Println("\tproblem: " + problems[k])
-				fmt.Println("\tverdict: " + v.GetColorESCS() + results[k] + util.ESCS_COL_OFF)
-				fmt.Println()
-				judgeFinished[k] = struct{}{} // ジャッジ済み
-			}
-		}
+// LanguageMappings ... 設定ファイルで言語IDに対応付けられた言語
+func (cf *codeforces) LanguageMappings() map[string][]string {
+	return languageMappings(cf.settingKey)
+}
 
-		if currentWaitingCount == 0 {
-			break
-		}
+// statusSource ... 提出の一覧の取得に使う cfStatusSource (一度作ったものを使い回す)
+func (cf *codeforces) statusSource() (*cfStatusSource, error) {
+	br, err := cf.session.get(cf.login)
+	if err != nil {
+		return nil, err
+	}
+	cf.session.mu.Lock()
+	defer cf.session.mu.Unlock()
+	if cf.status == nil {
+		cf.status = cf.newStatusSource(br)
+	}
+	return cf.status, nil
+}
 
-		if waitingTotal == -1 {
-			waitingTotal = currentWaitingCount
+// ListSubmissions ... コンテストの自分の提出の一覧 (contest が空文字列の場合は全てのコンテストの最近の提出)
+func (cf *codeforces) ListSubmissions(contest string) ([]*Submission, error) {
+	src, err := cf.statusSource()
+	if err != nil {
+		return nil, err
+	}
+	var subs []cfSubmission
+	if contest == "" {
+		if src.fallback {
+			return nil, &ErrContestRequired{oj_name: cf.Name()}
 		}
+		subs, err = src.api.userStatus(src.handle, statusRows)
+	} else {
+		subs, err = src.submissions(contest)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Submission, len(subs))
+	for i := range subs {
+		ret[i] = subs[i].submission()
+	}
+	return ret, nil
+}
 
-		util.SaveCursorPos() // カーソル位置を保存
-		{
-			fmt.Print(util.ESCS_COL_REVERSE + "waiting for judge (" + strconv.Itoa(waitingTotal-currentWaitingCount) + "/" + strconv.Itoa(waitingTotal) + ")" + util.ESCS_COL_OFF)
-
-			time.Sleep(1 * time.Minute) // 1分ごとに確認
+// GetSubmission ... submissionURL の提出 (コンテストの提出の一覧から探す)
+func (cf *codeforces) GetSubmission(submissionURL string) (*Submission, error) {
+	group := regexp.MustCompile(cf.url + "contest/([0-9]+)/submission/[0-9]+").FindStringSubmatch(submissionURL)
+	if group == nil {
+		return nil, &ErrNoSuchSubmission{url: submissionURL}
+	}
+	subs, err := cf.ListSubmissions(group[1])
+	if err != nil {
+		return nil, err
+	}
+	return findSubmission(subs, submissionURL)
+}
 
-			util.ClearCurrentLine()
-		}
-		util.RestoreCursorPos()
+// statusInterval ... 提出の状態を確認する間隔
+func (cf *codeforces) statusInterval() time.Duration {
+	if cf.status != nil {
+		return cf.status.checkInterval()
 	}
+	return CheckInterval
 }
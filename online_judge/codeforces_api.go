@@ -0,0 +1,316 @@
+package online_judge
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/algon-320/KIDE/setting"
+	"github.com/algon-320/KIDE/util"
+	"github.com/headzoo/surf/browser"
+)
+
+// codeforcesAPI ... Codeforces の公式API (https://codeforces.com/apiHelp) のクライアント
+// OnlineJudge.Codeforces.APIKey, APISecret が設定されている場合はリクエストに署名する (非公開のコンテストの提出も見られる)
+type codeforcesAPI struct {
+	baseURL string
+	key     string
+	secret  string
+	client  *http.Client
+}
+
+// newCodeforcesAPI ... 設定からAPIのクライアントを作る
+func newCodeforcesAPI() *codeforcesAPI {
+	api := &codeforcesAPI{
+		baseURL: Codeforces.url + "api/",
+		client:  httpClient(nil),
+	}
+	if tmp, ok := setting.Get("OnlineJudge.Codeforces.APIKey", "CODEFORCES_API_KEY"); ok {
+		api.key, _ = tmp.(string)
+	}
+	if tmp, ok := setting.Get("OnlineJudge.Codeforces.APISecret", "CODEFORCES_API_SECRET"); ok {
+		api.secret, _ = tmp.(string)
+	}
+	return api
+}
+
+// cfSubmission ... APIの Submission オブジェクト
+type cfSubmission struct {
+	ID                  int64   `json:"id"`
+	ContestID           int     `json:"contestId"`
+	CreationTimeSeconds int64   `json:"creationTimeSeconds"`
+	ProgrammingLanguage string  `json:"programmingLanguage"`
+	Verdict             string  `json:"verdict"` // ジャッジ中は空か "TESTING"
+	Testset             string  `json:"testset"`
+	PassedTestCount     int     `json:"passedTestCount"`
+	TimeConsumedMillis  int     `json:"timeConsumedMillis"`
+	MemoryConsumedBytes int64   `json:"memoryConsumedBytes"`
+	Points              float64 `json:"points"` // 部分点のあるコンテストのみ
+	Problem             struct {
+		ContestID int    `json:"contestId"`
+		Index     string `json:"index"`
+		Name      string `json:"name"`
+	} `json:"problem"`
+}
+
+// waiting ... ジャッジ中かどうか
+func (s *cfSubmission) waiting() bool {
+	return s.Verdict == "" || s.Verdict == "TESTING"
+}
+
+// status ... 判定を JudgeStatus にする
+func (s *cfSubmission) status() JudgeStatus {
+	switch s.Verdict {
+	case "OK":
+		if s.Testset == "PRETESTS" {
+			return JudgeStatusPP
+		}
+		return JudgeStatusAC
+	case "WRONG_ANSWER", "PRESENTATION_ERROR", "CHALLENGED":
+		return JudgeStatusWA
+	case "COMPILATION_ERROR":
+		return JudgeStatusCE
+	case "RUNTIME_ERROR":
+		return JudgeStatusRE
+	case "TIME_LIMIT_EXCEEDED", "IDLENESS_LIMIT_EXCEEDED":
+		return JudgeStatusTLE
+	case "MEMORY_LIMIT_EXCEEDED":
+		return JudgeStatusMLE
+	case "CRASHED", "INPUT_PREPARATION_CRASHED", "FAILED":
+		return JudgeStatusIE
+	default:
+		return JudgeStatusUNK
+	}
+}
+
+// verdictText ... サイトの表示と同じような判定の文字列 (`Wrong answer on test 3` など)
+func (s *cfSubmission) verdictText() string {
+	onTest := fmt.Sprintf(" on test %d", s.PassedTestCount+1)
+	switch {
+	case s.waiting():
+		return "Running" + onTest
+	case s.Verdict == "OK" && s.Testset == "PRETESTS":
+		return "Pretests passed"
+	case s.Verdict == "OK":
+		return "Accepted"
+	case s.Verdict == "COMPILATION_ERROR" || s.Verdict == "SKIPPED" || s.Verdict == "CHALLENGED":
+		return cfVerdictLabel(s.Verdict)
+	default:
+		return cfVerdictLabel(s.Verdict) + onTest
+	}
+}
+
+// cfVerdictLabel ... `WRONG_ANSWER` -> `Wrong answer`
+func cfVerdictLabel(verdict string) string {
+	s := strings.ToLower(strings.Replace(verdict, "_", " ", -1))
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// submission ... Submission にする
+func (s *cfSubmission) submission() *Submission {
+	ret := &Submission{
+		ID:       strconv.FormatInt(s.ID, 10),
+		URL:      Codeforces.url + fmt.Sprintf("contest/%d/submission/%d", s.ContestID, s.ID),
+		Problem:  s.Problem.Index + " - " + s.Problem.Name,
+		Language: s.ProgrammingLanguage,
+		Status:   s.status(),
+		Verdict:  s.verdictText(),
+		Waiting:  s.waiting(),
+		Time:     fmt.Sprintf("%d ms", s.TimeConsumedMillis),
+		Memory:   fmt.Sprintf("%d KB", s.MemoryConsumedBytes/1024),
+	}
+	if s.CreationTimeSeconds > 0 {
+		ret.Date = time.Unix(s.CreationTimeSeconds, 0)
+	}
+	return ret
+}
+
+// detailText ... 通ったテストの数、実行時間、メモリ
+func (s *cfSubmission) detailText() string {
+	return fmt.Sprintf("passed %d tests, %d ms, %d KB", s.PassedTestCount, s.TimeConsumedMillis, s.MemoryConsumedBytes/1024)
+}
+
+// contestStatus ... contest.status: コンテストの handle の提出 (新しい順, count が0なら全て)
+func (api *codeforcesAPI) contestStatus(contestID string, handle string, count int) ([]cfSubmission, error) {
+	params := url.Values{}
+	params.Set("contestId", contestID)
+	if handle != "" {
+		params.Set("handle", handle)
+	}
+	if count > 0 {
+		params.Set("from", "1")
+		params.Set("count", strconv.Itoa(count))
+	}
+	var ret []cfSubmission
+	err := api.call("contest.status", params, &ret)
+	return ret, err
+}
+
+// userStatus ... user.status: handle の全てのコンテストの提出 (新しい順, count が0なら全て)
+func (api *codeforcesAPI) userStatus(handle string, count int) ([]cfSubmission, error) {
+	params := url.Values{}
+	params.Set("handle", handle)
+	if count > 0 {
+		params.Set("from", "1")
+		params.Set("count", strconv.Itoa(count))
+	}
+	var ret []cfSubmission
+	err := api.call("user.status", params, &ret)
+	return ret, err
+}
+
+// call ... method を呼び出して結果を result に読み込む
+func (api *codeforcesAPI) call(method string, params url.Values, result interface{}) error {
+	if api.key != "" && api.secret != "" {
+		params = api.sign(method, params, fmt.Sprintf("%06d", rand.Intn(1000000)), time.Now())
+	}
+
+	resp, err := api.client.Get(api.baseURL + method + "?" + params.Encode())
+	if err != nil {
+		return &ErrCodeforcesAPI{method: method, comment: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status  string          `json:"status"`
+		Comment string          `json:"comment"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return &ErrCodeforcesAPI{method: method, comment: resp.Status}
+	}
+	if body.Status != "OK" {
+		return &ErrCodeforcesAPI{method: method, comment: body.Comment}
+	}
+	if err := json.Unmarshal(body.Result, result); err != nil {
+		return &ErrCodeforcesAPI{method: method, comment: err.Error()}
+	}
+	return nil
+}
+
+// sign ... apiKey, time, apiSig を加えたパラメータを返す
+// apiSig = {rnd}sha512hex({rnd}/{method}?{パラメータ (キー, 値の順にソート)}#{secret})
+func (api *codeforcesAPI) sign(method string, params url.Values, rnd string, now time.Time) url.Values {
+	signed := url.Values{}
+	for k, v := range params {
+		signed[k] = append([]string{}, v...)
+	}
+	signed.Set("apiKey", api.key)
+	signed.Set("time", strconv.FormatInt(now.Unix(), 10))
+
+	// Encode はキーでソートするので、値もソートしておく
+	for _, v := range signed {
+		sort.Strings(v)
+	}
+	hash := sha512.Sum512([]byte(rnd + "/" + method + "?" + signed.Encode() + "#" + api.secret))
+	signed.Set("apiSig", rnd+hex.EncodeToString(hash[:]))
+	return signed
+}
+
+// cfStatusSource ... 自分の提出の一覧を API から取得する (API が使えない場合は提出一覧ページから取得する)
+type cfStatusSource struct {
+	api      *codeforcesAPI
+	br       *browser.Browser
+	handle   string
+	fallback bool // API が使えなかったかどうか (一度失敗したら以降はページから取得する)
+}
+
+// newStatusSource ... br はページから取得する場合に使う (ログイン済みのもの)
+func (cf *codeforces) newStatusSource(br *browser.Browser) *cfStatusSource {
+	handle := cf.loadHandle()
+	return &cfStatusSource{
+		api:      newCodeforcesAPI(),
+		br:       br,
+		handle:   handle,
+		fallback: strings.Contains(handle, "@"), // メールアドレスでログインしている場合はハンドルが分からない
+	}
+}
+
+// submissions ... コンテストの自分の提出の一覧 (新しい順)
+func (src *cfStatusSource) submissions(contestID string) ([]cfSubmission, error) {
+	if !src.fallback {
+		subs, err := src.api.contestStatus(contestID, src.handle, 0)
+		if err == nil {
+			return subs, nil
+		}
+		fmt.Fprintln(os.Stderr, util.PrefixCaution+"Codeforces API is unavailable, scraping the submissions page instead.")
+		util.DebugPrint(err.Error())
+		src.fallback = true
+	}
+	return src.scrape(contestID)
+}
+
+// checkInterval ... 提出の状態を確認する間隔 (ページから取得する場合は負荷をかけないように長めにする)
+func (src *cfStatusSource) checkInterval() time.Duration {
+	if src.fallback {
+		return 1 * time.Minute
+	}
+	return CheckInterval
+}
+
+// scrape ... 提出一覧ページから自分の提出の一覧を取得する
+func (src *cfStatusSource) scrape(contestID string) ([]cfSubmission, error) {
+	if err := openPage(src.br, Codeforces.url+fmt.Sprintf("contest/%s/my", contestID)); err != nil {
+		return nil, err
+	}
+	contest, _ := strconv.Atoi(contestID)
+
+	ret := []cfSubmission{}
+	src.br.Dom().Find(".status-frame-datatable").Find("tr[data-submission-id]").Each(func(_ int, tr *goquery.Selection) {
+		var s cfSubmission
+		tmp, _ := tr.Attr("data-submission-id")
+		s.ID, _ = strconv.ParseInt(tmp, 10, 64)
+		s.ContestID = contest
+		s.Problem.ContestID = contest
+		s.Problem.Name = strings.TrimSpace(tr.Find("td:nth-of-type(4)").Text())
+		if i := strings.Index(s.Problem.Name, " - "); i >= 0 {
+			s.Problem.Index = s.Problem.Name[:i]
+			s.Problem.Name = s.Problem.Name[i+3:]
+		}
+		s.ProgrammingLanguage = strings.TrimSpace(tr.Find("td:nth-of-type(5)").Text())
+
+		cell := tr.Find("td.status-verdict-cell").First()
+		if waiting, _ := cell.Attr("waiting"); waiting == "true" {
+			s.Verdict = "TESTING"
+		} else {
+			s.Verdict, s.Testset, s.PassedTestCount = cfVerdictFromText(strings.TrimSpace(cell.Find("span.submissionVerdictWrapper").Text()))
+		}
+		s.TimeConsumedMillis, _ = strconv.Atoi(strings.Fields(tr.Find("td:nth-of-type(7)").Text() + " 0")[0])
+		mem, _ := strconv.ParseInt(strings.Fields(tr.Find("td:nth-of-type(8)").Text() + " 0")[0], 10, 64)
+		s.MemoryConsumedBytes = mem * 1024
+		ret = append(ret, s)
+	})
+	return ret, nil
+}
+
+// cfVerdictFromText ... 提出一覧ページの判定の文字列 (`Wrong answer on test 3` など) を API の verdict, testset, passedTestCount にする
+func cfVerdictFromText(text string) (string, string, int) {
+	passed := 0
+	if i := strings.Index(text, " on test "); i >= 0 {
+		n, _ := strconv.Atoi(strings.TrimSpace(text[i+len(" on test "):]))
+		passed = n - 1
+		text = text[:i]
+	}
+	switch {
+	case strings.HasPrefix(text, "Pretests passed"):
+		return "OK", "PRETESTS", passed
+	case strings.HasPrefix(text, "Accepted"):
+		return "OK", "TESTS", passed
+	case strings.HasPrefix(text, "Running"), strings.HasPrefix(text, "In queue"):
+		return "TESTING", "", passed
+	}
+	return strings.ToUpper(strings.Replace(text, " ", "_", -1)), "", passed
+}
@@ -0,0 +1,85 @@
+package online_judge
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCodeforcesAPISign(t *testing.T) {
+	fmt.Println("testing : codeforces_api.go > codeforcesAPI.sign")
+
+	api := &codeforcesAPI{key: "xxx", secret: "yyy"}
+	params := url.Values{}
+	params.Set("contestId", "566")
+	signed := api.sign("contest.hacks", params, "123456", time.Unix(1000000000, 0))
+
+	expect := "1234566539c3d4a0f9353495f3e46e8ad6ec2e404891e38ecef28c33af07114c907ddfa5f5d3ee47aa834965320122c32d664912360043951a86a419cdd3a4ed777048"
+	if got := signed.Get("apiSig"); got != expect {
+		t.Errorf("apiSig is %s, expected %s", got, expect)
+	}
+	if signed.Get("apiKey") != "xxx" || signed.Get("time") != "1000000000" {
+		t.Errorf("signed params : %v", signed)
+	}
+}
+
+func TestCodeforcesAPIContestStatus(t *testing.T) {
+	fmt.Println("testing : codeforces_api.go > codeforcesAPI.contestStatus")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/contest.status" || r.URL.Query().Get("handle") != "tourist" {
+			fmt.Fprint(w, `{"status":"FAILED","comment":"handle: not found"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":"OK","result":[
+			{"id":2,"contestId":566,"problem":{"contestId":566,"index":"B","name":"B"},"verdict":"TESTING","passedTestCount":4},
+			{"id":1,"contestId":566,"problem":{"contestId":566,"index":"A","name":"A"},"verdict":"WRONG_ANSWER","testset":"TESTS",
+			 "passedTestCount":2,"timeConsumedMillis":46,"memoryConsumedBytes":204800}]}`)
+	}))
+	defer server.Close()
+
+	api := &codeforcesAPI{baseURL: server.URL + "/api/", client: server.Client()}
+	subs, err := api.contestStatus("566", "tourist", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 || !subs[0].waiting() || subs[1].waiting() {
+		t.Fatalf("submissions : %v", subs)
+	}
+	if subs[1].status() != JudgeStatusWA || subs[1].verdictText() != "Wrong answer on test 3" {
+		t.Errorf("verdict : %s, %s", subs[1].status().ToString(), subs[1].verdictText())
+	}
+	if subs[1].detailText() != "passed 2 tests, 46 ms, 200 KB" {
+		t.Errorf("detail : %s", subs[1].detailText())
+	}
+
+	if _, err := api.contestStatus("566", "no_such_user", 0); err == nil {
+		t.Error("FAILED のレスポンスでエラーになりません")
+	}
+}
+
+func TestCodeforcesVerdictFromText(t *testing.T) {
+	fmt.Println("testing : codeforces_api.go > cfVerdictFromText")
+
+	testcase := []struct {
+		text    string
+		verdict string
+		testset string
+		passed  int
+	}{
+		{"Accepted", "OK", "TESTS", 0},
+		{"Pretests passed", "OK", "PRETESTS", 0},
+		{"Wrong answer on test 3", "WRONG_ANSWER", "", 2},
+		{"Time limit exceeded on test 10", "TIME_LIMIT_EXCEEDED", "", 9},
+		{"Compilation error", "COMPILATION_ERROR", "", 0},
+	}
+	for _, c := range testcase {
+		verdict, testset, passed := cfVerdictFromText(c.text)
+		if verdict != c.verdict || testset != c.testset || passed != c.passed {
+			t.Errorf("cfVerdictFromText(%s) returned %s, %s, %d", c.text, verdict, testset, passed)
+		}
+	}
+}
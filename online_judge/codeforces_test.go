@@ -2,32 +2,76 @@ package online_judge
 
 import (
 	"fmt"
+	"net/http"
+	"os"
+	"reflect"
 	"testing"
+
+	"github.com/algon-320/KIDE/util"
 )
 
+// codeforcesRoutes ... Codeforces のログインの流れと記録しておいたページを返すハンドラ
+// ハンドル kide、パスワード password でログインすると JSESSIONID が設定される
+func codeforcesRoutes(t *testing.T) map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"codeforces.com/enter": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "POST" {
+				r.ParseForm()
+				if r.Form.Get("csrf_token") != "" && r.Form.Get("handleOrEmail") == "kide" && r.Form.Get("password") == "password" {
+					http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "loggedin", Path: "/"})
+					http.Redirect(w, r, "/", http.StatusFound)
+					return
+				}
+			}
+			fixture(t, "codeforces_enter.html")(w, r)
+		},
+		"codeforces.com/": fixture(t, "codeforces_contest_1.html"),
+		"codeforces.com/contest/1/submit": func(w http.ResponseWriter, r *http.Request) {
+			if c, err := r.Cookie("JSESSIONID"); err != nil || c.Value != "loggedin" {
+				http.Redirect(w, r, "/enter?back=%2Fcontest%2F1%2Fsubmit", http.StatusFound)
+				return
+			}
+			fixture(t, "codeforces_contest_1.html")(w, r)
+		},
+		"codeforces.com/contest/1":              fixture(t, "codeforces_contest_1.html"),
+		"codeforces.com/contest/1/problem/A":    fixture(t, "codeforces_1A.html"),
+		"codeforces.com/problemset/problem/1/A": fixture(t, "codeforces_1A.html"),
+	}
+}
+
 func TestCodeforcesLogin(t *testing.T) {
 	fmt.Println("testing : codeforces.go > Codeforces.login")
+	defer serveFixtures(t, codeforcesRoutes(t))()
+
+	os.Setenv("CODEFORCES_HANDLE", "kide")
+	os.Setenv("CODEFORCES_PASSWORD", "password")
+	defer os.Unsetenv("CODEFORCES_HANDLE")
+	defer os.Unsetenv("CODEFORCES_PASSWORD")
+
 	br, err := Codeforces.login()
 	if err != nil {
 		t.Error("Codeforcesのログイン処理でエラーが発生しました。")
 		t.Error(err)
 		return
 	}
-	br.Open("http://codeforces.com/settings/general")
-	if br.Title() != "Settings - Codeforces" {
-		t.Error("Codeforcesにログイン出来ていません！ : %s", br.Title())
+	if !Codeforces.checkLoggedin(br) {
+		t.Errorf("Codeforcesにログイン出来ていません！ : %s", br.Url())
 		return
 	}
+
+	os.Setenv("CODEFORCES_PASSWORD", "wrong password")
+	util.SaveLoginSession(Codeforces.sessionFile, nil)
+	if _, err := Codeforces.login(); err == nil {
+		t.Error("間違ったパスワードでログイン出来てしまいます")
+	}
 }
 
-func TestCodeforcesNewProblem(t *testing.T) {
-	fmt.Println("testing : codeforces.go > Codeforces.NewProblem")
-	test := func(task, url string) {
-		if err := Codeforces.NewProblem(url); err != nil {
-			t.Error(err)
-		}
+func TestCodeforcesFetchProblem(t *testing.T) {
+	fmt.Println("testing : codeforces.go > Codeforces.fetchProblem")
+	defer serveFixtures(t, codeforcesRoutes(t))()
 
-		p, err := LoadProblem(task)
+	test := func(url string) {
+		p, err := Codeforces.fetchProblem(newBrowser(), url)
 		if err != nil {
 			t.Error(err)
 			return
@@ -36,24 +80,52 @@ func TestCodeforcesNewProblem(t *testing.T) {
 		if p.ID != "A" {
 			t.Error("id検出エラー")
 		}
-		if p.Name != "837_A" {
+		if p.Name != "1_A" {
 			t.Error("問題名検出エラー")
 		}
-		if p.ContestID != "837" {
+		if p.ContestID != "1" {
 			t.Error("コンテストid検出エラー")
 		}
 		if p.URL != url {
 			t.Error("URLエラー")
 		}
-		if p.Oj.Name() != Codeforces.Name() {
+		if p.Oj != Codeforces {
 			t.Error("OJエラー")
 		}
-		if len(p.Cases) != 3 {
+		if len(p.Cases) != 2 {
 			t.Error("サンプルケースを抽出出来ていません！")
+			return
 		}
+		if p.Cases[0].Input != "6 6 4\n" || p.Cases[0].Output != "4\n" {
+			t.Errorf("サンプルケースの内容が違います : %+v", p.Cases[0])
+		}
+		// 改行は <br /> で、特殊文字はエスケープされている
+		if p.Cases[1].Input != "2 3\n1 < 2\n" {
+			t.Errorf("サンプルケースの内容が違います : %+v", p.Cases[1])
+		}
+		if p.TimeLimitMs != 1000 || p.MemoryLimitMB != 256 || !reflect.DeepEqual(p.Tags, []string{"math"}) {
+			t.Errorf("問題の情報が違います : %v", p.Metadata())
+		}
+	}
+	test("https://codeforces.com/problemset/problem/1/A")
+	test("https://codeforces.com/contest/1/problem/A")
+}
+
+func TestCodeforcesProblemURLs(t *testing.T) {
+	fmt.Println("testing : codeforces.go > Codeforces.problemURLs")
+	defer serveFixtures(t, codeforcesRoutes(t))()
+
+	urls, err := Codeforces.problemURLs(newBrowser(), "https://codeforces.com/contest/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"https://codeforces.com/contest/1/problem/A",
+		"https://codeforces.com/contest/1/problem/B",
+	}
+	if !reflect.DeepEqual(urls, expect) {
+		t.Errorf("problemURLs returned %v, expected %v", urls, expect)
 	}
-	test("A", "http://codeforces.com/problemset/problem/837/A")
-	test("A", "http://codeforces.com/contest/837/problem/A")
 }
 
 func TestCodeforcesIsValidURL(t *testing.T) {
@@ -65,21 +137,21 @@ func TestCodeforcesIsValidURL(t *testing.T) {
 	}
 
 	testcase := map[string]res{
-		"http://codeforces.com/contests":                        res{isVaild: false, isProblemSet: false},
-		"http://codeforces.com/":                                res{isVaild: false, isProblemSet: false},
-		"http://codeforces.com/problemset":                      res{isVaild: false, isProblemSet: false},
-		"http://codeforces.com/problemset/problem/839/E":        res{isVaild: true, isProblemSet: false},
-		"http://codeforces.com/contest/839":                     res{isVaild: true, isProblemSet: true},
-		"http://codeforces.com/contest/839/problem/A":           res{isVaild: true, isProblemSet: false},
-		"http://codeforces.com/gym/211512":                      res{isVaild: true, isProblemSet: true},
-		"http://codeforces.com/gym/211512/problem/A":            res{isVaild: true, isProblemSet: false},
-		"http://codeforces.com/group/ilLpsu4YlI/contest/214226": res{isVaild: true, isProblemSet: true},
+		"https://codeforces.com/contests":                        res{isVaild: false, isProblemSet: false},
+		"https://codeforces.com/":                                res{isVaild: false, isProblemSet: false},
+		"https://codeforces.com/problemset":                      res{isVaild: false, isProblemSet: false},
+		"https://codeforces.com/problemset/problem/839/E":        res{isVaild: true, isProblemSet: false},
+		"https://codeforces.com/contest/839":                     res{isVaild: true, isProblemSet: true},
+		"https://codeforces.com/contest/839/problem/A":           res{isVaild: true, isProblemSet: false},
+		"https://codeforces.com/gym/211512":                      res{isVaild: true, isProblemSet: true},
+		"https://codeforces.com/gym/211512/problem/A":            res{isVaild: true, isProblemSet: false},
+		"https://codeforces.com/group/ilLpsu4YlI/contest/214226": res{isVaild: true, isProblemSet: true},
 	}
 
 	for k, v := range testcase {
 		p, s := Codeforces.IsValidURL(k)
 		if p != v.isVaild || s != v.isProblemSet {
-			t.Error(fmt.Sprintf("Codeforces.IsValidURL made incorrect judgement. %s", k))
+			t.Errorf("Codeforces.IsValidURL made incorrect judgement. %s", k)
 		}
 	}
 }
@@ -88,7 +160,7 @@ func TestCodeforcesIsValidURL(t *testing.T) {
 // func TestCodeforcecsSubmit(t *testing.T) {
 // 	fmt.Println("testing : codeforces.go > Codeforces.Submit")
 
-// 	err := Codeforces.NewProblem("http://codeforces.com/contest/837/problem/A")
+// 	err := Codeforces.NewProblem("https://codeforces.com/contest/837/problem/A")
 // 	if err != nil {
 // 		t.Error(err)
 // 	}
@@ -0,0 +1,283 @@
+package online_judge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/algon-320/KIDE/setting"
+	"github.com/algon-320/KIDE/util"
+	"github.com/headzoo/surf/browser"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// DefaultDownloadConcurrency ... コンテストの問題を同時にダウンロードする数 (OnlineJudge.DownloadConcurrency で変更できる)
+	DefaultDownloadConcurrency = 4
+	// failedDownloadsFilename ... ダウンロードに失敗した問題のURLの一覧 ({SamplecaseDir} 以下に保存する)
+	failedDownloadsFilename = "failed_downloads.json"
+)
+
+// problemFetcher ... problemURL の問題を br でダウンロードする (保存はしない)
+type problemFetcher func(br *browser.Browser, problemURL string) (*Problem, error)
+
+// contestJudge ... コンテストの問題を一括でダウンロード出来るオンラインジャッジが実装するインターフェース
+type contestJudge interface {
+	login() (*browser.Browser, error)
+	// problemURLs ... コンテストの問題一覧ページ contestURL から問題のURLの一覧を取得する
+	problemURLs(br *browser.Browser, contestURL string) ([]string, error)
+	// fetchProblem ... problemURL の問題をダウンロードする (保存はしない)
+	fetchProblem(br *browser.Browser, problemURL string) (*Problem, error)
+}
+
+// downloadSettings ... 同時にダウンロードする数を設定から読む
+// (ホストごとのリクエストの間隔は全てのリクエストで共通なので httpConfig で決まる)
+func downloadSettings() int {
+	concurrency := DefaultDownloadConcurrency
+	if v, ok := setting.Get("OnlineJudge.DownloadConcurrency", ""); ok {
+		if n, ok := v.(float64); ok && n >= 1 {
+			concurrency = int(n)
+		}
+	}
+	return concurrency
+}
+
+// downloadProgress ... ダウンロードの進捗を表として表示する
+// 端末に出力している場合は表を書き換え、そうでない場合は終わった問題を1行ずつ表示する
+type downloadProgress struct {
+	mu       sync.Mutex
+	urls     []string
+	status   []string
+	live     bool
+	rendered bool
+}
+
+func newDownloadProgress(urls []string) *downloadProgress {
+	status := make([]string, len(urls))
+	for i := range status {
+		status[i] = "waiting"
+	}
+	return &downloadProgress{
+		urls:   urls,
+		status: status,
+		live:   terminal.IsTerminal(int(os.Stdout.Fd())),
+	}
+}
+
+// set ... i 番目の問題の状態を更新する
+func (d *downloadProgress) set(i int, status string, finished bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status[i] = status
+	if d.live {
+		d.render()
+	} else if finished {
+		fmt.Printf("%s  %s\n", d.urls[i], status)
+	}
+}
+
+// render ... 表を書く (2回目以降は前回の表を上書きする)
+func (d *downloadProgress) render() {
+	if d.rendered {
+		util.MoveCursorUp(len(d.urls))
+	}
+	width := 0
+	for _, u := range d.urls {
+		if width < len(u) {
+			width = len(u)
+		}
+	}
+	for i, u := range d.urls {
+		util.ClearCurrentLine()
+		fmt.Printf("%s%s  %s\n", u, strings.Repeat(" ", width-len(u)), d.status[i])
+	}
+	d.rendered = true
+}
+
+// failedProblem ... ダウンロードに失敗した問題
+type failedProblem struct {
+	URL string `json:"url"`
+	Err string `json:"error"`
+}
+
+// DownloadContest ... コンテストの問題一覧ページ contestURL の問題を全てダウンロードして保存する
+// return: 保存できた問題 (一部の問題が失敗した場合も、保存できた問題と ErrFailedToDownloadProblems を返す)
+func DownloadContest(contestURL string) ([]*Problem, error) {
+	oj, err := FromProblemURL(contestURL)
+	if err != nil {
+		return nil, err
+	}
+	j, ok := oj.(contestJudge)
+	if _, isSet := oj.IsValidURL(contestURL); !ok || !isSet {
+		return nil, &ErrInvalidContestURL{url: contestURL}
+	}
+	br, err := j.login()
+	if err != nil {
+		return nil, err
+	}
+	return downloadProblemSet(j, br, contestURL)
+}
+
+// downloadProblemSet ... コンテストの問題一覧ページ contestURL の問題を全てダウンロードして保存する
+func downloadProblemSet(j contestJudge, br *browser.Browser, contestURL string) ([]*Problem, error) {
+	problemURLs, err := j.problemURLs(br, contestURL)
+	if err != nil {
+		return nil, err
+	}
+	return downloadContest(br, problemURLs, j.fetchProblem)
+}
+
+// downloadContest ... problemURLs の問題を並列にダウンロードして保存する
+// 同時にダウンロードする数は downloadSettings で決まる
+// 失敗した問題がある場合は ErrFailedToDownloadProblems を返し、kide dl --retry で失敗したものだけダウンロードし直せるように記録する
+// return: 保存できた問題 (problemURLs の順)
+func downloadContest(br *browser.Browser, problemURLs []string, fetch problemFetcher) ([]*Problem, error) {
+	if len(problemURLs) == 0 {
+		return nil, &ErrFailedToDownloadProblems{}
+	}
+	concurrency := downloadSettings()
+	progress := newDownloadProgress(problemURLs)
+	if progress.live {
+		progress.mu.Lock()
+		progress.render()
+		progress.mu.Unlock()
+	}
+
+	problems := make([]*Problem, len(problemURLs))
+	errs := make([]error, len(problemURLs))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(problemURLs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tab := br.NewTab() // Browser は並行に使えないのでワーカーごとに分ける
+			for i := range jobs {
+				progress.set(i, "downloading", false)
+				problems[i], errs[i] = fetchAndSave(tab, problemURLs[i], fetch)
+				if errs[i] != nil {
+					progress.set(i, util.ESCS_COL_RED_B+"failed"+util.ESCS_COL_OFF, true)
+				} else {
+					progress.set(i, util.ESCS_COL_GREEN_B+"done"+util.ESCS_COL_OFF+
+						fmt.Sprintf(" %s (%d cases)", problems[i].Key(), len(problems[i].Cases)), true)
+				}
+			}
+		}()
+	}
+	for i := range problemURLs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	saved := []*Problem{}
+	failed := []failedProblem{}
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, failedProblem{URL: problemURLs[i], Err: strings.TrimPrefix(err.Error(), util.PrefixError)})
+		} else {
+			saved = append(saved, problems[i])
+		}
+	}
+	if err := saveFailedDownloads(failed); err != nil {
+		fmt.Fprintln(os.Stderr, util.PrefixError+"File write error:", err)
+	}
+	if len(failed) > 0 {
+		return saved, &ErrFailedToDownloadProblems{failed: failed, total: len(problemURLs)}
+	}
+	fmt.Println(util.PrefixInfo + fmt.Sprintf("Downloaded %d problems", len(problemURLs)))
+	return saved, nil
+}
+
+// fetchAndSave ... 1問ダウンロードして保存する (スクレイピング中の panic もエラーとして返す)
+func fetchAndSave(br *browser.Browser, problemURL string, fetch problemFetcher) (p *Problem, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p, err = nil, fmt.Errorf("unexpected page structure (%v)", r)
+		}
+	}()
+	p, err = fetch(br, problemURL)
+	if err != nil {
+		return nil, err
+	}
+	return p, p.save(false)
+}
+
+// openPage ... br で pageURL を開く (ステータスコードが200以外ならエラー)
+func openPage(br *browser.Browser, pageURL string) error {
+	if err := br.Open(pageURL); err != nil {
+		return err
+	}
+	return checkStatus(br, pageURL)
+}
+
+// checkStatus ... br が開いた pageURL のページのステータスが200でなければエラーを返す
+func checkStatus(br *browser.Browser, pageURL string) error {
+	if code := br.StatusCode(); code != 200 {
+		return fmt.Errorf("`%s` returned status %d", pageURL, code)
+	}
+	return nil
+}
+
+func failedDownloadsPath() string {
+	return filepath.Join(samplecaseRoot(), failedDownloadsFilename)
+}
+
+// saveFailedDownloads ... 失敗した問題を記録する (無い場合は記録を消す)
+func saveFailedDownloads(failed []failedProblem) error {
+	path := failedDownloadsPath()
+	if len(failed) == 0 {
+		if util.FileExists(path) {
+			return os.Remove(path)
+		}
+		return nil
+	}
+	jsonBytes, err := json.MarshalIndent(failed, "", "  ")
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(path, jsonBytes, 0644)
+}
+
+// RetryFailedDownloads ... 前回のコンテストのダウンロードで失敗した問題だけをダウンロードし直す
+func RetryFailedDownloads() error {
+	bytes, err := ioutil.ReadFile(failedDownloadsPath())
+	if err != nil {
+		fmt.Println(util.PrefixInfo + "There are no failed downloads.")
+		return nil
+	}
+	var failed []failedProblem
+	if err := json.Unmarshal(bytes, &failed); err != nil {
+		return err
+	}
+
+	if len(failed) == 0 {
+		return saveFailedDownloads(nil)
+	}
+
+	// 記録は1回のコンテストのダウンロードのものなので、オンラインジャッジは全て同じ
+	oj, err := FromProblemURL(failed[0].URL)
+	if err != nil {
+		return err
+	}
+	j, ok := oj.(contestJudge)
+	if !ok {
+		return &ErrFailedToDownloadProblems{failed: failed, total: len(failed)}
+	}
+	urls := []string{}
+	for _, f := range failed {
+		urls = append(urls, f.URL)
+	}
+	br, err := j.login()
+	if err != nil {
+		return err
+	}
+	fmt.Println(util.PrefixInfo + fmt.Sprintf("Retry downloading %d problems", len(urls)))
+	_, err = downloadContest(br, urls, j.fetchProblem)
+	return err
+}
@@ -0,0 +1,55 @@
+package online_judge
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/algon-320/KIDE/util"
+	"github.com/headzoo/surf/browser"
+)
+
+func TestDownloadContest(t *testing.T) {
+	fmt.Println("testing : contest_download.go > downloadContest")
+
+	defer os.RemoveAll(samplecaseRoot())
+
+	urls := []string{
+		"http://a.example.com/contests/test/tasks/a",
+		"http://b.example.com/contests/test/tasks/b",
+		"http://c.example.com/contests/test/tasks/c",
+	}
+	fetch := func(_ *browser.Browser, problemURL string) (*Problem, error) {
+		id := problemURL[len(problemURL)-1:]
+		switch id {
+		case "b":
+			return nil, fmt.Errorf("not found")
+		case "c":
+			var s []string
+			_ = s[1] // スクレイピング中の panic もエラーになる
+		}
+		return &Problem{ID: id, ContestID: "test", Name: id, URL: problemURL, Oj: AtCoder}, nil
+	}
+
+	saved, err := downloadContest(newBrowser(), urls, fetch)
+	e, ok := err.(*ErrFailedToDownloadProblems)
+	if !ok {
+		t.Fatalf("downloadContest returned %v", err)
+	}
+	if len(e.failed) != 2 || e.failed[0].URL != urls[1] || e.failed[1].URL != urls[2] {
+		t.Errorf("failed problems : %v", e.failed)
+	}
+	if len(saved) != 1 || saved[0].Key() != "atcoder/test/A" {
+		t.Errorf("saved problems : %v", saved)
+	}
+	if !strings.Contains(e.Error(), urls[1]) {
+		t.Errorf("the error doesn't list the failed problem : %s", e.Error())
+	}
+	if _, err := LoadProblem("atcoder/test/A"); err != nil {
+		t.Error(err)
+	}
+	if !util.FileExists(failedDownloadsPath()) {
+		t.Error("failed downloads are not recorded")
+	}
+}
@@ -0,0 +1,109 @@
+package online_judge
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/algon-320/KIDE/util"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// waitRetryInitial ... コンテスト開始後、問題が見つからなかった場合に再試行するまでの最初の待ち時間
+	waitRetryInitial = 1 * time.Second
+	// waitRetryMax ... 再試行するまでの待ち時間の上限
+	waitRetryMax = 30 * time.Second
+	// waitRetryTimeout ... コンテスト開始後、問題が見つからないまま諦めるまでの時間
+	waitRetryTimeout = 10 * time.Minute
+)
+
+// contestTimer ... コンテストの開始時刻を取得出来るオンラインジャッジが実装するインターフェース
+type contestTimer interface {
+	// contestStartTime ... コンテストの問題一覧ページ contestURL のコンテストの開始時刻
+	contestStartTime(contestURL string) (time.Time, error)
+}
+
+// ContestStartTime ... contestURL のコンテストの開始時刻を返す
+func ContestStartTime(contestURL string) (time.Time, error) {
+	oj, err := FromProblemURL(contestURL)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, ok := oj.(contestTimer)
+	if !ok {
+		return time.Time{}, &ErrFailedToGetContestStartTime{message: oj.Name() + " is not supported"}
+	}
+	return t.contestStartTime(contestURL)
+}
+
+// WaitAndDownloadContest ... コンテストの開始まで待ってから DownloadContest する
+// 開始時刻まではカウントダウンを表示し、開始後は問題がダウンロード出来るまで間隔を空けながら再試行する
+// (開始時刻が取得出来ない場合はすぐに再試行を始める)
+func WaitAndDownloadContest(contestURL string) ([]*Problem, error) {
+	start, err := ContestStartTime(contestURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, util.PrefixCaution+fmt.Sprintf("Failed to get the start time : %s", err))
+	} else {
+		fmt.Println(util.PrefixInfo + "The contest starts at " + start.Local().Format("2006-01-02 15:04:05"))
+		countdown(start)
+	}
+
+	interval := waitRetryInitial
+	deadline := time.Now().Add(waitRetryTimeout)
+	for {
+		problems, err := DownloadContest(contestURL)
+		if len(problems) > 0 {
+			return problems, err
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return nil, err
+		}
+		fmt.Fprintln(os.Stderr, util.PrefixCaution+fmt.Sprintf("No problems yet. Retry in %v ...", interval))
+		time.Sleep(interval)
+
+		interval *= 2
+		if interval > waitRetryMax {
+			interval = waitRetryMax
+		}
+	}
+}
+
+// countdown ... start まで残り時間を表示しながら待つ
+func countdown(start time.Time) {
+	live := terminal.IsTerminal(int(os.Stdout.Fd()))
+	for {
+		remain := time.Until(start)
+		if remain <= 0 {
+			break
+		}
+		if live {
+			fmt.Print("\r")
+			util.ClearCurrentLine()
+			fmt.Print(util.PrefixInfo + "Starts in " + formatDuration(remain))
+		}
+		// 次の秒の切り替わりまで待つ
+		wait := remain % time.Second
+		if wait == 0 {
+			wait = time.Second
+		}
+		time.Sleep(wait)
+	}
+	if live {
+		fmt.Print("\r")
+		util.ClearCurrentLine()
+	}
+	fmt.Println(util.PrefixInfo + "The contest has started!")
+}
+
+// formatDuration ... 残り時間を `1d 02:03:04` のような形式にする
+func formatDuration(d time.Duration) string {
+	sec := int64((d + time.Second - 1) / time.Second) // 切り上げ
+	days := sec / 86400
+	sec %= 86400
+	s := fmt.Sprintf("%02d:%02d:%02d", sec/3600, sec/60%60, sec%60)
+	if days > 0 {
+		s = fmt.Sprintf("%dd ", days) + s
+	}
+	return s
+}
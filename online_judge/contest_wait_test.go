@@ -0,0 +1,23 @@
+package online_judge
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFormatDuration(t *testing.T) {
+	fmt.Println("testing : contest_wait.go > formatDuration")
+
+	testcase := map[time.Duration]string{
+		0:                       "00:00:00",
+		1500 * time.Millisecond: "00:00:02",
+		time.Hour + 2*time.Minute + 3*time.Second: "01:02:03",
+		26*time.Hour + 5*time.Second:              "1d 02:00:05",
+	}
+	for d, expect := range testcase {
+		if got := formatDuration(d); got != expect {
+			t.Errorf("formatDuration(%v) returned %s, expected %s", d, got, expect)
+		}
+	}
+}
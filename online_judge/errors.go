@@ -2,6 +2,8 @@ package online_judge
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/algon-320/KIDE/util"
 )
@@ -68,3 +70,217 @@ type ErrFailedToLoadSamplecase struct {
 func (e ErrFailedToLoadSamplecase) Error() string {
 	return util.PrefixError + fmt.Sprintf("Failed to Load samplecase : %s", e.message)
 }
+
+//-----------------
+
+type ErrFailedToGetLanguages struct {
+	oj_name string
+}
+
+func (e ErrFailedToGetLanguages) Error() string {
+	return util.PrefixError + fmt.Sprintf("Failed to get the language list of `%s`", e.oj_name)
+}
+
+//-----------------
+
+type ErrAmbiguousProblemID struct {
+	id         string
+	candidates []string
+}
+
+func (e ErrAmbiguousProblemID) Error() string {
+	return util.PrefixError + fmt.Sprintf("Problem id `%s` is ambiguous : %s", e.id, strings.Join(e.candidates, ", "))
+}
+
+//-----------------
+
+type ErrFailedToDownloadProblems struct {
+	failed []failedProblem
+	total  int
+}
+
+func (e ErrFailedToDownloadProblems) Error() string {
+	if e.total == 0 {
+		return util.PrefixError + "No problems found."
+	}
+	lines := []string{fmt.Sprintf("Failed to download %d of %d problems (run `kide dl --retry` to retry only these) :", len(e.failed), e.total)}
+	for _, f := range e.failed {
+		lines = append(lines, fmt.Sprintf("  %s : %s", f.URL, f.Err))
+	}
+	return util.PrefixError + strings.Join(lines, "\n")
+}
+
+//-----------------
+
+type ErrInvalidContestURL struct {
+	url string
+}
+
+func (e ErrInvalidContestURL) Error() string {
+	return util.PrefixError + fmt.Sprintf("Invalid contest url `%s`", e.url)
+}
+
+//-----------------
+
+type ErrFailedToGetContestStartTime struct {
+	message string
+}
+
+func (e ErrFailedToGetContestStartTime) Error() string {
+	return util.PrefixError + fmt.Sprintf("Failed to get the start time of the contest : %s", e.message)
+}
+
+//-----------------
+
+type ErrCodeforcesAPI struct {
+	method  string
+	comment string
+}
+
+func (e ErrCodeforcesAPI) Error() string {
+	return util.PrefixError + fmt.Sprintf("Codeforces API `%s` failed : %s", e.method, e.comment)
+}
+
+//-----------------
+
+type ErrContestRequired struct {
+	oj_name string
+}
+
+func (e ErrContestRequired) Error() string {
+	return util.PrefixError + fmt.Sprintf("%s : specify a contest to show submissions", e.oj_name)
+}
+
+//-----------------
+
+type ErrNoSuchSubmission struct {
+	url string
+}
+
+func (e ErrNoSuchSubmission) Error() string {
+	return util.PrefixError + fmt.Sprintf("Submission `%s` is not found", e.url)
+}
+
+//-----------------
+
+type ErrInvalidContest struct {
+	contest string
+}
+
+func (e ErrInvalidContest) Error() string {
+	return util.PrefixError + fmt.Sprintf("Invalid contest `%s` (use `{oj}/{contest id}` such as `atcoder/abc300`)", e.contest)
+}
+
+//-----------------
+
+type ErrLoginNotSupported struct {
+	oj_name string
+}
+
+func (e ErrLoginNotSupported) Error() string {
+	return util.PrefixError + fmt.Sprintf("`%s` has no account to login", e.oj_name)
+}
+
+//-----------------
+
+type ErrSessionRejected struct {
+	oj_name string
+}
+
+func (e ErrSessionRejected) Error() string {
+	return util.PrefixError + fmt.Sprintf("The session of `%s` was rejected even after logging in again", e.oj_name)
+}
+
+//-----------------
+
+type ErrRequestTimeout struct {
+	url     string
+	timeout time.Duration
+}
+
+func (e ErrRequestTimeout) Error() string {
+	return util.PrefixError + fmt.Sprintf("Request to `%s` timed out after %v", e.url, e.timeout)
+}
+
+//-----------------
+
+type ErrNoStatement struct {
+	key string
+}
+
+func (e ErrNoStatement) Error() string {
+	return util.PrefixError + fmt.Sprintf("The statement of `%s` is not saved (download the problem again with `dl`)", e.key)
+}
+
+//-----------------
+
+type ErrBrokenIndex struct {
+	path string
+	err  error
+}
+
+func (e ErrBrokenIndex) Error() string {
+	return util.PrefixError + fmt.Sprintf("The problem index `%s` is broken (delete it to rebuild) : %s", e.path, e.err)
+}
+
+//-----------------
+
+type ErrInvalidSortKey struct {
+	key        string
+	candidates []string
+}
+
+func (e ErrInvalidSortKey) Error() string {
+	return util.PrefixError + fmt.Sprintf("Invalid sort key `%s` (use one of %s)", e.key, strings.Join(e.candidates, ", "))
+}
+
+//-----------------
+
+type ErrFailedToGetContestEndTime struct {
+	message string
+}
+
+func (e ErrFailedToGetContestEndTime) Error() string {
+	return util.PrefixError + fmt.Sprintf("Failed to get the end time of the contest : %s", e.message)
+}
+
+//-----------------
+
+type ErrInvalidDestination struct {
+	dest string
+}
+
+func (e ErrInvalidDestination) Error() string {
+	return util.PrefixError + fmt.Sprintf("Invalid destination `%s` (use `{contest id}/{problem id}` or `{problem id}`)", e.dest)
+}
+
+//-----------------
+
+type ErrProblemExists struct {
+	key string
+}
+
+func (e ErrProblemExists) Error() string {
+	return util.PrefixError + fmt.Sprintf("Problem `%s` already exists", e.key)
+}
+
+//-----------------
+
+type ErrRefreshNotSupported struct {
+	oj_name string
+}
+
+func (e ErrRefreshNotSupported) Error() string {
+	return util.PrefixError + fmt.Sprintf("Problems of `%s` cannot be downloaded again", e.oj_name)
+}
+
+//-----------------
+
+type ErrNoSuchHistoryEntry struct {
+	n     int
+	count int
+}
+
+func (e ErrNoSuchHistoryEntry) Error() string {
+	return util.PrefixError + fmt.Sprintf("Submission #%d doesn't exist (the history has %d submissions)", e.n, e.count)
+}
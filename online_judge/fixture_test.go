@@ -0,0 +1,85 @@
+package online_judge
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/algon-320/KIDE/internal/testenv"
+)
+
+// TestMain ... 問題やセッションを本来の保存先に書かないように、一時ディレクトリを KIDE_HOME にしてテストする
+// (KIDE_HOME はパッケージの初期化より前に testenv で設定される)
+func TestMain(m *testing.M) {
+	os.Exit(testenv.Run(m))
+}
+
+// testdataDir ... 記録しておいたページのディレクトリ
+var testdataDir, _ = filepath.Abs("testdata")
+
+// originalHostHeader ... 転送する前のリクエストのホスト (serveFixtures のルーティングに使う)
+const originalHostHeader = "X-Original-Host"
+
+// redirectTransport ... 全てのリクエストを target に転送する
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	forwarded := req.Clone(req.Context())
+	forwarded.Header.Set(originalHostHeader, req.URL.Host)
+	forwarded.URL.Scheme = rt.target.Scheme
+	forwarded.URL.Host = rt.target.Host
+	forwarded.Host = rt.target.Host
+
+	resp, err := http.DefaultTransport.RoundTrip(forwarded)
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req // リダイレクトやクッキーは元のURLで扱う
+	return resp, nil
+}
+
+// fixture ... testdata/name を返すハンドラ
+func fixture(t *testing.T, name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadFile(filepath.Join(testdataDir, name))
+		if err != nil {
+			t.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(body)
+	}
+}
+
+// serveFixtures ... 記録しておいたページを返すローカルのサーバーを立てて、オンラインジャッジへのリクエストを全てそこに転送する
+// routes: `atcoder.jp/contests/abc300/tasks` のようなホストとパス (クエリを除く) -> ハンドラ
+// (リクエストの間隔と再試行の待ち時間は0にする)
+// 返り値の関数でサーバーを止めて transport を元に戻す
+func serveFixtures(t *testing.T, routes map[string]http.HandlerFunc) func() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(originalHostHeader) + r.URL.Path
+		h, ok := routes[key]
+		if !ok {
+			t.Logf("no fixture for %s %s", r.Method, key)
+			http.NotFound(w, r)
+			return
+		}
+		h(w, r)
+	}))
+	target, _ := url.Parse(srv.URL)
+
+	prev, prevConfig := transport, clientConfig
+	transport = &redirectTransport{target: target}
+	clientConfig.interval = 0
+	clientConfig.backoff = 0
+	return func() {
+		transport, clientConfig = prev, prevConfig
+		srv.Close()
+	}
+}
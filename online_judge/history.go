@@ -0,0 +1,249 @@
+package online_judge
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/algon-320/KIDE/util"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// 提出の履歴は {DataDir}/history/submissions.jsonl に1行に1つの提出 (HistoryEntry) を追記していく
+// 提出したソースコードは {DataDir}/history/sources/{ソースコードのSHA-256} に保存する (同じコードは1つだけ)
+
+const historyDirName = "history"
+
+// HistoryEntry ... 履歴に記録する1つの提出
+type HistoryEntry struct {
+	N           int       `json:"-"`       // 何番目の提出か (1から)
+	ProblemKey  string    `json:"problem"` // 問題のキー
+	ProblemName string    `json:"problem_name"`
+	ProblemURL  string    `json:"problem_url"`
+	Judge       string    `json:"judge"`    // オンラインジャッジの名前 (OnlineJudge.Name)
+	Language    string    `json:"language"` // 言語名 (language.Language.Name)
+	Profile     string    `json:"profile,omitempty"`
+	SourceHash  string    `json:"source_hash"` // ソースコードのSHA-256
+	URL         string    `json:"url"`         // 提出のURL
+	SubmittedAt time.Time `json:"submitted_at"`
+	Verdict     string    `json:"verdict"` // 最終的な結果 (JudgeStatus.Abbrev)
+
+	// 以下は取得できなかった場合は空
+	Time           string       `json:"time,omitempty"`
+	Memory         string       `json:"memory,omitempty"`
+	Score          string       `json:"score,omitempty"`
+	CompileMessage string       `json:"compile_message,omitempty"`
+	Cases          []CaseResult `json:"cases,omitempty"`
+}
+
+var historyMu sync.Mutex
+
+func historyLogPath() string {
+	return util.DataPath(historyDirName, "submissions.jsonl")
+}
+
+func historySourcePath(hash string) string {
+	return util.DataPath(historyDirName, "sources", hash)
+}
+
+// sourceHash ... ソースコードのSHA-256 (16進数)
+func sourceHash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// statusFromAbbrev ... JudgeStatus.Abbrev の逆
+func statusFromAbbrev(abbrev string) JudgeStatus {
+	for js := JudgeStatusAC; js <= JudgeStatusIE; js++ {
+		if js.Abbrev() == abbrev {
+			return js
+		}
+	}
+	return JudgeStatusUNK
+}
+
+// Status ... 最終的な結果
+func (e *HistoryEntry) Status() JudgeStatus {
+	return statusFromAbbrev(e.Verdict)
+}
+
+// RecordSubmission ... 提出のジャッジ結果 res を履歴に追加する
+// profile: 提出する言語を選ぶのに使ったプロファイル
+func RecordSubmission(res *JudgeResult, profile string) (*HistoryEntry, error) {
+	e := &HistoryEntry{
+		SourceHash:     sourceHash(res.Code),
+		URL:            res.URL,
+		SubmittedAt:    res.Date,
+		Verdict:        res.Status.Abbrev(),
+		Profile:        profile,
+		Time:           res.Time,
+		Memory:         res.Memory,
+		Score:          res.Score,
+		CompileMessage: res.CompileMessage,
+		Cases:          res.Cases,
+	}
+	if e.SubmittedAt.IsZero() {
+		e.SubmittedAt = time.Now()
+	}
+	if p := res.Problem; p != nil {
+		e.ProblemKey = p.Key()
+		e.ProblemName = p.Name
+		e.ProblemURL = p.URL
+		if p.Oj != nil {
+			e.Judge = p.Oj.Name()
+		}
+	}
+	if res.Language != nil {
+		e.Language = res.Language.Name()
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if srcPath := historySourcePath(e.SourceHash); !util.FileExists(srcPath) {
+		if err := util.WriteFileAtomic(srcPath, []byte(res.Code), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	path := historyLogPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	// 1行ずつ追記する (O_APPEND なので途中で書き込みが混ざることはない)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// SubmissionHistory ... 履歴の全ての提出を古い順に返す (履歴が無い場合は空)
+// 壊れた行は警告を出して読み飛ばす
+func SubmissionHistory() ([]*HistoryEntry, error) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	f, err := os.Open(historyLogPath())
+	if os.IsNotExist(err) {
+		return []*HistoryEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ret := []*HistoryEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		e := &HistoryEntry{}
+		if err := json.Unmarshal([]byte(line), e); err != nil {
+			fmt.Fprintln(os.Stderr, util.PrefixCaution+fmt.Sprintf("Skipped the broken line %d of `%s` : %s", lineNo, historyLogPath(), err))
+			continue
+		}
+		e.N = len(ret) + 1
+		ret = append(ret, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ProblemHistory ... id で指定された問題の提出を古い順に返す
+// 保存している問題のキーに解決出来ない場合 (削除した問題など) は、キーの末尾が一致する提出を返す
+func ProblemHistory(id string) ([]*HistoryEntry, error) {
+	entries, err := SubmissionHistory()
+	if err != nil {
+		return nil, err
+	}
+	match := func(e *HistoryEntry) bool {
+		key := strings.ToLower(e.ProblemKey)
+		id := strings.ToLower(strings.Trim(filepath.ToSlash(id), "/"))
+		return key == id || strings.HasSuffix(key, "/"+id)
+	}
+	if key, err := ResolveProblemID(id); err == nil {
+		match = func(e *HistoryEntry) bool { return e.ProblemKey == key }
+	}
+
+	ret := []*HistoryEntry{}
+	for _, e := range entries {
+		if match(e) {
+			ret = append(ret, e)
+		}
+	}
+	return ret, nil
+}
+
+// HistoryEntryAt ... n 番目の提出
+func HistoryEntryAt(n int) (*HistoryEntry, error) {
+	entries, err := SubmissionHistory()
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 || n > len(entries) {
+		return nil, &ErrNoSuchHistoryEntry{n: n, count: len(entries)}
+	}
+	return entries[n-1], nil
+}
+
+// SourceCode ... 提出したソースコード
+func (e *HistoryEntry) SourceCode() (string, error) {
+	code, err := ioutil.ReadFile(historySourcePath(e.SourceHash))
+	if err != nil {
+		return "", err
+	}
+	return string(code), nil
+}
+
+// Print ... 提出の詳細 (ソースコードとジャッジ結果) を出力する
+func (e *HistoryEntry) Print() error {
+	code, err := e.SourceCode()
+	if err != nil {
+		return err
+	}
+
+	fd := int(os.Stdout.Fd())
+	width, _, err := terminal.GetSize(fd)
+	if err != nil {
+		width = 80
+	}
+
+	res := &JudgeResult{
+		Code:           code,
+		Date:           e.SubmittedAt,
+		URL:            e.URL,
+		Status:         e.Status(),
+		Time:           e.Time,
+		Memory:         e.Memory,
+		Score:          e.Score,
+		CompileMessage: e.CompileMessage,
+		Cases:          e.Cases,
+	}
+	util.PrintTitle(width, 4, "#", fmt.Sprintf("Submission #%d", e.N))
+	util.PrintTitle(width, 4, "=", "Problem")
+	fmt.Printf("%s (%s)\n%s\n", e.ProblemName, e.ProblemKey, e.ProblemURL)
+	res.print(width, e.Language)
+	return nil
+}
@@ -0,0 +1,87 @@
+package online_judge
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/algon-320/KIDE/language"
+	"github.com/algon-320/KIDE/util"
+)
+
+func TestSubmissionHistory(t *testing.T) {
+	fmt.Println("testing : history.go > RecordSubmission, SubmissionHistory, ProblemHistory")
+
+	defer os.RemoveAll(util.DataPath(historyDirName))
+	defer os.RemoveAll(samplecaseRoot())
+
+	a := &Problem{ID: "A", ContestID: "abc300", Name: "A - N-choice question", Oj: AtCoder, URL: "https://atcoder.jp/contests/abc300/tasks/abc300_a"}
+	b := &Problem{ID: "1", Name: "道路の舗装", Oj: Yukicoder}
+	if err := a.save(false); err != nil {
+		t.Fatal(err)
+	}
+	cpp := language.GetLanguage("C++")
+
+	date := time.Date(2019, 3, 1, 21, 0, 0, 0, time.Local)
+	results := []*JudgeResult{
+		{Problem: a, Code: "wrong", Language: cpp, Date: date, URL: "https://atcoder.jp/contests/abc300/submissions/1", Status: JudgeStatusWA,
+			Cases: []CaseResult{{Name: "sample_01.txt", Status: JudgeStatusWA, Verdict: "WA", Time: "6 ms"}}},
+		{Problem: b, Code: "int main() {}", Language: cpp, Date: date.Add(time.Minute), Status: JudgeStatusCE, CompileMessage: "error"},
+		{Problem: a, Code: "correct", Language: cpp, Date: date.Add(time.Hour), Status: JudgeStatusAC, Time: "6 ms", Memory: "3596 KB"},
+		{Problem: a, Code: "correct", Language: cpp, Status: JudgeStatusAC},
+	}
+	for _, res := range results {
+		if _, err := RecordSubmission(res, "release"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := SubmissionHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || entries[3].N != 4 || entries[3].SubmittedAt.IsZero() {
+		t.Fatalf("SubmissionHistory returned %+v", entries)
+	}
+	e := entries[0]
+	if e.ProblemKey != "atcoder/abc300/A" || e.Judge != AtCoder.Name() || e.Language != "C++" || e.Profile != "release" ||
+		e.Status() != JudgeStatusWA || !e.SubmittedAt.Equal(date) || len(e.Cases) != 1 || e.Cases[0].Status != JudgeStatusWA {
+		t.Errorf("unexpected entry : %+v", e)
+	}
+	if code, err := e.SourceCode(); err != nil || code != "wrong" {
+		t.Errorf("SourceCode returned %q, %v", code, err)
+	}
+	// 同じソースコードは1つだけ保存される
+	if entries[2].SourceHash != entries[3].SourceHash {
+		t.Error("the same code has different hashes")
+	}
+
+	// 問題ごとの履歴 (削除した問題も末尾で探せる)
+	testcase := map[string][]int{
+		"abc300/A":    {1, 3, 4},
+		"yukicoder/1": {2},
+		"1":           {2},
+		"B":           {},
+	}
+	for id, expect := range testcase {
+		got, err := ProblemHistory(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ns := []int{}
+		for _, e := range got {
+			ns = append(ns, e.N)
+		}
+		if fmt.Sprint(ns) != fmt.Sprint(expect) {
+			t.Errorf("ProblemHistory(%q) = %v, expected %v", id, ns, expect)
+		}
+	}
+
+	if e, err := HistoryEntryAt(2); err != nil || e.CompileMessage != "error" {
+		t.Errorf("HistoryEntryAt(2) returned %+v, %v", e, err)
+	}
+	if _, err := HistoryEntryAt(5); err == nil {
+		t.Error("存在しない提出でエラーになりません")
+	}
+}
@@ -0,0 +1,306 @@
+package online_judge
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/algon-320/KIDE/setting"
+	"github.com/algon-320/KIDE/util"
+	"github.com/headzoo/surf"
+	"github.com/headzoo/surf/browser"
+)
+
+const (
+	// DefaultRequestInterval ... 同じホストへのリクエストの最小間隔 (OnlineJudge.RequestIntervalMs で変更できる)
+	DefaultRequestInterval = 500 * time.Millisecond
+	// DefaultRequestTimeout ... 1回のリクエストのタイムアウト (OnlineJudge.HTTP.TimeoutSec で変更できる)
+	DefaultRequestTimeout = 30 * time.Second
+	// DefaultRetries ... 冪等なリクエストが一時的に失敗した時に再試行する回数 (OnlineJudge.HTTP.Retries で変更できる)
+	DefaultRetries = 3
+	// DefaultRetryInterval ... 最初の再試行までの間隔 (OnlineJudge.HTTP.RetryIntervalMs で変更できる、再試行するごとに倍になる)
+	DefaultRetryInterval = 1 * time.Second
+	// maxRetryInterval ... 再試行までの間隔の上限
+	maxRetryInterval = 30 * time.Second
+)
+
+// httpConfig ... オンラインジャッジへのリクエストの設定
+type httpConfig struct {
+	timeout   time.Duration
+	retries   int
+	backoff   time.Duration
+	interval  time.Duration
+	userAgent string // 空文字列の場合はブラウザなどが付けるものをそのまま使う
+	proxy     string // 空文字列の場合は環境変数 HTTP_PROXY、HTTPS_PROXY に従う
+}
+
+// loadHTTPConfig ... 設定ファイルからリクエストの設定を読む
+func loadHTTPConfig() httpConfig {
+	conf := httpConfig{
+		timeout:  DefaultRequestTimeout,
+		retries:  DefaultRetries,
+		backoff:  DefaultRetryInterval,
+		interval: DefaultRequestInterval,
+	}
+	number := func(selector string, min float64) (float64, bool) {
+		v, ok := setting.Get(selector, "")
+		if !ok {
+			return 0, false
+		}
+		n, ok := v.(float64)
+		return n, ok && n >= min
+	}
+	if n, ok := number("OnlineJudge.HTTP.TimeoutSec", 0); ok {
+		conf.timeout = time.Duration(n * float64(time.Second))
+	}
+	if n, ok := number("OnlineJudge.HTTP.Retries", 0); ok {
+		conf.retries = int(n)
+	}
+	if n, ok := number("OnlineJudge.HTTP.RetryIntervalMs", 0); ok {
+		conf.backoff = time.Duration(n) * time.Millisecond
+	}
+	if n, ok := number("OnlineJudge.RequestIntervalMs", 0); ok {
+		conf.interval = time.Duration(n) * time.Millisecond
+	}
+	if v, ok := setting.Get("OnlineJudge.HTTP.UserAgent", "KIDE_USER_AGENT"); ok {
+		conf.userAgent, _ = v.(string)
+	}
+	if v, ok := setting.Get("OnlineJudge.HTTP.Proxy", "KIDE_PROXY"); ok {
+		conf.proxy, _ = v.(string)
+	}
+	return conf
+}
+
+// clientConfig ... 全てのリクエストに使う設定
+var clientConfig = loadHTTPConfig()
+
+// transport ... オンラインジャッジへのリクエストを実際に送る http.RoundTripper
+// (テストではローカルのサーバーに転送するものに差し替える)
+var transport = newBaseTransport(clientConfig.proxy)
+
+// newBaseTransport ... proxy を通す http.Transport を作る
+func newBaseTransport(proxy string) http.RoundTripper {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, util.PrefixError+"Invalid proxy `"+proxy+"` :", err)
+		} else {
+			t.Proxy = http.ProxyURL(u)
+		}
+	}
+	return t
+}
+
+// hostLimiter ... ホストごとにリクエストの間隔を空ける
+type hostLimiter struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+var rateLimiter = &hostLimiter{next: map[string]time.Time{}}
+
+// wait ... host に前回リクエストしてから interval 経つまで待つ
+func (l *hostLimiter) wait(host string, interval time.Duration) {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(interval)
+	l.mu.Unlock()
+
+	time.Sleep(at.Sub(now))
+}
+
+// judgeTransport ... 全てのリクエストに共通の処理をする http.RoundTripper
+// User-Agent を付け、ホストごとに間隔を空け、タイムアウトを設定し、冪等なリクエストは一時的な失敗の時に間隔を倍にしながら再試行する
+type judgeTransport struct {
+	base http.RoundTripper
+}
+
+// clientTransport ... transport に共通の処理を加えたもの (ブラウザやクライアントはこれを使う)
+func clientTransport() http.RoundTripper {
+	return &judgeTransport{base: transport}
+}
+
+func (t *judgeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	conf := clientConfig
+	retries := 0
+	if isIdempotent(req) {
+		retries = conf.retries
+	}
+
+	for attempt := 0; ; attempt++ {
+		rateLimiter.wait(req.URL.Host, conf.interval)
+		resp, err := t.roundTripOnce(req, conf)
+		if attempt >= retries || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		wait := conf.backoff << uint(attempt)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			if d := retryAfter(resp); d > wait {
+				wait = d
+			}
+			resp.Body.Close()
+		}
+		if wait > maxRetryInterval {
+			wait = maxRetryInterval
+		}
+		fmt.Fprintf(os.Stderr, util.PrefixCaution+"%s %s failed (%s). Retry in %v ...\n", req.Method, req.URL, reason, wait)
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// roundTripOnce ... タイムアウトと User-Agent を設定して1回リクエストする
+func (t *judgeTransport) roundTripOnce(req *http.Request, conf httpConfig) (*http.Response, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if conf.timeout > 0 {
+		ctx, cancel = context.WithTimeout(req.Context(), conf.timeout)
+	}
+	r := req.Clone(ctx)
+	if conf.userAgent != "" {
+		r.Header.Set("User-Agent", conf.userAgent)
+	}
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		cancel()
+		if ctx.Err() == context.DeadlineExceeded && req.Context().Err() == nil {
+			return nil, &ErrRequestTimeout{url: req.URL.String(), timeout: conf.timeout}
+		}
+		return nil, err
+	}
+	// 本文を読み終わるまでタイムアウトを有効にしておく
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	resp.Request = req
+	return resp, nil
+}
+
+// cancelOnClose ... Close した時にリクエストの context をキャンセルする
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// isIdempotent ... 再試行しても問題ないリクエストかどうか (提出などのPOSTは再試行しない)
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", "GET", "HEAD", "OPTIONS":
+		return req.Body == nil || req.Body == http.NoBody
+	}
+	return false
+}
+
+// shouldRetry ... 一時的な失敗かどうか (通信エラー、タイムアウト、429、5xx)
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil // 呼び出し元がキャンセルした場合は再試行しない
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter ... Retry-After ヘッダで指定された待ち時間 (秒数で指定されている場合のみ)
+func retryAfter(resp *http.Response) time.Duration {
+	sec, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || sec < 0 {
+		return 0
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// newBrowser ... clientTransport を使うブラウザを作る
+func newBrowser() *browser.Browser {
+	br := surf.NewBrowser()
+	br.SetTransport(clientTransport())
+	return br
+}
+
+// httpClient ... clientTransport を使うクライアントを作る
+// jar: ログインのセッションを使う場合はブラウザのクッキー (使わない場合はnil)
+func httpClient(jar http.CookieJar) *http.Client {
+	return &http.Client{Transport: clientTransport(), Jar: jar}
+}
+
+// getDocument ... url のページを取得して読み込む
+func getDocument(url string) (*goquery.Document, error) {
+	resp, err := httpClient(nil).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	return goquery.NewDocumentFromResponse(resp)
+}
+
+// cookieRecorder ... レスポンスの Set-Cookie を記録する
+// (ブラウザのクッキーからは有効期限が分からないので、セッションを保存するときにこれで補う)
+type cookieRecorder struct {
+	base    http.RoundTripper
+	mu      sync.Mutex
+	cookies map[string]*http.Cookie // 名前 -> 最後に受け取ったクッキー
+}
+
+func (r *cookieRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range resp.Cookies() {
+		if c.MaxAge > 0 {
+			c.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		r.cookies[c.Name] = c
+	}
+	return resp, nil
+}
+
+// withExpiry ... cookies に記録しておいた有効期限を付ける
+func (r *cookieRecorder) withExpiry(cookies []*http.Cookie) []*http.Cookie {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range cookies {
+		if rc, ok := r.cookies[c.Name]; ok && rc.Value == c.Value {
+			c.Expires = rc.Expires
+		}
+	}
+	return cookies
+}
+
+// newSessionBrowser ... Set-Cookie を記録するブラウザを作る (ログインに使う)
+func newSessionBrowser() (*browser.Browser, *cookieRecorder) {
+	rec := &cookieRecorder{base: clientTransport(), cookies: map[string]*http.Cookie{}}
+	br := surf.NewBrowser()
+	br.SetTransport(rec)
+	return br, rec
+}
@@ -0,0 +1,106 @@
+package online_judge
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withClientConfig ... テストの間だけ transport と clientConfig を差し替える
+func withClientConfig(conf httpConfig) func() {
+	prev, prevConfig := transport, clientConfig
+	transport, clientConfig = http.DefaultTransport, conf
+	return func() {
+		transport, clientConfig = prev, prevConfig
+	}
+}
+
+func TestJudgeTransportRetry(t *testing.T) {
+	fmt.Println("testing : http.go > judgeTransport (retry)")
+	defer withClientConfig(httpConfig{retries: 3, userAgent: "kide-test"})()
+
+	count := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		if r.Header.Get("User-Agent") != "kide-test" {
+			t.Errorf("User-Agent is %q", r.Header.Get("User-Agent"))
+		}
+		if count < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	resp, err := httpClient(nil).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 || string(body) != "ok" || count != 3 {
+		t.Errorf("status %d, body %q after %d requests", resp.StatusCode, body, count)
+	}
+
+	// POST は再試行しない
+	count = 0
+	resp, err = httpClient(nil).Post(srv.URL, "text/plain", strings.NewReader("source"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || count != 1 {
+		t.Errorf("POST : status %d after %d requests", resp.StatusCode, count)
+	}
+
+	// 再試行しても失敗し続ける場合は最後のレスポンスを返す
+	count = -10
+	resp, err = httpClient(nil).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || count != -6 {
+		t.Errorf("status %d, count %d", resp.StatusCode, count)
+	}
+}
+
+func TestJudgeTransportTimeout(t *testing.T) {
+	fmt.Println("testing : http.go > judgeTransport (timeout)")
+	defer withClientConfig(httpConfig{timeout: 50 * time.Millisecond})()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	_, err := httpClient(nil).Get(srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("unexpected error : %v", err)
+	}
+}
+
+func TestJudgeTransportInterval(t *testing.T) {
+	fmt.Println("testing : http.go > judgeTransport (interval)")
+	defer withClientConfig(httpConfig{interval: 50 * time.Millisecond})()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		resp, err := httpClient(nil).Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Errorf("3 requests took only %v", d)
+	}
+}
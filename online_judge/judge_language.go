@@ -0,0 +1,133 @@
+package online_judge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/algon-320/KIDE/language"
+	"github.com/algon-320/KIDE/setting"
+	"github.com/algon-320/KIDE/util"
+)
+
+// JudgeLanguage ... オンラインジャッジで提出時に選択できる言語
+type JudgeLanguage struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// LanguageCacheExpiration ... 言語一覧のキャッシュの有効期限
+const LanguageCacheExpiration = 7 * 24 * time.Hour
+
+type languageCache struct {
+	Date      time.Time       `json:"date"`
+	Languages []JudgeLanguage `json:"languages"`
+}
+
+// getLanguages ... 言語の一覧を返す (OnlineJudge.Languages の実装用)
+// キャッシュが有効期限内ならそれを返し、そうでなければ scrape で取得して cacheFile にキャッシュする
+// refresh: trueならキャッシュを使わずに取得し直す
+func getLanguages(cacheFile string, refresh bool, scrape func() ([]JudgeLanguage, error)) ([]JudgeLanguage, error) {
+	cachePath := util.CachePath(cacheFile)
+
+	if !refresh && util.FileExists(cachePath) {
+		if bytes, err := ioutil.ReadFile(cachePath); err == nil {
+			var cache languageCache
+			if err := json.Unmarshal(bytes, &cache); err == nil &&
+				time.Since(cache.Date) < LanguageCacheExpiration {
+				util.DebugPrint("Load language list from " + cachePath)
+				return cache.Languages, nil
+			}
+		}
+	}
+
+	langs, err := scrape()
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := json.Marshal(languageCache{Date: time.Now(), Languages: langs})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	json.Indent(&buf, jsonBytes, "", "  ")
+	if err := util.WriteFileAtomic(cachePath, buf.Bytes(), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, util.PrefixError+"File write error:", err)
+	}
+	return langs, nil
+}
+
+// scrapeLanguageOptions ... 提出フォームの言語の select 要素から言語の一覧を作る
+func scrapeLanguageOptions(sel *goquery.Selection) []JudgeLanguage {
+	ret := []JudgeLanguage{}
+	sel.Find("option").Each(func(_ int, opt *goquery.Selection) {
+		id, ok := opt.Attr("value")
+		if !ok || id == "" {
+			return
+		}
+		ret = append(ret, JudgeLanguage{ID: id, Name: strings.TrimSpace(opt.Text())})
+	})
+	return ret
+}
+
+// langIDFromSetting ... OnlineJudge.{settingKey}.LanguageID.{言語名} に設定された言語IDを返す
+// 設定の値は言語IDの文字列か、プロファイル名から言語IDへのマップ ("default" はプロファイル未指定の場合と該当するプロファイルが無い場合に使われる)
+func langIDFromSetting(settingKey string, lang language.Language, profile string) (string, bool) {
+	v, ok := setting.Get("OnlineJudge."+settingKey+".LanguageID."+lang.Name(), "")
+	if !ok {
+		return "", false
+	}
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case map[string]interface{}:
+		if id, ok := v[profile].(string); ok && profile != "" {
+			return id, true
+		}
+		if id, ok := v["default"].(string); ok {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// languageMappings ... OnlineJudge.{settingKey}.LanguageID の設定を、言語ID から `言語名` または `言語名@プロファイル名` の一覧へのマップとして返す
+// (OnlineJudge.LanguageMappings の実装用)
+func languageMappings(settingKey string) map[string][]string {
+	ret := map[string][]string{}
+	v, ok := setting.Get("OnlineJudge."+settingKey+".LanguageID", "")
+	if !ok {
+		return ret
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return ret
+	}
+	for langName, ids := range m {
+		switch ids := ids.(type) {
+		case string:
+			ret[ids] = append(ret[ids], langName)
+		case map[string]interface{}:
+			for profile, id := range ids {
+				if id, ok := id.(string); ok {
+					name := langName
+					if profile != "default" {
+						name += "@" + profile
+					}
+					ret[id] = append(ret[id], name)
+				}
+			}
+		}
+	}
+	for _, v := range ret {
+		sort.Strings(v)
+	}
+	return ret
+}
@@ -17,7 +17,7 @@ func TestFromProblemURL(t *testing.T) {
 		return
 	}
 
-	cf, err2 := FromProblemURL("http://codeforces.com/contest/835/problem/E")
+	cf, err2 := FromProblemURL("https://codeforces.com/contest/835/problem/E")
 	if err2 != nil {
 		t.Error(err2)
 		return
@@ -27,12 +27,12 @@ func TestFromProblemURL(t *testing.T) {
 		return
 	}
 
-	ac, err3 := FromProblemURL("https://beta.atcoder.jp/contests/arc079/tasks/arc079_b")
+	ac, err3 := FromProblemURL("https://atcoder.jp/contests/arc079/tasks/arc079_b")
 	if err3 != nil {
 		t.Error(err3)
 		return
 	}
-	if ac.Name() != ac.Name() {
+	if ac.Name() != AtCoder.Name() {
 		t.Error("AtCoderの問題URLのパースに失敗")
 		return
 	}
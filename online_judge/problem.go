@@ -1,31 +1,21 @@
 package online_judge
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"os"
-	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/algon-320/KIDE/util"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
-const (
-	// SamplecaseDir ... 問題のJSONが保存されるディレクトリ
-	SamplecaseDir = "samplecases"
-	// SamplecaseFilename ... 問題のJSONのファイル名
-	SamplecaseFilename = `problem_%s.json` // %sにIDを埋め込む
-)
-
 // TestCase ... サンプルケースの入出力
 type TestCase struct {
 	Input  string `json:"input"`
 	Output string `json:"output"`
+	Sample bool   `json:"sample,omitempty"` // ジャッジからダウンロードしたサンプルケース (付いていないものは自分で追加したもの)
 }
 
 type Problem struct {
@@ -35,149 +25,119 @@ type Problem struct {
 	URL       string      `json:"url"`
 	Oj        OnlineJudge `json:"oj"`
 	Cases     []TestCase  `json:"cases"`
+
+	// 以下は問題ページから読み取れた場合のみ設定される (以前のバージョンで保存した問題では空)
+	TimeLimitMs   int      `json:"time_limit_ms,omitempty"`   // 実行時間制限 (ミリ秒)
+	MemoryLimitMB int      `json:"memory_limit_mb,omitempty"` // メモリ制限 (MB)
+	Score         int      `json:"score,omitempty"`           // 配点
+	Interactive   bool     `json:"interactive,omitempty"`     // インタラクティブ (リアクティブ) な問題か
+	Tolerance     float64  `json:"tolerance,omitempty"`       // 出力の許容誤差 (絶対誤差または相対誤差)
+	Tags          []string `json:"tags,omitempty"`
+
+	// Statement ... 問題文のHTML (JSONとは別のファイルに保存する, LoadStatement で読み込む)
+	Statement string `json:"-"`
 }
 
-// TODO : String() にするべき
-func (p *Problem) Print() {
-	fd := int(os.Stdout.Fd())
-	width, _, err := terminal.GetSize(fd)
-	if err != nil {
-		width = 80
+// TimeLimit ... 実行時間制限を `2 sec` のような文字列で返す (分からない場合は空文字列)
+func (p *Problem) TimeLimit() string {
+	if p.TimeLimitMs <= 0 {
+		return ""
 	}
+	return strconv.FormatFloat(float64(p.TimeLimitMs)/1000, 'f', -1, 64) + " sec"
+}
 
-	fmt.Println("id:", p.ID)
-	fmt.Println("name:", p.Name)
-	fmt.Println("contest_id:", p.ContestID)
-	fmt.Println("url:", p.URL)
-	fmt.Println("oj:", p.Oj.Name())
-	for i, tc := range p.Cases {
-		util.PrintTitlef(width, 4, "=", "sample case %d", i)
-		util.PrintTitle(width, 8, "-", "Input")
-		fmt.Print(tc.Input)
-		util.PrintTitle(width, 8, "-", "Output")
-		fmt.Print(tc.Output)
+// MemoryLimit ... メモリ制限を `1024 MB` のような文字列で返す (分からない場合は空文字列)
+func (p *Problem) MemoryLimit() string {
+	if p.MemoryLimitMB <= 0 {
+		return ""
 	}
-	fmt.Println(strings.Repeat("=", width))
+	return strconv.Itoa(p.MemoryLimitMB) + " MB"
 }
 
-// Save ... ファイルに保存する
-func (p *Problem) Save() error {
-	// 実行ファイルのディレクトリに移動
-	prevDir, _ := os.Getwd()
-	defer os.Chdir(prevDir)
-	exeDir, _ := os.Executable()
-	exeDir = filepath.Dir(exeDir)
-	os.Chdir(exeDir)
-
-	p.ID = strings.ToUpper(p.ID)
-	filename := fmt.Sprintf(SamplecaseFilename, p.ID)
-
-	jsonBytes, err := json.Marshal(p)
-	if err != nil {
-		return err
+// Metadata ... 分かっている問題の情報を (名前, 値) の組で返す
+func (p *Problem) Metadata() [][2]string {
+	ret := [][2]string{}
+	if v := p.TimeLimit(); v != "" {
+		ret = append(ret, [2]string{"time_limit", v})
 	}
-
-	// サンプルケースのフォルダがなければ作る
-	if !util.FileExists(SamplecaseDir) {
-		if err = os.Mkdir(SamplecaseDir, 0775); err != nil {
-			return err
-		}
-		absPath, _ := filepath.Abs(SamplecaseDir)
-		fmt.Println(fmt.Sprintf(util.PrefixInfo+"Created a directory `%s`", absPath))
+	if v := p.MemoryLimit(); v != "" {
+		ret = append(ret, [2]string{"memory_limit", v})
 	}
-	os.Chdir(SamplecaseDir)
-
-	var buf bytes.Buffer
-	json.Indent(&buf, jsonBytes, "", "  ")
-	err = ioutil.WriteFile(filename, buf.Bytes(), 0644)
-	if err != nil {
-		return err
+	if p.Score > 0 {
+		ret = append(ret, [2]string{"score", strconv.Itoa(p.Score)})
 	}
-	fmt.Println(util.PrefixInfo + "Save problem : " + p.ID)
-	return nil
-}
-
-// LoadProblem ... id で指定された問題を読み込む
-func LoadProblem(id string) (*Problem, error) {
-	id = strings.ToUpper(id)
-
-	// 実行ファイルのディレクトリ/{SamplecaseDir}に移動
-	prevDir, _ := os.Getwd()
-	defer os.Chdir(prevDir)
-	exeDir, _ := os.Executable()
-	exeDir = filepath.Dir(exeDir)
-	os.Chdir(exeDir)
-	os.Chdir(SamplecaseDir)
-
-	filename := fmt.Sprintf(SamplecaseFilename, id)
-
-	if !util.FileExists(filename) {
-		return nil, &ErrFailedToLoadSamplecase{message: filename + " dosen't exist."}
+	if p.Interactive {
+		ret = append(ret, [2]string{"interactive", "yes"})
+	}
+	if p.Tolerance > 0 {
+		ret = append(ret, [2]string{"tolerance", strconv.FormatFloat(p.Tolerance, 'g', -1, 64)})
+	}
+	if len(p.Tags) > 0 {
+		ret = append(ret, [2]string{"tags", strings.Join(p.Tags, ", ")})
 	}
+	return ret
+}
 
-	bytes, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return nil, &ErrFailedToLoadSamplecase{message: "failed to open " + filename}
+// MarshalJSON ... Oj は登録されている名前として保存する
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type alias Problem
+	var ojName string
+	if r := Lookup(p.Oj); r != nil {
+		ojName = r.Name()
+	} else if p.Oj != nil {
+		return nil, &ErrNoSuchOnlineJudge{oj_name: p.Oj.Name()}
 	}
+	return json.Marshal(&struct {
+		*alias
+		Oj string `json:"oj"`
+	}{
+		alias: (*alias)(p),
+		Oj:    ojName,
+	})
+}
 
-	var tmp struct {
-		ID        string     `json:"id"`
-		ContestID string     `json:"contest_id"`
-		Name      string     `json:"name"`
-		URL       string     `json:"url"`
-		Oj        string     `json:"oj"`
-		Cases     []TestCase `json:"cases"`
+// UnmarshalJSON ... Oj は登録されている名前から探す
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	type alias Problem
+	tmp := struct {
+		*alias
+		Oj string `json:"oj"`
+	}{
+		alias: (*alias)(p),
 	}
-	err = json.Unmarshal(bytes, &tmp)
-	if err != nil {
-		return nil, err
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
 	}
 	oj, err := FromName(tmp.Oj)
 	if err != nil {
-		return nil, err
-	}
-	p := &Problem{
-		ID:        tmp.ID,
-		ContestID: tmp.ContestID,
-		Name:      tmp.Name,
-		URL:       tmp.URL,
-		Oj:        oj,
-		Cases:     tmp.Cases,
+		return err
 	}
-
-	util.DebugPrint("Load problem : " + id)
-	return p, nil
+	p.Oj = oj
+	return nil
 }
 
-// GetAllProblemID ... 保存済みの問題の一覧を返す
-func GetAllProblemID() []string {
-	// 実行ファイルのディレクトリ/{SamplecaseDir}に移動
-	prevDir, _ := os.Getwd()
-	defer os.Chdir(prevDir)
-	exeDir, _ := os.Executable()
-	exeDir = filepath.Dir(exeDir)
-	os.Chdir(exeDir)
-	os.Chdir(SamplecaseDir)
-
-	files, err := ioutil.ReadDir(".")
+// TODO : String() にするべき
+func (p *Problem) Print() {
+	fd := int(os.Stdout.Fd())
+	width, _, err := terminal.GetSize(fd)
 	if err != nil {
-		log.Fatal(err)
+		width = 80
 	}
 
-	ret := []string{}
-
-	pat := strings.Replace(SamplecaseFilename, `.`, `\.`, -1)
-	pat = strings.Replace(pat, `%s`, `(.*)`, -1)
-
-	re := regexp.MustCompile(pat)
-	for _, file := range files {
-		if !file.IsDir() {
-			group := re.FindSubmatch([]byte(file.Name()))
-			if group != nil {
-				ret = append(ret, string(group[1]))
-			}
-		}
+	fmt.Println("id:", p.Key())
+	fmt.Println("name:", p.Name)
+	fmt.Println("contest_id:", p.ContestID)
+	fmt.Println("url:", p.URL)
+	fmt.Println("oj:", p.Oj.Name())
+	for _, m := range p.Metadata() {
+		fmt.Printf("%s: %s\n", m[0], m[1])
 	}
-
-	return ret
+	for i, tc := range p.Cases {
+		util.PrintTitlef(width, 4, "=", "sample case %d", i)
+		util.PrintTitle(width, 8, "-", "Input")
+		fmt.Print(tc.Input)
+		util.PrintTitle(width, 8, "-", "Output")
+		fmt.Print(tc.Output)
+	}
+	fmt.Println(strings.Repeat("=", width))
 }
@@ -0,0 +1,289 @@
+package online_judge
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/algon-320/KIDE/util"
+)
+
+// 保存している問題の一覧 (インデックス) は {SamplecaseDir}/index.json に問題のキー -> IndexEntry の形で保存する
+// 問題を保存したとき、テストしたとき、提出したときに更新される
+// インデックスに無い問題 (以前のバージョンで保存したものなど) は ProblemIndex で読み込むときに追加される
+
+// indexFilename ... インデックスのファイル名
+const indexFilename = "index.json"
+
+// IndexEntry ... インデックスに記録する問題の情報
+type IndexEntry struct {
+	Key          string    `json:"-"`
+	Judge        string    `json:"judge"` // オンラインジャッジの名前 (OnlineJudge.Name)
+	ContestID    string    `json:"contest_id,omitempty"`
+	Name         string    `json:"name"`
+	URL          string    `json:"url"`
+	Tags         []string  `json:"tags,omitempty"`
+	DownloadedAt time.Time `json:"downloaded_at"` // 最初にダウンロードした日時
+
+	LocalVerdict  string    `json:"local_verdict,omitempty"` // 最後にサンプルケースでテストした結果 (`AC` か `WA`)
+	LocalTestedAt time.Time `json:"local_tested_at"`
+	JudgeVerdict  string    `json:"judge_verdict,omitempty"` // 最後に提出した結果 (JudgeStatus.Abbrev)
+	JudgedAt      time.Time `json:"judged_at"`
+	SolvedAt      time.Time `json:"solved_at"` // 最初に AC した日時 (まだの場合はゼロ値)
+}
+
+// Solved ... 提出して AC したことがあるか
+func (e *IndexEntry) Solved() bool {
+	return !e.SolvedAt.IsZero()
+}
+
+var indexMu sync.Mutex
+
+func indexPath() string {
+	return filepath.Join(samplecaseRoot(), indexFilename)
+}
+
+// loadIndex ... インデックスを読み込む (ファイルが無い場合は空, indexMu を取った状態で呼ぶ)
+func loadIndex() (map[string]*IndexEntry, error) {
+	idx := map[string]*IndexEntry{}
+	data, err := ioutil.ReadFile(indexPath())
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, &ErrBrokenIndex{path: indexPath(), err: err}
+	}
+	for key, e := range idx {
+		e.Key = key
+	}
+	return idx, nil
+}
+
+// saveIndex ... インデックスを保存する (indexMu を取った状態で呼ぶ)
+func saveIndex(idx map[string]*IndexEntry) error {
+	jsonBytes, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	json.Indent(&buf, jsonBytes, "", "  ")
+	return util.WriteFileAtomic(indexPath(), buf.Bytes(), 0644)
+}
+
+// updateIndex ... インデックスを読み込んで f で変更して保存する
+func updateIndex(f func(idx map[string]*IndexEntry)) error {
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	idx, err := loadIndex()
+	if err != nil {
+		return err
+	}
+	f(idx)
+	return saveIndex(idx)
+}
+
+// setProblem ... 問題 p の情報で e を更新する (ダウンロード日時と結果はそのまま)
+func (e *IndexEntry) setProblem(p *Problem) {
+	e.Key = p.Key()
+	e.Judge = p.Oj.Name()
+	e.ContestID = p.ContestID
+	e.Name = p.Name
+	e.URL = p.URL
+	e.Tags = p.Tags
+}
+
+// indexProblem ... 保存した問題をインデックスに追加する (既にある場合は問題の情報だけ更新する)
+func indexProblem(p *Problem, downloadedAt time.Time) error {
+	return updateIndex(func(idx map[string]*IndexEntry) {
+		e, ok := idx[p.Key()]
+		if !ok {
+			e = &IndexEntry{DownloadedAt: downloadedAt}
+			idx[p.Key()] = e
+		}
+		e.setProblem(p)
+	})
+}
+
+// RecordLocalVerdict ... サンプルケースでテストした結果をインデックスに記録する
+func (p *Problem) RecordLocalVerdict(passed bool) error {
+	return updateIndex(func(idx map[string]*IndexEntry) {
+		e := indexEntryOf(idx, p)
+		e.LocalVerdict = "WA"
+		if passed {
+			e.LocalVerdict = "AC"
+		}
+		e.LocalTestedAt = time.Now()
+	})
+}
+
+// RecordJudgeVerdict ... 提出した結果をインデックスに記録する (at: 提出日時, 分からない場合はゼロ値)
+func (p *Problem) RecordJudgeVerdict(status JudgeStatus, at time.Time) error {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return updateIndex(func(idx map[string]*IndexEntry) {
+		e := indexEntryOf(idx, p)
+		e.JudgeVerdict = status.Abbrev()
+		e.JudgedAt = at
+		if status == JudgeStatusAC && e.SolvedAt.IsZero() {
+			e.SolvedAt = at
+		}
+	})
+}
+
+// indexEntryOf ... idx の p のエントリ (無ければ追加する)
+func indexEntryOf(idx map[string]*IndexEntry, p *Problem) *IndexEntry {
+	e, ok := idx[p.Key()]
+	if !ok {
+		e = &IndexEntry{DownloadedAt: problemModTime(p.Key())}
+		e.setProblem(p)
+		idx[p.Key()] = e
+	}
+	return e
+}
+
+// problemModTime ... 問題のJSONの更新日時 (インデックスに無い問題のダウンロード日時の代わりに使う)
+func problemModTime(key string) time.Time {
+	info, err := os.Stat(keyToPath(key))
+	if err != nil {
+		return time.Now()
+	}
+	return info.ModTime()
+}
+
+// ProblemIndex ... 保存している問題のインデックスをキーの順で返す
+// 保存されているのにインデックスに無い問題は追加し、削除された問題はインデックスから消す
+func ProblemIndex() ([]*IndexEntry, error) {
+	keys := GetAllProblemID()
+
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	idx, err := loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	exists := map[string]bool{}
+	for _, key := range keys {
+		exists[key] = true
+		if _, ok := idx[key]; ok {
+			continue
+		}
+		p, err := LoadProblem(key)
+		if err != nil {
+			continue
+		}
+		e := &IndexEntry{DownloadedAt: problemModTime(key)}
+		e.setProblem(p)
+		idx[key] = e
+		changed = true
+	}
+	for key := range idx {
+		if !exists[key] {
+			delete(idx, key)
+			changed = true
+		}
+	}
+	if changed {
+		if err := saveIndex(idx); err != nil {
+			return nil, err
+		}
+	}
+
+	ret := make([]*IndexEntry, 0, len(idx))
+	for _, e := range idx {
+		ret = append(ret, e)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Key < ret[j].Key })
+	return ret, nil
+}
+
+// IndexFilter ... インデックスの絞り込みの条件 (空の条件は使わない)
+type IndexFilter struct {
+	Judge    string // オンラインジャッジの名前 (OnlineJudge.Name)
+	Contest  string // コンテストIDの一部 (大文字小文字の区別なし)
+	Unsolved bool   // まだ AC していない問題だけ
+	Search   string // キー、問題名、タグ、URLのどれかに含まれる文字列 (大文字小文字の区別なし, 空白区切りで全てを含むもの)
+}
+
+// Match ... e が条件に合うか
+func (f *IndexFilter) Match(e *IndexEntry) bool {
+	if f.Judge != "" && e.Judge != f.Judge {
+		return false
+	}
+	if f.Contest != "" && !strings.Contains(strings.ToLower(e.ContestID), strings.ToLower(f.Contest)) {
+		return false
+	}
+	if f.Unsolved && e.Solved() {
+		return false
+	}
+	if f.Search != "" {
+		text := strings.ToLower(strings.Join(append([]string{e.Key, e.Name, e.URL}, e.Tags...), "\n"))
+		for _, word := range strings.Fields(strings.ToLower(f.Search)) {
+			if !strings.Contains(text, word) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// FilterIndex ... entries のうち f に合うものを返す
+func FilterIndex(entries []*IndexEntry, f *IndexFilter) []*IndexEntry {
+	ret := []*IndexEntry{}
+	for _, e := range entries {
+		if f.Match(e) {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+// IndexSortKeys ... SortIndex で使える並べ替えの基準
+var IndexSortKeys = []string{"id", "name", "oj", "date", "local", "judge"}
+
+// SortIndex ... entries を by の順に並べ替える (同じ場合はキーの順)
+// by: IndexSortKeys のどれか, reverse: 逆順にする
+func SortIndex(entries []*IndexEntry, by string, reverse bool) error {
+	var less func(a, b *IndexEntry) bool
+	switch by {
+	case "id", "":
+		less = func(a, b *IndexEntry) bool { return false }
+	case "name":
+		less = func(a, b *IndexEntry) bool { return a.Name < b.Name }
+	case "oj":
+		less = func(a, b *IndexEntry) bool { return a.Judge < b.Judge }
+	case "date":
+		less = func(a, b *IndexEntry) bool { return a.DownloadedAt.Before(b.DownloadedAt) }
+	case "local":
+		less = func(a, b *IndexEntry) bool { return a.LocalVerdict < b.LocalVerdict }
+	case "judge":
+		less = func(a, b *IndexEntry) bool { return a.JudgeVerdict < b.JudgeVerdict }
+	default:
+		return &ErrInvalidSortKey{key: by, candidates: IndexSortKeys}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if reverse {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.Key < b.Key
+	})
+	return nil
+}
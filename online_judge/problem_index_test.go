@@ -0,0 +1,115 @@
+package online_judge
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestProblemIndex(t *testing.T) {
+	fmt.Println("testing : problem_index.go > ProblemIndex, FilterIndex, SortIndex")
+
+	defer os.RemoveAll(samplecaseRoot())
+
+	problems := []*Problem{
+		{ID: "a", ContestID: "abc300", Name: "Hello", Oj: AtCoder},
+		{ID: "e", ContestID: "1234", Name: "Range Queries", Oj: Codeforces, Tags: []string{"data structures", "segment tree"}},
+		{ID: "1", Name: "Sum", Oj: Yukicoder},
+	}
+	for _, p := range problems {
+		if err := p.save(false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := problems[0].RecordLocalVerdict(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := problems[0].RecordJudgeVerdict(JudgeStatusAC, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// AC した後に WA になっても解いた問題のまま
+	if err := problems[0].RecordJudgeVerdict(JudgeStatusWA, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := problems[1].RecordLocalVerdict(false); err != nil {
+		t.Fatal(err)
+	}
+
+	// インデックスに無い問題 (以前のバージョンで保存したもの) も追加される
+	os.Remove(indexPath())
+	if err := problems[0].RecordJudgeVerdict(JudgeStatusAC, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ProblemIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("ProblemIndex returned %d entries", len(entries))
+	}
+	if e := entries[0]; e.Key != "atcoder/abc300/A" || e.JudgeVerdict != "AC" || !e.Solved() || e.DownloadedAt.IsZero() {
+		t.Errorf("unexpected entry : %+v", e)
+	}
+
+	keys := func(entries []*IndexEntry) string {
+		ret := ""
+		for _, e := range entries {
+			ret += e.Key + " "
+		}
+		return ret
+	}
+	testcase := []struct {
+		filter IndexFilter
+		expect string
+	}{
+		{IndexFilter{Judge: Codeforces.Name()}, "codeforces/1234/E "},
+		{IndexFilter{Contest: "ABC"}, "atcoder/abc300/A "},
+		{IndexFilter{Unsolved: true}, "codeforces/1234/E yukicoder/1 "},
+		{IndexFilter{Search: "Segment TREE"}, "codeforces/1234/E "},
+		{IndexFilter{Search: "segment hello"}, ""},
+	}
+	for _, tc := range testcase {
+		if got := keys(FilterIndex(entries, &tc.filter)); got != tc.expect {
+			t.Errorf("FilterIndex(%+v) = %q, expected %q", tc.filter, got, tc.expect)
+		}
+	}
+
+	if err := SortIndex(entries, "name", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(entries); got != "atcoder/abc300/A codeforces/1234/E yukicoder/1 " {
+		t.Errorf("SortIndex(name) = %q", got)
+	}
+	if err := SortIndex(entries, "oj", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(entries); got != "yukicoder/1 codeforces/1234/E atcoder/abc300/A " {
+		t.Errorf("SortIndex(oj, reverse) = %q", got)
+	}
+	if err := SortIndex(entries, "size", false); err == nil {
+		t.Error("不正な並べ替えの基準でエラーになりません")
+	}
+
+	// 削除された問題はインデックスから消える
+	os.Remove(keyToPath("yukicoder/1"))
+	if entries, err := ProblemIndex(); err != nil || len(entries) != 2 {
+		t.Errorf("ProblemIndex returned %d entries : %v", len(entries), err)
+	}
+
+	// インデックスが壊れていても問題は保存できる
+	if err := ioutil.WriteFile(indexPath(), []byte("{broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := problems[2].save(false); err != nil {
+		t.Errorf("save failed with the broken index : %v", err)
+	}
+	if _, err := LoadProblem("yukicoder/1"); err != nil {
+		t.Error(err)
+	}
+	if _, err := ProblemIndex(); err == nil {
+		t.Error("壊れたインデックスでエラーになりません")
+	}
+}
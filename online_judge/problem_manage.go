@@ -0,0 +1,232 @@
+package online_judge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/algon-320/KIDE/util"
+)
+
+// RemoveProblem ... id で指定された問題 (JSONと問題文) を削除する
+// return: 削除した問題のキー
+func RemoveProblem(id string) (string, error) {
+	key, err := ResolveProblemID(id)
+	if err != nil {
+		return "", err
+	}
+	if err := removeProblemFiles(key); err != nil {
+		return "", err
+	}
+	err = updateIndex(func(idx map[string]*IndexEntry) {
+		delete(idx, key)
+	})
+	return key, err
+}
+
+// removeProblemFiles ... 問題のファイルを削除して、空になったディレクトリも削除する
+func removeProblemFiles(key string) error {
+	if err := os.Remove(keyToPath(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(keyToStatementPath(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	root := filepath.Clean(samplecaseRoot())
+	for dir := filepath.Dir(keyToPath(key)); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break // 空でない
+		}
+	}
+	return nil
+}
+
+// MoveProblem ... id で指定された問題のコンテストIDと問題IDを変える (テストや提出の記録も引き継ぐ)
+// dest: `{コンテストID}/{問題ID}` か `{問題ID}` (コンテストはそのまま), 先頭にオンラインジャッジのキーを付けてもよい
+// return: 移動した後の問題
+func MoveProblem(id, dest string) (*Problem, error) {
+	oldKey, err := ResolveProblemID(id)
+	if err != nil {
+		return nil, err
+	}
+	p, err := LoadProblem(oldKey)
+	if err != nil {
+		return nil, err
+	}
+	if s, err := LoadStatement(oldKey); err == nil {
+		p.Statement = s.Statement
+	}
+
+	parts := strings.Split(strings.Trim(dest, "/"), "/")
+	if r := Lookup(p.Oj); r != nil && len(parts) > 1 && parts[0] == r.Key {
+		parts = parts[1:]
+	}
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		p.ID = parts[0]
+	case len(parts) == 2 && parts[1] != "":
+		p.ContestID, p.ID = parts[0], parts[1]
+	default:
+		return nil, &ErrInvalidDestination{dest: dest}
+	}
+	p.ID = strings.ToUpper(p.ID)
+	newKey := p.Key()
+	if newKey == oldKey {
+		return p, nil
+	}
+	if util.FileExists(keyToPath(newKey)) {
+		return nil, &ErrProblemExists{key: newKey}
+	}
+
+	if err := p.save(false); err != nil {
+		return nil, err
+	}
+	err = updateIndex(func(idx map[string]*IndexEntry) {
+		if old, ok := idx[oldKey]; ok {
+			e := *old
+			e.setProblem(p)
+			idx[newKey] = &e
+			delete(idx, oldKey)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, removeProblemFiles(oldKey)
+}
+
+// problemRefetcher ... ログインせずに問題をダウンロード出来るオンラインジャッジ (AOJ) が実装するインターフェース
+type problemRefetcher interface {
+	fetchProblem(problemURL string) (*Problem, error)
+}
+
+// fetchProblemOf ... oj の problemURL の問題をダウンロードする (保存はしない)
+func fetchProblemOf(oj OnlineJudge, problemURL string) (*Problem, error) {
+	switch j := oj.(type) {
+	case contestJudge:
+		br, err := j.login()
+		if err != nil {
+			return nil, err
+		}
+		return j.fetchProblem(br, problemURL)
+	case problemRefetcher:
+		return j.fetchProblem(problemURL)
+	}
+	return nil, &ErrRefreshNotSupported{oj_name: oj.Name()}
+}
+
+// RefreshProblem ... id で指定された問題をダウンロードし直して、サンプルケースと問題の情報を更新する
+// 自分で追加したテストケース (ジャッジからダウンロードしたものでないもの) は残す
+// return: 更新した問題, 残したテストケースの数
+func RefreshProblem(id string) (*Problem, int, error) {
+	p, err := LoadProblem(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	fresh, err := fetchProblemOf(p.Oj, p.URL)
+	if err != nil {
+		return nil, 0, err
+	}
+	// キーが変わらないようにする
+	fresh.ID, fresh.ContestID = p.ID, p.ContestID
+
+	var kept int
+	fresh.Cases, kept = mergeCases(p.Cases, fresh.Cases)
+	if err := fresh.save(false); err != nil {
+		return nil, 0, err
+	}
+	return fresh, kept, nil
+}
+
+// mergeCases ... ダウンロードし直したサンプルケース samples の後に、以前のテストケース old のうち自分で追加したもの (Sample が付いていないもの) を加える
+// Sample が1つも付いていない問題 (以前のバージョンで保存したもの) は、samples と入力が同じものをサンプルケースとみなす
+// (同じ入力のものが複数ある場合は前にあるものから順にサンプルケース1つに1つずつ対応させる)
+// return: 新しいテストケース, 残したテストケースの数
+func mergeCases(old, samples []TestCase) ([]TestCase, int) {
+	isSample := make([]bool, len(old))
+	marked := false
+	for i, c := range old {
+		isSample[i] = c.Sample
+		marked = marked || c.Sample
+	}
+	if !marked {
+		remaining := map[string]int{}
+		for _, c := range samples {
+			remaining[c.Input]++
+		}
+		for i, c := range old {
+			if remaining[c.Input] > 0 {
+				remaining[c.Input]--
+				isSample[i] = true
+			}
+		}
+	}
+
+	ret := append([]TestCase{}, samples...)
+	kept := 0
+	for i, c := range old {
+		if isSample[i] {
+			continue
+		}
+		ret = append(ret, c)
+		kept++
+	}
+	return ret, kept
+}
+
+// contestEnder ... コンテストの終了時刻を取得出来るオンラインジャッジが実装するインターフェース
+type contestEnder interface {
+	// contestEndTime ... コンテストID contestID のコンテストの終了時刻
+	contestEndTime(contestID string) (time.Time, error)
+}
+
+// PruneOptions ... PruneProblems で削除する問題の条件 (どれかに当てはまるものを削除する)
+type PruneOptions struct {
+	OlderThan time.Duration // ダウンロードしてからこれ以上経った問題 (0 なら使わない)
+	Finished  bool          // 終了したコンテストの問題 (終了時刻が取得出来るオンラインジャッジのみ)
+}
+
+// PruneCandidates ... opt の条件に当てはまる問題を返す
+func PruneCandidates(opt PruneOptions, now time.Time) ([]*IndexEntry, error) {
+	entries, err := ProblemIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	finished := map[string]bool{} // コンテストのキー -> 終了したか (コンテストごとに1度だけ調べる)
+	contestFinished := func(e *IndexEntry) bool {
+		if e.ContestID == "" {
+			return false
+		}
+		contestKey := strings.TrimSuffix(e.Key, "/"+filepath.Base(e.Key))
+		if f, ok := finished[contestKey]; ok {
+			return f
+		}
+		finished[contestKey] = false
+		oj, err := FromName(e.Judge)
+		if err != nil {
+			return false
+		}
+		ender, ok := oj.(contestEnder)
+		if !ok {
+			return false
+		}
+		end, err := ender.contestEndTime(e.ContestID)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, util.PrefixCaution+fmt.Sprintf("Failed to get the end time of `%s` : %s", contestKey, err))
+			return false
+		}
+		finished[contestKey] = end.Before(now)
+		return finished[contestKey]
+	}
+
+	ret := []*IndexEntry{}
+	for _, e := range entries {
+		if (opt.OlderThan > 0 && now.Sub(e.DownloadedAt) >= opt.OlderThan) || (opt.Finished && contestFinished(e)) {
+			ret = append(ret, e)
+		}
+	}
+	return ret, nil
+}
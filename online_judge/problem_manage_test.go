@@ -0,0 +1,214 @@
+package online_judge
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/algon-320/KIDE/util"
+)
+
+func TestMergeCases(t *testing.T) {
+	fmt.Println("testing : problem_manage.go > mergeCases")
+
+	old := []TestCase{
+		{Input: "1\n", Output: "2\n", Sample: true},     // そのまま残っているサンプル
+		{Input: "3\n", Output: "wrong\n", Sample: true}, // 入力が修正されたサンプル
+		{Input: "100\n", Output: "101\n"},               // 自分で追加したもの
+		{Input: "1\n", Output: "2\n"},                   // 自分で追加したもの (サンプルと同じ入力)
+	}
+	samples := []TestCase{{Input: "1\n", Output: "2\n", Sample: true}, {Input: "4\n", Output: "5\n", Sample: true}}
+	got, kept := mergeCases(old, samples)
+	expect := []TestCase{
+		{Input: "1\n", Output: "2\n", Sample: true},
+		{Input: "4\n", Output: "5\n", Sample: true},
+		{Input: "100\n", Output: "101\n"},
+		{Input: "1\n", Output: "2\n"},
+	}
+	if kept != 2 || !reflect.DeepEqual(got, expect) {
+		t.Errorf("mergeCases returned %+v, %d", got, kept)
+	}
+
+	// 以前のバージョンで保存した問題 (Sample が付いていない) は入力が同じものをサンプルケースとみなす
+	// (ジャッジ側でサンプルケースの数が変わっていても、自分で追加したものは残る)
+	legacy := []TestCase{
+		{Input: "1\n", Output: "2\n"},
+		{Input: "100\n", Output: "101\n"},
+		{Input: "1\n", Output: "2\n"}, // サンプルと同じ入力のものが2つ目 (自分で追加したもの)
+		{Input: "200\n", Output: "201\n"},
+	}
+	got, kept = mergeCases(legacy, samples)
+	expect = []TestCase{
+		{Input: "1\n", Output: "2\n", Sample: true},
+		{Input: "4\n", Output: "5\n", Sample: true},
+		{Input: "100\n", Output: "101\n"},
+		{Input: "1\n", Output: "2\n"},
+		{Input: "200\n", Output: "201\n"},
+	}
+	if kept != 3 || !reflect.DeepEqual(got, expect) {
+		t.Errorf("mergeCases returned %+v, %d", got, kept)
+	}
+}
+
+func TestRemoveAndMoveProblem(t *testing.T) {
+	fmt.Println("testing : problem_manage.go > RemoveProblem, MoveProblem")
+
+	defer os.RemoveAll(samplecaseRoot())
+
+	a := &Problem{ID: "a", ContestID: "abc300", Name: "A", Oj: AtCoder, Statement: "<p>A</p>"}
+	b := &Problem{ID: "b", ContestID: "abc300", Name: "B", Oj: AtCoder}
+	for _, p := range []*Problem{a, b} {
+		if err := p.save(false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.RecordJudgeVerdict(JudgeStatusAC, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 記録を引き継いで移動する
+	moved, err := MoveProblem("abc300/A", "atcoder/abc301/C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Key() != "atcoder/abc301/C" || moved.ContestID != "abc301" {
+		t.Errorf("moved to %s", moved.Key())
+	}
+	if util.FileExists(keyToPath("atcoder/abc300/A")) || util.FileExists(keyToStatementPath("atcoder/abc300/A")) {
+		t.Error("the old files are left")
+	}
+	if p, err := LoadStatement("abc301/C"); err != nil || p.Statement != "<p>A</p>" || p.ID != "C" {
+		t.Errorf("the statement was not moved : %v", err)
+	}
+	entries, err := ProblemIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Key != "atcoder/abc301/C" || !entries[1].Solved() {
+		t.Errorf("index after moving : %+v", entries)
+	}
+	if _, err := MoveProblem("abc301/C", "abc300/B"); err == nil {
+		t.Error("既にある問題に移動出来てしまいます")
+	}
+	if _, err := MoveProblem("abc301/C", "a/b/c"); err == nil {
+		t.Error("不正な移動先でエラーになりません")
+	}
+
+	// 削除すると空になったディレクトリも消える
+	if key, err := RemoveProblem("abc301/C"); err != nil || key != "atcoder/abc301/C" {
+		t.Fatalf("RemoveProblem returned %s, %v", key, err)
+	}
+	if util.FileExists(filepath.Dir(keyToPath("atcoder/abc301/C"))) {
+		t.Error("the contest directory is left")
+	}
+	if entries, _ := ProblemIndex(); len(entries) != 1 {
+		t.Errorf("index after removing : %+v", entries)
+	}
+	if _, err := RemoveProblem("abc301/C"); err == nil {
+		t.Error("存在しない問題を削除出来てしまいます")
+	}
+}
+
+func TestRefreshProblem(t *testing.T) {
+	fmt.Println("testing : problem_manage.go > RefreshProblem")
+	defer serveFixtures(t, atcoderRoutes(t))()
+	defer os.RemoveAll(samplecaseRoot())
+
+	os.Setenv("ATCODER_HANDLE", "kide")
+	os.Setenv("ATCODER_PASSWORD", "password")
+	defer os.Unsetenv("ATCODER_HANDLE")
+	defer os.Unsetenv("ATCODER_PASSWORD")
+	defer util.RemoveLoginSession(AtCoder.sessionFile)
+	defer AtCoder.session.set(nil)
+
+	old := &Problem{
+		ID: "A", ContestID: "practice", Name: "old name", Oj: AtCoder,
+		URL: "https://atcoder.jp/contests/practice/tasks/practice_1",
+		Cases: []TestCase{
+			{Input: "1\n2 3\ntest\n", Output: "wrong\n", Sample: true},
+			{Input: "5\n5 5\nmy\n", Output: "15 my\n"},
+		},
+	}
+	if err := old.save(false); err != nil {
+		t.Fatal(err)
+	}
+
+	p, kept, err := RefreshProblem("practice/A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept != 1 || len(p.Cases) != 3 || p.Cases[0].Output != "6 test\n" || !p.Cases[0].Sample || p.Cases[2].Sample {
+		t.Errorf("refreshed cases : %+v (kept %d)", p.Cases, kept)
+	}
+	loaded, err := LoadStatement("practice/A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Name != "practice_1" || loaded.TimeLimitMs != 2000 || len(loaded.Cases) != 3 {
+		t.Errorf("refreshed problem : %+v", loaded)
+	}
+}
+
+func TestPruneCandidates(t *testing.T) {
+	fmt.Println("testing : problem_manage.go > PruneCandidates")
+	defer serveFixtures(t, map[string]http.HandlerFunc{
+		"atcoder.jp/contests/abc001": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<html><body><small class="contest-duration">
+				<time class="fixtime-full">2016-01-01 21:00:00+0900</time> - <time class="fixtime-full">2016-01-01 22:40:00+0900</time>
+			</small></body></html>`)
+		},
+		"atcoder.jp/contests/abc999": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<html><body><small class="contest-duration">
+				<time class="fixtime-full">2099-01-01 21:00:00+0900</time> - <time class="fixtime-full">2099-01-01 22:40:00+0900</time>
+			</small></body></html>`)
+		},
+	})()
+	defer os.RemoveAll(samplecaseRoot())
+
+	now := time.Now()
+	problems := []*Problem{
+		{ID: "A", ContestID: "abc001", Oj: AtCoder},
+		{ID: "B", ContestID: "abc001", Oj: AtCoder},
+		{ID: "A", ContestID: "abc999", Oj: AtCoder},
+		{ID: "1", Oj: Yukicoder},
+	}
+	for _, p := range problems {
+		if err := p.save(false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// yukicoder/1 は 30 日前にダウンロードしたことにする
+	updateIndex(func(idx map[string]*IndexEntry) {
+		idx["yukicoder/1"].DownloadedAt = now.Add(-30 * 24 * time.Hour)
+	})
+
+	keys := func(entries []*IndexEntry) string {
+		ret := ""
+		for _, e := range entries {
+			ret += e.Key + " "
+		}
+		return ret
+	}
+	testcase := []struct {
+		opt    PruneOptions
+		expect string
+	}{
+		{PruneOptions{OlderThan: 7 * 24 * time.Hour}, "yukicoder/1 "},
+		{PruneOptions{Finished: true}, "atcoder/abc001/A atcoder/abc001/B "},
+		{PruneOptions{OlderThan: 7 * 24 * time.Hour, Finished: true}, "atcoder/abc001/A atcoder/abc001/B yukicoder/1 "},
+		{PruneOptions{}, ""},
+	}
+	for _, tc := range testcase {
+		entries, err := PruneCandidates(tc.opt, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := keys(entries); got != tc.expect {
+			t.Errorf("PruneCandidates(%+v) = %q, expected %q", tc.opt, got, tc.expect)
+		}
+	}
+}
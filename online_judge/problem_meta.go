@@ -0,0 +1,138 @@
+package online_judge
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	timeLimitRegexp   = regexp.MustCompile(`([0-9]+(?:\.[0-9]+)?)\s*(ms|msec|milliseconds?|sec|seconds?|s\b|秒)`)
+	memoryLimitRegexp = regexp.MustCompile(`(?i)([0-9]+(?:\.[0-9]+)?)\s*(KiB|KB|kilobytes?|MiB|MB|megabytes?|GiB|GB|gigabytes?)`)
+	// toleranceRegexp ... `10^{-6}`、`10^-6`、`1e-6` のような書き方の指数を取り出す
+	toleranceRegexp = regexp.MustCompile(`10\s*\^\s*\{?\s*[-−]\s*([0-9]+)\s*\}?|\b1\s*[eE]\s*-\s*([0-9]+)\b`)
+	sentenceRegexp  = regexp.MustCompile(`。|\.\s|\n`)
+	scoreRegexp     = regexp.MustCompile(`(?:配点|Score)\s*[:：]\s*([0-9]+)`)
+)
+
+// parseTimeLimit ... `2 sec`、`1 second`、`実行時間制限 : 1ケース 5.000秒` などから実行時間制限をミリ秒で返す (見つからない場合は0)
+func parseTimeLimit(text string) int {
+	group := timeLimitRegexp.FindStringSubmatch(text)
+	if group == nil {
+		return 0
+	}
+	v, err := strconv.ParseFloat(group[1], 64)
+	if err != nil {
+		return 0
+	}
+	if strings.HasPrefix(group[2], "m") {
+		return int(v)
+	}
+	return int(math.Round(v * 1000))
+}
+
+// parseMemoryLimit ... `1024 MB`、`256 megabytes`、`131072 KB` などからメモリ制限をMBで返す (見つからない場合は0)
+func parseMemoryLimit(text string) int {
+	group := memoryLimitRegexp.FindStringSubmatch(text)
+	if group == nil {
+		return 0
+	}
+	v, err := strconv.ParseFloat(group[1], 64)
+	if err != nil {
+		return 0
+	}
+	switch strings.ToLower(group[2][:1]) {
+	case "k":
+		v /= 1024
+	case "g":
+		v *= 1024
+	}
+	return int(math.Round(v))
+}
+
+// parseTolerance ... 問題文の誤差の許容についての記述から許容誤差を返す (記述が無い場合は0)
+// 「誤差」や「error」を含む文の中の `10^{-6}` などを探す
+func parseTolerance(statement string) float64 {
+	for _, sentence := range sentenceRegexp.Split(statement, -1) {
+		lower := strings.ToLower(sentence)
+		if !strings.Contains(sentence, "誤差") && !strings.Contains(lower, "error") {
+			continue
+		}
+		group := toleranceRegexp.FindStringSubmatch(sentence)
+		if group == nil {
+			continue
+		}
+		exp := group[1]
+		if exp == "" {
+			exp = group[2]
+		}
+		n, err := strconv.Atoi(exp)
+		if err != nil {
+			continue
+		}
+		return math.Pow(10, -float64(n))
+	}
+	return 0
+}
+
+// parseScore ... `配点 : 100 点`、`Score : 100 points` から配点を返す (見つからない場合は0)
+func parseScore(text string) int {
+	group := scoreRegexp.FindStringSubmatch(text)
+	if group == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(group[1])
+	return n
+}
+
+// isInteractive ... 問題文からインタラクティブ (リアクティブ) な問題かどうかを判定する
+// 英語の問題文は `Interaction` の見出し (Codeforces の入出力の節など) があるかどうかで判定する
+func isInteractive(statement *goquery.Selection) bool {
+	text := statement.Text()
+	if strings.Contains(text, "インタラクティブ") || strings.Contains(text, "リアクティブ") ||
+		strings.Contains(strings.ToLower(text), "interactive problem") {
+		return true
+	}
+	heading := false
+	statement.Find("h1, h2, h3, h4, h5, h6, .section-title").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		heading = strings.EqualFold(strings.TrimSpace(s.Text()), "Interaction")
+		return !heading
+	})
+	return heading
+}
+
+// fillStatementMetadata ... 問題文 statement から許容誤差とインタラクティブかどうかを p に設定し、問題文のHTMLを保存しておく
+func fillStatementMetadata(p *Problem, statement *goquery.Selection) {
+	p.Statement = statementHTML(statement)
+	text := statement.Text()
+	p.Tolerance = parseTolerance(text)
+	p.Interactive = isInteractive(statement)
+}
+
+// textAfter ... text の最初の marker より後ろの部分 (marker が無い場合は空文字列)
+func textAfter(text, marker string) string {
+	i := strings.Index(text, marker)
+	if i < 0 {
+		return ""
+	}
+	return text[i+len(marker):]
+}
+
+// fillLimits ... `実行時間制限: 2 sec / メモリ制限: 1024 MB` のような文から実行時間制限とメモリ制限を p に設定する
+func fillLimits(p *Problem, text string) {
+	for _, marker := range []string{"実行時間制限", "Time Limit", "time limit"} {
+		if t := textAfter(text, marker); t != "" {
+			p.TimeLimitMs = parseTimeLimit(t)
+			break
+		}
+	}
+	for _, marker := range []string{"メモリ制限", "Memory Limit", "memory limit"} {
+		if t := textAfter(text, marker); t != "" {
+			p.MemoryLimitMB = parseMemoryLimit(t)
+			break
+		}
+	}
+}
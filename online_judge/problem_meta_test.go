@@ -0,0 +1,110 @@
+package online_judge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestParseLimits(t *testing.T) {
+	fmt.Println("testing : problem_meta.go > parseTimeLimit, parseMemoryLimit")
+
+	times := map[string]int{
+		"2 sec":                       2000,
+		"time limit per test1 second": 1000,
+		"1ケース 5.000秒 / メモリ制限 : 512 MB": 5000,
+		"2.5 seconds": 2500,
+		"500 ms":      500,
+		"no limit":    0,
+	}
+	for text, expect := range times {
+		if got := parseTimeLimit(text); got != expect {
+			t.Errorf("parseTimeLimit(%q) = %d, expected %d", text, got, expect)
+		}
+	}
+
+	memories := map[string]int{
+		"1024 MB":       1024,
+		"1024 MiB":      1024,
+		"256 megabytes": 256,
+		"131072 KB":     128,
+		"2 GB":          2048,
+		"":              0,
+	}
+	for text, expect := range memories {
+		if got := parseMemoryLimit(text); got != expect {
+			t.Errorf("parseMemoryLimit(%q) = %d, expected %d", text, got, expect)
+		}
+	}
+}
+
+func TestParseStatementMetadata(t *testing.T) {
+	fmt.Println("testing : problem_meta.go > parseTolerance, parseScore, isInteractive")
+
+	tolerances := map[string]float64{
+		"想定解との絶対誤差または相対誤差が 10^{-6} 以下であれば正解として扱われる。":                                                   1e-6,
+		"Your answer is considered correct if its absolute or relative error does not exceed 10^{-9}.": 1e-9,
+		"The error of 1e-4 is allowed.":             1e-4,
+		"1 \\leq N \\leq 10^{5}. Print the answer.": 0,
+		"制約 : 1 ≤ N ≤ 10^5。誤差は許されない。":               0,
+	}
+	for text, expect := range tolerances {
+		if got := parseTolerance(text); got != expect {
+			t.Errorf("parseTolerance(%q) = %g, expected %g", text, got, expect)
+		}
+	}
+
+	if got := parseScore("配点 : 300 点"); got != 300 {
+		t.Errorf("parseScore returned %d", got)
+	}
+	if got := parseScore("Score : 500 points"); got != 500 {
+		t.Errorf("parseScore returned %d", got)
+	}
+	interactive := map[string]bool{
+		"<p>この問題はインタラクティブな問題です。</p>":                                                            true,
+		`<div class="section-title">Interaction</div><p>The jury ...</p>`:                       true,
+		"<h3>Interaction</h3><p>...</p>":                                                        true,
+		"<p>Print the answer.</p>":                                                              false,
+		"<p>There is no interaction between the queries.</p>":                                   false,
+		`<div class="section-title">Note</div><p>Interaction with the judge is not needed.</p>`: false,
+	}
+	for html, expect := range interactive {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isInteractive(doc.Selection); got != expect {
+			t.Errorf("isInteractive(%q) = %v, expected %v", html, got, expect)
+		}
+	}
+}
+
+func TestProblemMetadataJSON(t *testing.T) {
+	fmt.Println("testing : problem.go > Problem (metadata)")
+
+	// 以前のバージョンで保存した問題も読める
+	old := `{"id":"A","contest_id":"abc300","name":"abc300_a","url":"https://atcoder.jp/contests/abc300/tasks/abc300_a","oj":"atcoder","cases":[]}`
+	var p Problem
+	if err := json.Unmarshal([]byte(old), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.TimeLimitMs != 0 || len(p.Metadata()) != 0 {
+		t.Errorf("unexpected metadata : %v", p.Metadata())
+	}
+
+	p.TimeLimitMs, p.MemoryLimitMB, p.Tolerance, p.Tags = 1500, 1024, 1e-6, []string{"dp"}
+	data, err := json.Marshal(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Problem
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.TimeLimit() != "1.5 sec" || loaded.MemoryLimit() != "1024 MB" || loaded.Tolerance != 1e-6 || len(loaded.Tags) != 1 {
+		t.Errorf("unexpected metadata : %v", loaded.Metadata())
+	}
+}
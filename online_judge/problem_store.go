@@ -0,0 +1,276 @@
+package online_judge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/algon-320/KIDE/util"
+)
+
+// 問題は {SamplecaseDir}/{オンラインジャッジ}/{コンテストID}/{問題ID}.json に保存される
+// (問題文のHTMLは同じ場所の {問題ID}.html)
+// (コンテストIDが無い場合は {SamplecaseDir}/{オンラインジャッジ}/{問題ID}.json)
+// このディレクトリ構成と同じ `atcoder/abc300/A` のような文字列を問題のキーとして使う
+
+const (
+	// SamplecaseDir ... 問題のJSONが保存されるディレクトリ
+	SamplecaseDir = "samplecases"
+	// SamplecaseExt ... 問題のJSONの拡張子
+	SamplecaseExt = ".json"
+	// legacySamplecaseFilename ... 以前の問題のJSONのファイル名 (%sにIDを埋め込む)
+	legacySamplecaseFilename = `problem_%s.json`
+	// currentContestFilename ... 最後にダウンロードしたコンテストのキーを保存するファイル
+	currentContestFilename = "current_contest"
+)
+
+// samplecaseRoot ... 問題のJSONが保存されるディレクトリのパス (util.DataDir の下)
+func samplecaseRoot() string {
+	return util.DataPath(SamplecaseDir)
+}
+
+// ContestKey ... 問題が属するコンテストのキー (`atcoder/abc300` など, コンテストが無い場合は `yukicoder` など)
+func (p *Problem) ContestKey() string {
+	var judgeKey string
+	if r := Lookup(p.Oj); r != nil {
+		judgeKey = r.Key
+	}
+	if p.ContestID == "" {
+		return judgeKey
+	}
+	return judgeKey + "/" + p.ContestID
+}
+
+// Key ... 問題のキー (`atcoder/abc300/A` など)
+func (p *Problem) Key() string {
+	return p.ContestKey() + "/" + strings.ToUpper(p.ID)
+}
+
+// keyToPath ... 問題のキーから保存先のパスを返す
+func keyToPath(key string) string {
+	return filepath.Join(samplecaseRoot(), filepath.FromSlash(key)+SamplecaseExt)
+}
+
+// Save ... ファイルに保存する
+// 保存した問題のコンテストが現在のコンテストになる
+func (p *Problem) Save() error {
+	return p.save(true)
+}
+
+// save ... Save の本体 (verbose: falseなら何も表示しない, 並列にダウンロードする場合に使う)
+func (p *Problem) save(verbose bool) error {
+	migrateLegacyProblems()
+
+	p.ID = strings.ToUpper(p.ID)
+	key := p.Key()
+	path := keyToPath(key)
+
+	jsonBytes, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	// サンプルケースのフォルダがなければ作る
+	if !util.FileExists(filepath.Dir(path)) {
+		if err = os.MkdirAll(filepath.Dir(path), 0775); err != nil {
+			return err
+		}
+		if verbose {
+			fmt.Println(fmt.Sprintf(util.PrefixInfo+"Created a directory `%s`", filepath.Dir(path)))
+		}
+	}
+
+	var buf bytes.Buffer
+	json.Indent(&buf, jsonBytes, "", "  ")
+	err = util.WriteFileAtomic(path, buf.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	if err := p.saveStatement(); err != nil {
+		return err
+	}
+	// インデックスは一覧のためのものなので、更新できなくても (壊れている場合など) 問題の保存は失敗させない
+	if err := indexProblem(p, time.Now()); err != nil {
+		fmt.Fprintln(os.Stderr, util.PrefixCaution+err.Error())
+	}
+	if verbose {
+		fmt.Println(util.PrefixInfo + "Save problem : " + key)
+	}
+
+	return SetCurrentContest(p.ContestKey())
+}
+
+// LoadProblem ... id で指定された問題を読み込む
+// id は `atcoder/abc300/A` のようなキーの他に、`abc300/A` や `A` のような省略形も使える (ResolveProblemID を参照)
+func LoadProblem(id string) (*Problem, error) {
+	key, err := ResolveProblemID(id)
+	if err != nil {
+		return nil, err
+	}
+	path := keyToPath(key)
+
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, &ErrFailedToLoadSamplecase{message: "failed to open " + path}
+	}
+
+	var p Problem
+	err = json.Unmarshal(bytes, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	util.DebugPrint("Load problem : " + key)
+	return &p, nil
+}
+
+// ResolveProblemID ... 省略形を含む問題の id を、保存されている問題のキーに直す
+// キーと完全に一致しない場合は、`/` 区切りで末尾が一致するものを探す
+// 候補が複数ある場合は現在のコンテストのものを選び、それでも決まらない場合はエラーになる
+// (大文字小文字は区別しない)
+func ResolveProblemID(id string) (string, error) {
+	id = strings.ToLower(strings.Trim(filepath.ToSlash(id), "/"))
+	all := GetAllProblemID()
+
+	for _, key := range all {
+		if strings.ToLower(key) == id {
+			return key, nil
+		}
+	}
+
+	candidates := []string{}
+	for _, key := range all {
+		if strings.HasSuffix(strings.ToLower(key), "/"+id) {
+			candidates = append(candidates, key)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", &ErrFailedToLoadSamplecase{message: "problem `" + id + "` dosen't exist."}
+	case 1:
+		return candidates[0], nil
+	}
+
+	if current := CurrentContest(); current != "" {
+		inContest := []string{}
+		for _, key := range candidates {
+			if strings.HasPrefix(strings.ToLower(key), strings.ToLower(current)+"/") {
+				inContest = append(inContest, key)
+			}
+		}
+		if len(inContest) == 1 {
+			util.DebugPrint("resolved `" + id + "` in the current contest : " + inContest[0])
+			return inContest[0], nil
+		}
+	}
+	return "", &ErrAmbiguousProblemID{id: id, candidates: candidates}
+}
+
+// FindProblemByURL ... 問題のURLが problemURL である保存済みの問題のキーを返す
+func FindProblemByURL(problemURL string) (string, error) {
+	problemURL = strings.TrimSpace(problemURL)
+	for _, key := range GetAllProblemID() {
+		bytes, err := ioutil.ReadFile(keyToPath(key))
+		if err != nil {
+			continue
+		}
+		var p struct {
+			URL string `json:"url"`
+		}
+		if json.Unmarshal(bytes, &p) == nil && p.URL == problemURL {
+			return key, nil
+		}
+	}
+	return "", &ErrFailedToLoadSamplecase{message: "problem of `" + problemURL + "` dosen't exist."}
+}
+
+// GetAllProblemID ... 保存済みの問題のキーの一覧を返す
+func GetAllProblemID() []string {
+	migrateLegacyProblems()
+
+	root := samplecaseRoot()
+	ret := []string{}
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != SamplecaseExt {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || !strings.Contains(rel, string(filepath.Separator)) {
+			return nil // 直下のファイルは問題ではない
+		}
+		ret = append(ret, filepath.ToSlash(strings.TrimSuffix(rel, SamplecaseExt)))
+		return nil
+	})
+	sort.Strings(ret)
+	return ret
+}
+
+// CurrentContest ... 現在のコンテストのキー (最後にダウンロードした問題のコンテスト)
+func CurrentContest() string {
+	bytes, err := ioutil.ReadFile(filepath.Join(samplecaseRoot(), currentContestFilename))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(bytes))
+}
+
+// SetCurrentContest ... 現在のコンテストを contestKey にする
+func SetCurrentContest(contestKey string) error {
+	return util.WriteFileAtomic(filepath.Join(samplecaseRoot(), currentContestFilename), []byte(contestKey+"\n"), 0644)
+}
+
+var migrateOnce sync.Once
+
+// migrateLegacyProblems ... 以前の {SamplecaseDir}/problem_{ID}.json を新しい場所に移動する
+func migrateLegacyProblems() {
+	migrateOnce.Do(func() {
+		root := samplecaseRoot()
+		files, err := ioutil.ReadDir(root)
+		if err != nil {
+			return
+		}
+
+		pat := strings.Replace(legacySamplecaseFilename, `.`, `\.`, -1)
+		pat = strings.Replace(pat, `%s`, `(.*)`, -1)
+		re := regexp.MustCompile("^" + pat + "$")
+
+		for _, file := range files {
+			if file.IsDir() || !re.MatchString(file.Name()) {
+				continue
+			}
+			oldPath := filepath.Join(root, file.Name())
+			bytes, err := ioutil.ReadFile(oldPath)
+			if err != nil {
+				continue
+			}
+			var p Problem
+			if err := json.Unmarshal(bytes, &p); err != nil {
+				fmt.Fprintln(os.Stderr, util.PrefixCaution+fmt.Sprintf("Failed to migrate `%s` : %s", oldPath, err))
+				continue
+			}
+
+			newPath := keyToPath(p.Key())
+			if util.FileExists(newPath) {
+				fmt.Fprintln(os.Stderr, util.PrefixCaution+fmt.Sprintf("Skip migrating `%s` : `%s` already exists", oldPath, newPath))
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(newPath), 0775); err != nil {
+				continue
+			}
+			if err := os.Rename(oldPath, newPath); err != nil {
+				fmt.Fprintln(os.Stderr, util.PrefixCaution+fmt.Sprintf("Failed to migrate `%s` : %s", oldPath, err))
+				continue
+			}
+			fmt.Fprintln(os.Stderr, util.PrefixInfo+fmt.Sprintf("Migrated problem `%s` to `%s`", file.Name(), p.Key()))
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package online_judge
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestProblemStoreResolve(t *testing.T) {
+	fmt.Println("testing : problem_store.go > Problem.Save, ResolveProblemID")
+
+	defer os.RemoveAll(samplecaseRoot())
+
+	problems := []*Problem{
+		{ID: "a", ContestID: "abc300", Name: "A", Oj: AtCoder},
+		{ID: "a", ContestID: "837", Name: "A", Oj: Codeforces},
+		{ID: "b", ContestID: "837", Name: "B", Oj: Codeforces},
+		{ID: "1", Name: "1", Oj: Yukicoder},
+	}
+	for _, p := range problems {
+		if err := p.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := problems[0].Key(); got != "atcoder/abc300/A" {
+		t.Errorf("Key() returned %s", got)
+	}
+
+	testcase := map[string]string{
+		"atcoder/abc300/A": "atcoder/abc300/A",
+		"abc300/a":         "atcoder/abc300/A",
+		"b":                "codeforces/837/B",
+		"1":                "yukicoder/1",
+		"yukicoder/1":      "yukicoder/1",
+	}
+	for id, expect := range testcase {
+		key, err := ResolveProblemID(id)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if key != expect {
+			t.Errorf("ResolveProblemID(%s) returned %s", id, key)
+		}
+	}
+
+	// 最後に保存した問題のコンテストが優先される
+	SetCurrentContest("codeforces/837")
+	if key, err := ResolveProblemID("A"); err != nil || key != "codeforces/837/A" {
+		t.Errorf("ResolveProblemID(A) returned %s, %v", key, err)
+	}
+	SetCurrentContest("yukicoder")
+	if _, err := ResolveProblemID("A"); err == nil {
+		t.Error("曖昧な問題idでエラーになりません")
+	}
+	if _, err := ResolveProblemID("Z"); err == nil {
+		t.Error("存在しない問題idでエラーになりません")
+	}
+}
+
+func TestProblemStoreConcurrentSave(t *testing.T) {
+	fmt.Println("testing : problem_store.go > Problem.save (concurrent)")
+
+	defer os.RemoveAll(samplecaseRoot())
+
+	done := make(chan error)
+	for i := 0; i < 20; i++ {
+		go func(i int) {
+			p := &Problem{ID: string(rune('A' + i)), ContestID: "abc300", Name: "abc300", Oj: AtCoder, Statement: "<p>statement</p>"}
+			done <- p.save(false)
+		}(i)
+	}
+	for i := 0; i < 20; i++ {
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+	if ids := GetAllProblemID(); len(ids) != 20 {
+		t.Errorf("GetAllProblemID returned %d problems", len(ids))
+	}
+	if _, err := LoadStatement("abc300/T"); err != nil {
+		t.Error(err)
+	}
+	if got := CurrentContest(); got != "atcoder/abc300" {
+		t.Errorf("CurrentContest() = %s", got)
+	}
+}
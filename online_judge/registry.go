@@ -0,0 +1,153 @@
+package online_judge
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/algon-320/KIDE/util"
+)
+
+// Capability ... オンラインジャッジが対応している機能
+type Capability int
+
+const (
+	// CapabilityContestDownload ... コンテストの問題を一括でダウンロード出来る
+	CapabilityContestDownload Capability = 1 << iota
+	// CapabilityCustomTest ... コードテスト(カスタムテスト)がある
+	CapabilityCustomTest
+	// CapabilityAPI ... 公式のAPIがある
+	CapabilityAPI
+)
+
+// capabilityLabels ... 表示用の名前
+var capabilityLabels = []struct {
+	cap   Capability
+	label string
+}{
+	{CapabilityContestDownload, "contest download"},
+	{CapabilityCustomTest, "custom test"},
+	{CapabilityAPI, "api"},
+}
+
+// Registration ... オンラインジャッジの登録情報
+type Registration struct {
+	Judge        OnlineJudge
+	Key          string     // 問題の保存先のディレクトリ名などに使う短い名前 (FromName でも使える)
+	Aliases      []string   // FromName で使える別名 (大文字小文字は区別しない)
+	Hosts        []string   // 問題のURLのホスト名
+	Capabilities Capability // 対応している機能
+	MainClass    string     // 提出するソースコードのメインクラスに要求する名前 (要求しない場合は空)
+}
+
+// Name ... オンラインジャッジの名前 (問題のJSONにはこの名前で保存される)
+func (r *Registration) Name() string {
+	return r.Judge.Name()
+}
+
+// Has ... cap の機能に対応しているかどうか
+func (r *Registration) Has(cap Capability) bool {
+	return r.Capabilities&cap != 0
+}
+
+// CapabilityLabels ... 対応している機能の名前の一覧
+func (r *Registration) CapabilityLabels() []string {
+	ret := []string{}
+	for _, c := range capabilityLabels {
+		if r.Has(c.cap) {
+			ret = append(ret, c.label)
+		}
+	}
+	return ret
+}
+
+// matchName ... name がこのオンラインジャッジの名前か別名に一致するかどうか
+func (r *Registration) matchName(name string) bool {
+	if strings.EqualFold(name, r.Name()) || strings.EqualFold(name, r.Key) {
+		return true
+	}
+	for _, a := range r.Aliases {
+		if strings.EqualFold(name, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost ... URLのホスト名がこのオンラインジャッジのものかどうか
+func (r *Registration) matchHost(host string) bool {
+	for _, h := range r.Hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+var registry = []*Registration{}
+
+// Register ... オンラインジャッジを登録する (各オンラインジャッジの init で呼ぶ)
+// 同じ名前のオンラインジャッジが既に登録されている場合は panic する
+func Register(r *Registration) {
+	for _, v := range registry {
+		if v.matchName(r.Name()) {
+			panic(fmt.Errorf("online judge `%s` is already registered", r.Name()))
+		}
+	}
+	registry = append(registry, r)
+}
+
+// Registered ... 登録されているオンラインジャッジの一覧 (登録順)
+func Registered() []*Registration {
+	ret := make([]*Registration, len(registry))
+	copy(ret, registry)
+	return ret
+}
+
+// Lookup ... oj の登録情報を返す (登録されていない場合はnil)
+func Lookup(oj OnlineJudge) *Registration {
+	for _, r := range registry {
+		if r.Judge == oj {
+			return r
+		}
+	}
+	return nil
+}
+
+// FromName ... 名前からOnlineJudgeを返す (大文字小文字は区別しない, 別名も使える)
+func FromName(ojName string) (OnlineJudge, error) {
+	for _, r := range registry {
+		if r.matchName(ojName) {
+			return r.Judge, nil
+		}
+	}
+	return nil, &ErrNoSuchOnlineJudge{oj_name: ojName}
+}
+
+// FromProblemURL ... 問題(またはコンテスト)のURLからOnlineJudgeを返す
+func FromProblemURL(problemURL string) (OnlineJudge, error) {
+	urlObj, err := url.Parse(problemURL)
+	if err != nil {
+		return nil, &ErrInvalidProblemURL{url: problemURL}
+	}
+
+	for _, r := range registry {
+		if !r.matchHost(urlObj.Hostname()) {
+			continue
+		}
+		if p, _ := r.Judge.IsValidURL(problemURL); p {
+			util.DebugPrint(problemURL + " is a url of " + r.Name())
+			return r.Judge, nil
+		}
+		return nil, &ErrInvalidProblemURL{url: problemURL}
+	}
+	return nil, &ErrNoSuchOnlineJudge{oj_name: urlObj.Hostname()}
+}
+
+// MainClassName ... oj が提出するソースコードのメインクラスに要求する名前を返す (特に要求しない場合は空文字列)
+func MainClassName(oj OnlineJudge) string {
+	if r := Lookup(oj); r != nil {
+		return r.MainClass
+	}
+	return ""
+}
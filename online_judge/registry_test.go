@@ -0,0 +1,93 @@
+package online_judge
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestRegistryFromName(t *testing.T) {
+	fmt.Println("testing : registry.go > FromName")
+
+	testcase := map[string]OnlineJudge{
+		"AtCoder":           AtCoder,
+		"atcoder":           AtCoder,
+		"Codeforces":        Codeforces,
+		"yukicoder":         Yukicoder,
+		"Aizu Online Judge": AOJ,
+		"aoj":               AOJ,
+	}
+	for name, expect := range testcase {
+		oj, err := FromName(name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if oj != expect {
+			t.Errorf("FromName(%s) returned %s", name, oj.Name())
+		}
+	}
+
+	if _, err := FromName("no such judge"); err == nil {
+		t.Error("存在しないオンラインジャッジでエラーになりません")
+	}
+}
+
+func TestRegistryFromProblemURL(t *testing.T) {
+	fmt.Println("testing : registry.go > FromProblemURL")
+
+	testcase := map[string]OnlineJudge{
+		"https://atcoder.jp/contests/abc070/tasks/abc070_a":                         AtCoder,
+		"https://codeforces.com/contest/839/problem/A":                              Codeforces,
+		"https://yukicoder.me/problems/no/273":                                      Yukicoder,
+		"http://judge.u-aizu.ac.jp/onlinejudge/description.jsp?id=ITP1_1_A&lang=jp": AOJ,
+	}
+	for url, expect := range testcase {
+		oj, err := FromProblemURL(url)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if oj != expect {
+			t.Errorf("FromProblemURL(%s) returned %s", url, oj.Name())
+		}
+	}
+
+	if _, err := FromProblemURL("https://atcoder.jp/post/37"); err == nil {
+		t.Error("問題ではないURLでエラーになりません")
+	}
+	if _, err := FromProblemURL("https://example.com/problems/1"); err == nil {
+		t.Error("対応していないオンラインジャッジのURLでエラーになりません")
+	}
+}
+
+func TestRegistryProblemJSON(t *testing.T) {
+	fmt.Println("testing : registry.go > Problem.MarshalJSON, Problem.UnmarshalJSON")
+
+	p := &Problem{
+		ID:        "ITP1_1_A",
+		ContestID: "",
+		Name:      "ITP1_1_A",
+		URL:       "http://judge.u-aizu.ac.jp/onlinejudge/description.jsp?id=ITP1_1_A",
+		Oj:        AOJ,
+		Cases:     []TestCase{{Input: "", Output: "Hello World\n"}},
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var raw map[string]interface{}
+	json.Unmarshal(data, &raw)
+	if raw["oj"] != AOJ.Name() {
+		t.Errorf("ojが名前で保存されていません : %v", raw["oj"])
+	}
+
+	var q Problem
+	if err := json.Unmarshal(data, &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Oj != AOJ || q.ID != p.ID || len(q.Cases) != 1 {
+		t.Error("JSONから問題を復元出来ていません")
+	}
+}
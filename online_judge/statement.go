@@ -0,0 +1,321 @@
+package online_judge
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/algon-320/KIDE/util"
+	"golang.org/x/crypto/ssh/terminal"
+	"golang.org/x/net/html"
+)
+
+// StatementExt ... 問題文のHTMLの拡張子 (問題のJSONと同じ場所に保存される)
+const StatementExt = ".html"
+
+// keyToStatementPath ... 問題のキーから問題文の保存先のパスを返す
+func keyToStatementPath(key string) string {
+	return strings.TrimSuffix(keyToPath(key), SamplecaseExt) + StatementExt
+}
+
+// statementHTML ... 問題ページの sel の部分を問題文として保存するHTMLにする
+// (Shift_JIS のページは UTF-8 に直す)
+func statementHTML(sel *goquery.Selection) string {
+	if sel.Length() == 0 {
+		return ""
+	}
+	ret, err := goquery.OuterHtml(sel.First())
+	if err != nil {
+		return ""
+	}
+	if !utf8.ValidString(ret) {
+		if utf, err := util.ShiftJIS2UTF8(ret); err == nil {
+			ret = utf
+		}
+	}
+	return ret
+}
+
+// saveStatement ... 問題文のHTMLを保存する (問題文が無い場合は何もしない)
+func (p *Problem) saveStatement() error {
+	if p.Statement == "" {
+		return nil
+	}
+	return util.WriteFileAtomic(keyToStatementPath(p.Key()), []byte(p.Statement), 0644)
+}
+
+// LoadStatement ... id で指定された問題の問題文のHTMLを読み込む
+func LoadStatement(id string) (*Problem, error) {
+	p, err := LoadProblem(id)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := ioutil.ReadFile(keyToStatementPath(p.Key()))
+	if os.IsNotExist(err) {
+		return nil, &ErrNoStatement{key: p.Key()}
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.Statement = string(bytes)
+	return p, nil
+}
+
+// PrintStatement ... 問題文を端末の幅に合わせて出力する (lang は RenderStatement を参照)
+func (p *Problem) PrintStatement(lang string) error {
+	fd := int(os.Stdout.Fd())
+	width, _, err := terminal.GetSize(fd)
+	if err != nil {
+		width = 80
+	}
+
+	text, err := RenderStatement(p.Statement, lang, width)
+	if err != nil {
+		return err
+	}
+	util.PrintTitle(width, 4, "=", p.Key()+" "+p.Name)
+	for _, m := range p.Metadata() {
+		fmt.Printf("%s: %s\n", m[0], m[1])
+	}
+	fmt.Println()
+	fmt.Print(text)
+	return nil
+}
+
+// RenderStatement ... 問題文のHTMLを端末に表示するための文字列にする
+// lang: `ja` か `en` (AtCoder のように両方の言語がある問題文の場合にどちらを表示するか), width: 折り返す幅
+func RenderStatement(statement string, lang string, width int) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(statement))
+	if err != nil {
+		return "", err
+	}
+	root := doc.Find("body")
+	if sel := root.Find("span.lang-" + lang); lang != "" && sel.Length() > 0 {
+		root = sel.First()
+	}
+
+	r := &statementRenderer{width: width}
+	for _, n := range root.Nodes {
+		r.children(n)
+	}
+	r.flush()
+	return strings.Trim(r.out.String(), "\n") + "\n", nil
+}
+
+// statementRenderer ... HTMLのノードを辿って、ブロックごとに折り返しながら書き出す
+type statementRenderer struct {
+	out    strings.Builder
+	line   strings.Builder // 書き出していないブロックの中身
+	indent string          // リストの中ではインデントする
+	width  int
+}
+
+// flush ... 溜めている文章を折り返して書き出す
+func (r *statementRenderer) flush() {
+	text := strings.Join(strings.Fields(r.line.String()), " ")
+	r.line.Reset()
+	if text == "" {
+		return
+	}
+	for _, l := range wrapText(text, r.width-displayWidth(r.indent)) {
+		r.out.WriteString(r.indent + l + "\n")
+	}
+}
+
+// block ... ブロックの前後を空ける
+func (r *statementRenderer) block() {
+	r.flush()
+	if s := r.out.String(); s != "" && !strings.HasSuffix(s, "\n\n") {
+		r.out.WriteString("\n")
+	}
+}
+
+func (r *statementRenderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.node(c)
+	}
+}
+
+func (r *statementRenderer) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.line.WriteString(replaceTeXMath(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		r.children(n)
+		return
+	}
+
+	switch n.Data {
+	case "script", "style", "button", "form":
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.block()
+		r.flush()
+		title := strings.Join(strings.Fields(replaceTeXMath(nodeText(n))), " ")
+		r.out.WriteString(r.indent + util.ESCS_BOLD + title + util.ESCS_COL_OFF + "\n")
+		r.out.WriteString(r.indent + strings.Repeat("─", displayWidth(title)) + "\n")
+	case "p", "div", "section", "blockquote":
+		r.block()
+		r.children(n)
+		r.block()
+	case "br":
+		r.flush()
+	case "hr":
+		r.block()
+		r.out.WriteString(strings.Repeat("─", r.width) + "\n\n")
+	case "ul", "ol":
+		r.block()
+		prev := r.indent
+		num := 0
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || c.Data != "li" {
+				continue
+			}
+			num++
+			mark := "• "
+			if n.Data == "ol" {
+				mark = strconv.Itoa(num) + ". "
+			}
+			r.indent = prev + "  "
+			r.line.WriteString(mark)
+			r.children(c)
+			r.flush()
+		}
+		r.indent = prev
+		r.block()
+	case "pre":
+		r.block()
+		text := strings.Trim(nodeText(n), "\n")
+		for _, l := range strings.Split(text, "\n") {
+			r.out.WriteString(r.indent + "    " + l + "\n")
+		}
+		r.block()
+	case "var":
+		// AtCoder は数式を <var> で囲む
+		r.line.WriteString(texToUnicode(nodeText(n)))
+	case "sup", "sub":
+		r.line.WriteString(script(strings.TrimSpace(nodeText(n)), n.Data == "sup"))
+	case "b", "strong":
+		r.line.WriteString(util.ESCS_BOLD + strings.TrimSpace(nodeText(n)) + util.ESCS_COL_OFF)
+	case "img":
+		alt := ""
+		for _, a := range n.Attr {
+			if a.Key == "alt" {
+				alt = a.Val
+			}
+		}
+		r.line.WriteString(" [image" + strings.TrimRight(": "+alt, ": ") + "] ")
+	case "tr":
+		r.flush()
+		cells := []string{}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+				cells = append(cells, strings.Join(strings.Fields(replaceTeXMath(nodeText(c))), " "))
+			}
+		}
+		r.out.WriteString(r.indent + "| " + strings.Join(cells, " | ") + " |\n")
+	case "table":
+		r.block()
+		r.children(n)
+		r.block()
+	default:
+		r.children(n)
+	}
+}
+
+// nodeText ... n 以下のテキスト (<var> の中の数式はそのまま)
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "br" {
+			sb.WriteString("\n")
+			continue
+		}
+		if c.Type == html.ElementNode && c.Data == "var" {
+			sb.WriteString(texToUnicode(nodeText(c)))
+			continue
+		}
+		sb.WriteString(nodeText(c))
+	}
+	return sb.String()
+}
+
+// runeWidth ... 端末での文字の幅 (全角文字は2)
+func runeWidth(r rune) int {
+	if r < 0x1100 {
+		return 1
+	}
+	if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(0x3000 <= r && r <= 0x303f) || (0xff01 <= r && r <= 0xff60) || (0xffe0 <= r && r <= 0xffe6) {
+		return 2
+	}
+	return 1
+}
+
+// displayWidth ... 端末での文字列の幅 (エスケープシーケンスは数えない)
+func displayWidth(s string) int {
+	w := 0
+	escape := false
+	for _, r := range s {
+		switch {
+		case escape:
+			escape = r != 'm'
+		case r == '\033':
+			escape = true
+		default:
+			w += runeWidth(r)
+		}
+	}
+	return w
+}
+
+// wrapText ... text を幅 width で折り返す (英単語の途中では折り返さず、日本語はどこでも折り返す)
+func wrapText(text string, width int) []string {
+	if width < 10 {
+		width = 10
+	}
+	ret := []string{}
+	line := []rune{}
+	w := 0
+	lastSpace := -1
+	escape := false
+	for _, c := range text {
+		line = append(line, c)
+		switch {
+		case escape:
+			escape = c != 'm'
+			continue
+		case c == '\033':
+			escape = true
+			continue
+		case c == ' ':
+			lastSpace = len(line) - 1
+		}
+		w += runeWidth(c)
+		if w <= width {
+			continue
+		}
+
+		cut := len(line) - 1
+		if c != ' ' && runeWidth(c) == 1 && lastSpace > 0 {
+			cut = lastSpace
+		}
+		ret = append(ret, strings.TrimRight(string(line[:cut]), " "))
+		line = []rune(strings.TrimLeft(string(line[cut:]), " "))
+		w = displayWidth(string(line))
+		lastSpace = -1
+	}
+	if len(line) > 0 {
+		ret = append(ret, string(line))
+	}
+	return ret
+}
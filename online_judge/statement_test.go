@@ -0,0 +1,161 @@
+package online_judge
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/algon-320/KIDE/util"
+)
+
+func TestTeXToUnicode(t *testing.T) {
+	fmt.Println("testing : tex.go > texToUnicode, replaceTeXMath")
+
+	testcase := map[string]string{
+		`N \leq 10^5`:                 "N ≤ 10⁵",
+		`1 \le A_i \le 10^{9}`:        "1 ≤ Aᵢ ≤ 10⁹",
+		`2 \times 10^{18}`:            "2 × 10¹⁸",
+		`\frac{a+b}{2}`:               "(a+b)/2",
+		`\sqrt{N}`:                    "√N",
+		`10^{-6}`:                     "10⁻⁶",
+		`A_{i,j}`:                     "A_(i,j)",
+		`x^{\prime}`:                  "x′",
+		`\mathrm{mod}\ 998244353`:     "mod 998244353",
+		`\binom{n}{k} \bmod p`:        "C(n, k)  mod  p",
+		`a \neq b, \alpha \in \{1\}`:  "a ≠ b, α ∈ {1}",
+		`S_1 S_2 \ldots S_N`:          "S₁ S₂ … S_N",
+		`\lfloor \frac{N}{2} \rfloor`: "⌊ N/2 ⌋",
+	}
+	for tex, expect := range testcase {
+		if got := texToUnicode(tex); got != expect {
+			t.Errorf("texToUnicode(%q) = %q, expected %q", tex, got, expect)
+		}
+	}
+
+	text := `$1 \leq N \leq 10^5$ を満たす整数 $$$N$$$ と \(A_1\) が与えられる。$10 ドル`
+	expect := `1 ≤ N ≤ 10⁵ を満たす整数 N と A₁ が与えられる。$10 ドル`
+	if got := replaceTeXMath(text); got != expect {
+		t.Errorf("replaceTeXMath(%q) = %q, expected %q", text, got, expect)
+	}
+
+	// 数式ではない `$` はそのまま
+	for _, text := range []string{"It costs $5 and you get $3 back.", "$ 1 + 2 $", "a$$b"} {
+		if got := replaceTeXMath(text); got != text {
+			t.Errorf("replaceTeXMath(%q) = %q", text, got)
+		}
+	}
+	if got := replaceTeXMath("$x$ and $y_1$"); got != "x and y₁" {
+		t.Errorf("replaceTeXMath returned %q", got)
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	fmt.Println("testing : statement.go > wrapText, displayWidth")
+
+	if w := displayWidth("あいうabc" + util.ESCS_BOLD + "x" + util.ESCS_COL_OFF); w != 10 {
+		t.Errorf("displayWidth returned %d", w)
+	}
+	got := wrapText("the quick brown fox jumps over the lazy dog", 15)
+	expect := []string{"the quick brown", "fox jumps over", "the lazy dog"}
+	if strings.Join(got, "|") != strings.Join(expect, "|") {
+		t.Errorf("wrapText returned %q", got)
+	}
+	got = wrapText("あいうえおかきくけこさしすせそ", 10)
+	expect = []string{"あいうえお", "かきくけこ", "さしすせそ"}
+	if strings.Join(got, "|") != strings.Join(expect, "|") {
+		t.Errorf("wrapText returned %q", got)
+	}
+}
+
+func TestRenderStatement(t *testing.T) {
+	fmt.Println("testing : statement.go > RenderStatement")
+
+	statement := `<div id="task-statement"><span class="lang">
+<span class="lang-ja"><h3>問題文</h3><p>整数 <var>N</var> (<var>1 \leq N \leq 10^5</var>) が与えられます。</p>
+<ul><li>条件 1</li><li><var>A_i</var> は整数</li></ul>
+<pre><var>N</var>
+<var>A_1</var> <var>\ldots</var> <var>A_N</var>
+</pre></span>
+<span class="lang-en"><h3>Problem Statement</h3><p>Given an integer <var>N</var>.</p></span>
+</span></div>`
+
+	ja, err := RenderStatement(statement, "ja", 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		util.ESCS_BOLD + "問題文" + util.ESCS_COL_OFF + "\n──────\n",
+		"整数 N (1 ≤ N ≤ 10⁵) が与えられます。\n",
+		"  • 条件 1\n  • Aᵢ は整数\n",
+		"    N\n    A₁ … A_N\n",
+	} {
+		if !strings.Contains(ja, expect) {
+			t.Errorf("rendered statement does not contain %q :\n%s", expect, ja)
+		}
+	}
+	if strings.Contains(ja, "Problem Statement") {
+		t.Errorf("rendered statement contains English part :\n%s", ja)
+	}
+
+	en, err := RenderStatement(statement, "en", 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(en, "Given an integer N.") || strings.Contains(en, "問題文") {
+		t.Errorf("rendered English statement is wrong :\n%s", en)
+	}
+
+	// 言語が分かれていない問題文はそのまま表示する
+	cf := `<div class="problem-statement"><p>Print $$$a+b$$$.</p><ol><li>one</li><li>two</li></ol></div>`
+	got, err := RenderStatement(cf, "en", 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Print a+b.\n\n  1. one\n  2. two\n" {
+		t.Errorf("RenderStatement returned %q", got)
+	}
+}
+
+func TestLoadStatement(t *testing.T) {
+	fmt.Println("testing : statement.go > Problem.Save, LoadStatement")
+	defer serveFixtures(t, atcoderRoutes(t))()
+	defer os.RemoveAll(samplecaseRoot())
+
+	p, err := AtCoder.fetchProblem(newBrowser(), "https://atcoder.jp/contests/practice/tasks/practice_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(p.Statement, `id="task-statement"`) {
+		t.Fatalf("問題文が取得されていません : %q", p.Statement)
+	}
+	if err := p.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadStatement("practice/A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Statement != p.Statement {
+		t.Error("保存した問題文と読み込んだ問題文が違います")
+	}
+	rendered, err := RenderStatement(loaded.Statement, "ja", 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rendered, "整数 a, b, c と、文字列 s が与えられます。") {
+		t.Errorf("rendered statement :\n%s", rendered)
+	}
+
+	// 問題文の無い問題
+	old := &Problem{ID: "B", ContestID: "practice", Name: "B", Oj: AtCoder}
+	if err := old.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadStatement("practice/B"); err == nil {
+		t.Error("問題文の無い問題でエラーになりません")
+	} else if _, ok := err.(*ErrNoStatement); !ok {
+		t.Errorf("unexpected error : %v", err)
+	}
+}
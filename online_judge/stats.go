@@ -0,0 +1,234 @@
+package online_judge
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/algon-320/KIDE/util"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// StatsCount ... 1つの項目 (オンラインジャッジ、言語、結果、月) の提出数
+type StatsCount struct {
+	Key      string `json:"key"`
+	Count    int    `json:"submissions"`
+	Accepted int    `json:"accepted"` // そのうち AC (PP を含む) の数
+}
+
+// Stats ... 提出の履歴の集計
+type Stats struct {
+	Submissions int `json:"submissions"`
+	Problems    int `json:"problems"` // 提出したことのある問題の数
+	Solved      int `json:"solved"`   // AC (PP を含む) したことのある問題の数
+
+	ByJudge    []StatsCount `json:"by_judge"`    // 提出数の多い順
+	ByLanguage []StatsCount `json:"by_language"` // 提出数の多い順
+	ByVerdict  []StatsCount `json:"by_verdict"`  // 提出数の多い順
+	ByMonth    []StatsCount `json:"by_month"`    // 古い順 (`2006-01` の形式)
+	Failures   []StatsCount `json:"failures"`    // 失敗した結果 (AC, PP, 不明な結果を除く、多い順)
+
+	FirstTryAccepted int     `json:"first_try_accepted"` // 最初の提出で AC (PP を含む) した問題の数
+	FirstTryRate     float64 `json:"first_try_rate"`     // FirstTryAccepted / Problems
+
+	// ダウンロードしてから最初に AC するまでの平均 (ダウンロード日時が分かる問題のみ)
+	DlToAC        time.Duration `json:"-"`
+	DlToACHours   float64       `json:"dl_to_ac_hours"`
+	DlToACSamples int           `json:"dl_to_ac_samples"` // 平均を取った問題の数
+}
+
+// statsCounter ... キーごとの提出数を数える
+type statsCounter map[string]*StatsCount
+
+func (c statsCounter) add(key string, accepted bool) {
+	if key == "" {
+		key = "-"
+	}
+	sc, ok := c[key]
+	if !ok {
+		sc = &StatsCount{Key: key}
+		c[key] = sc
+	}
+	sc.Count++
+	if accepted {
+		sc.Accepted++
+	}
+}
+
+// sorted ... 提出数の多い順 (byKey なら キーの順) に並べる
+func (c statsCounter) sorted(byKey bool) []StatsCount {
+	ret := make([]StatsCount, 0, len(c))
+	for _, sc := range c {
+		ret = append(ret, *sc)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if !byKey && ret[i].Count != ret[j].Count {
+			return ret[i].Count > ret[j].Count
+		}
+		return ret[i].Key < ret[j].Key
+	})
+	return ret
+}
+
+// accepted ... 提出が通ったか (Codeforces のコンテスト中の PP も通ったものとして数える)
+func accepted(st JudgeStatus) bool {
+	return st == JudgeStatusAC || st == JudgeStatusPP
+}
+
+// ComputeStats ... 提出の履歴 history (古い順) と問題のインデックス index から集計する
+func ComputeStats(history []*HistoryEntry, index []*IndexEntry) *Stats {
+	s := &Stats{Submissions: len(history)}
+	byJudge, byLanguage, byVerdict, byMonth, failures := statsCounter{}, statsCounter{}, statsCounter{}, statsCounter{}, statsCounter{}
+
+	firstSubmission := map[string]*HistoryEntry{} // 問題のキー -> 最初の提出
+	firstAC := map[string]time.Time{}             // 問題のキー -> 最初に AC した日時
+	for _, e := range history {
+		st := e.Status()
+		ac := accepted(st)
+		byJudge.add(e.Judge, ac)
+		byLanguage.add(e.Language, ac)
+		byVerdict.add(e.Verdict, ac)
+		byMonth.add(e.SubmittedAt.Local().Format("2006-01"), ac)
+		if !ac && st != JudgeStatusUNK {
+			failures.add(e.Verdict, false)
+		}
+
+		if _, ok := firstSubmission[e.ProblemKey]; !ok {
+			firstSubmission[e.ProblemKey] = e
+		}
+		if t, ok := firstAC[e.ProblemKey]; ac && (!ok || e.SubmittedAt.Before(t)) {
+			firstAC[e.ProblemKey] = e.SubmittedAt
+		}
+	}
+	s.ByJudge = byJudge.sorted(false)
+	s.ByLanguage = byLanguage.sorted(false)
+	s.ByVerdict = byVerdict.sorted(false)
+	s.ByMonth = byMonth.sorted(true)
+	s.Failures = failures.sorted(false)
+
+	s.Problems = len(firstSubmission)
+	s.Solved = len(firstAC)
+	for _, e := range firstSubmission {
+		if accepted(e.Status()) {
+			s.FirstTryAccepted++
+		}
+	}
+	if s.Problems > 0 {
+		s.FirstTryRate = float64(s.FirstTryAccepted) / float64(s.Problems)
+	}
+
+	var total time.Duration
+	for _, e := range index {
+		ac, ok := firstAC[e.Key]
+		if !ok {
+			ac = e.SolvedAt // 履歴を記録する前に AC した問題
+		}
+		if ac.IsZero() || e.DownloadedAt.IsZero() || ac.Before(e.DownloadedAt) {
+			continue
+		}
+		total += ac.Sub(e.DownloadedAt)
+		s.DlToACSamples++
+	}
+	if s.DlToACSamples > 0 {
+		s.DlToAC = total / time.Duration(s.DlToACSamples)
+		s.DlToACHours = s.DlToAC.Hours()
+	}
+	return s
+}
+
+// LoadStats ... 保存している提出の履歴と問題のインデックスから集計する
+func LoadStats() (*Stats, error) {
+	history, err := SubmissionHistory()
+	if err != nil {
+		return nil, err
+	}
+	index, err := ProblemIndex()
+	if err != nil {
+		return nil, err
+	}
+	return ComputeStats(history, index), nil
+}
+
+// percent ... a / b を `50.0%` の形式で (b が 0 なら `-`)
+func percent(a, b int) string {
+	if b == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(a)/float64(b))
+}
+
+// bar ... 最大値 max に対する count の割合を長さ width の棒で表す (AC の部分は `#`, それ以外は `-`)
+// (PrintTable はバイト数で幅を揃えるので ASCII だけを使う)
+func bar(count, accepted, max, width int) string {
+	if max == 0 {
+		return ""
+	}
+	n := (count*width + max - 1) / max
+	a := accepted * width / max
+	return strings.Repeat("#", a) + strings.Repeat("-", n-a)
+}
+
+// printCounts ... 項目ごとの提出数を表と棒グラフで出力する
+// showAC: AC の数と割合を表示する (false なら全体に対する割合を表示する)
+func printCounts(width int, title string, counts []StatsCount, showAC bool) {
+	util.PrintTitle(width, 4, "=", title)
+	max, total := 0, 0
+	for _, sc := range counts {
+		if max < sc.Count {
+			max = sc.Count
+		}
+		total += sc.Count
+	}
+	barWidth := width / 3
+	if barWidth > 40 {
+		barWidth = 40
+	}
+	data := [][]string{}
+	for _, sc := range counts {
+		if showAC {
+			data = append(data, []string{sc.Key, fmt.Sprint(sc.Count), fmt.Sprint(sc.Accepted), percent(sc.Accepted, sc.Count), bar(sc.Count, sc.Accepted, max, barWidth)})
+		} else {
+			data = append(data, []string{sc.Key, fmt.Sprint(sc.Count), percent(sc.Count, total), bar(sc.Count, sc.Count, max, barWidth)})
+		}
+	}
+	if showAC {
+		util.PrintTable([]string{strings.ToLower(title), "submissions", "AC", "AC rate", ""}, data, true)
+	} else {
+		util.PrintTable([]string{strings.ToLower(title), "submissions", "share", ""}, data, true)
+	}
+}
+
+// Print ... 集計結果を表と棒グラフで出力する
+func (s *Stats) Print() {
+	fd := int(os.Stdout.Fd())
+	width, _, err := terminal.GetSize(fd)
+	if err != nil {
+		width = 80
+	}
+
+	util.PrintTitle(width, 4, "#", "Stats")
+	dlToAC := "-"
+	if s.DlToACSamples > 0 {
+		dlToAC = fmt.Sprintf("%s (%d problems)", formatDuration(s.DlToAC), s.DlToACSamples)
+	}
+	util.PrintTable([]string{"item", "value"}, [][]string{
+		{"submissions", fmt.Sprint(s.Submissions)},
+		{"problems", fmt.Sprint(s.Problems)},
+		{"solved", fmt.Sprintf("%d (%s)", s.Solved, percent(s.Solved, s.Problems))},
+		{"first-try AC", fmt.Sprintf("%d (%s)", s.FirstTryAccepted, percent(s.FirstTryAccepted, s.Problems))},
+		{"dl to AC (avg)", dlToAC},
+	}, false)
+	if s.Submissions == 0 {
+		return
+	}
+
+	printCounts(width, "Judge", s.ByJudge, true)
+	printCounts(width, "Language", s.ByLanguage, true)
+	printCounts(width, "Verdict", s.ByVerdict, false)
+	printCounts(width, "Month", s.ByMonth, true)
+	if len(s.Failures) > 0 {
+		printCounts(width, "Failures", s.Failures, false)
+	}
+}
@@ -0,0 +1,63 @@
+package online_judge
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestComputeStats(t *testing.T) {
+	fmt.Println("testing : stats.go > ComputeStats")
+
+	dl := time.Date(2019, 3, 1, 21, 0, 0, 0, time.Local)
+	sub := func(key, judge, lang, verdict string, at time.Time) *HistoryEntry {
+		return &HistoryEntry{ProblemKey: key, Judge: judge, Language: lang, Verdict: verdict, SubmittedAt: at}
+	}
+	history := []*HistoryEntry{
+		sub("atcoder/abc300/A", "AtCoder", "C++", "AC", dl.Add(10*time.Minute)),
+		sub("atcoder/abc300/B", "AtCoder", "C++", "WA", dl.Add(20*time.Minute)),
+		sub("atcoder/abc300/B", "AtCoder", "C++", "TLE", dl.Add(30*time.Minute)),
+		sub("atcoder/abc300/B", "AtCoder", "Python3", "AC", dl.Add(50*time.Minute)),
+		sub("yukicoder/1", "yukicoder", "C++", "WA", dl.AddDate(0, 1, 0)),
+		sub("atcoder/abc300/A", "AtCoder", "C++", "AC", dl.AddDate(0, 1, 0)),
+		sub("codeforces/1000/A", "Codeforces", "C++", "PP", dl.AddDate(0, 1, 0)), // PP は最初の提出での AC として数える
+		sub("codeforces/1000/B", "Codeforces", "C++", "UNK", dl.AddDate(0, 1, 0)),
+	}
+	index := []*IndexEntry{
+		{Key: "atcoder/abc300/A", DownloadedAt: dl},
+		{Key: "atcoder/abc300/B", DownloadedAt: dl},
+		{Key: "atcoder/abc300/C", DownloadedAt: dl, SolvedAt: dl.Add(90 * time.Minute)}, // 履歴より前の AC
+		{Key: "yukicoder/1", DownloadedAt: dl},
+	}
+
+	s := ComputeStats(history, index)
+	if s.Submissions != 8 || s.Problems != 5 || s.Solved != 3 || s.FirstTryAccepted != 2 {
+		t.Errorf("unexpected counts : %+v", s)
+	}
+	if s.DlToACSamples != 3 || s.DlToAC != 50*time.Minute || s.DlToACHours != s.DlToAC.Hours() {
+		t.Errorf("dl to AC : %v (%d problems)", s.DlToAC, s.DlToACSamples)
+	}
+
+	testcase := []struct {
+		got    []StatsCount
+		expect []StatsCount
+	}{
+		{s.ByJudge, []StatsCount{{"AtCoder", 5, 3}, {"Codeforces", 2, 1}, {"yukicoder", 1, 0}}},
+		{s.ByLanguage, []StatsCount{{"C++", 7, 3}, {"Python3", 1, 1}}},
+		{s.ByVerdict, []StatsCount{{"AC", 3, 3}, {"WA", 2, 0}, {"PP", 1, 1}, {"TLE", 1, 0}, {"UNK", 1, 0}}},
+		{s.ByMonth, []StatsCount{{"2019-03", 4, 2}, {"2019-04", 4, 2}}},
+		{s.Failures, []StatsCount{{"WA", 2, 0}, {"TLE", 1, 0}}},
+	}
+	for _, tc := range testcase {
+		if !reflect.DeepEqual(tc.got, tc.expect) {
+			t.Errorf("got %+v, expected %+v", tc.got, tc.expect)
+		}
+	}
+
+	// 履歴が無い場合
+	empty := ComputeStats([]*HistoryEntry{}, nil)
+	if empty.Submissions != 0 || empty.FirstTryRate != 0 || empty.ByJudge == nil {
+		t.Errorf("unexpected stats of empty history : %+v", empty)
+	}
+}
@@ -3,6 +3,8 @@ package online_judge
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/algon-320/KIDE/language"
@@ -57,6 +59,26 @@ func (js JudgeStatus) ToString() string {
 	return label
 }
 
+// Abbrev ... ジャッジ結果の略称 (`AC`、`WA` など)
+func (js JudgeStatus) Abbrev() string {
+	abbrevMap := map[JudgeStatus]string{
+		JudgeStatusAC:  "AC",
+		JudgeStatusPP:  "PP",
+		JudgeStatusWA:  "WA",
+		JudgeStatusCE:  "CE",
+		JudgeStatusRE:  "RE",
+		JudgeStatusTLE: "TLE",
+		JudgeStatusMLE: "MLE",
+		JudgeStatusOLE: "OLE",
+		JudgeStatusIE:  "IE",
+	}
+	abbrev, ok := abbrevMap[js]
+	if !ok {
+		return "UNK"
+	}
+	return abbrev
+}
+
 // GetColorESCS ... ジャッジ結果に対応する色のエスケープシーケンスを返す
 func (js JudgeStatus) GetColorESCS() string {
 	colorMap := map[JudgeStatus]string{
@@ -93,6 +115,22 @@ type JudgeResult struct {
 	Date     time.Time
 	URL      string
 	Status   JudgeStatus
+
+	// 以下は取得できなかった場合は空
+	Time           string // 実行時間 (表示用, テストケースの最大)
+	Memory         string // メモリ (表示用, テストケースの最大)
+	Score          string
+	CompileMessage string
+	Cases          []CaseResult // テストケースごとの結果
+}
+
+// CaseResult ... テストケースごとのジャッジ結果
+type CaseResult struct {
+	Name    string      `json:"name"`
+	Status  JudgeStatus `json:"status"`
+	Verdict string      `json:"verdict"`          // 表示されている判定の文字列
+	Time    string      `json:"time,omitempty"`   // 実行時間 (表示用)
+	Memory  string      `json:"memory,omitempty"` // メモリ (表示用)
 }
 
 // Print ... ジャッジの詳細を出力する TODO: 文字列で返すようにするべき(またはString()を実装する)
@@ -106,8 +144,13 @@ func (res *JudgeResult) Print() {
 	util.PrintTitle(width, 4, "#", "JudgeResult")
 	// util.PrintTitle(30, 4, "=", "Problem")
 	// res.Problem.Print()
+	res.print(width, fmt.Sprint(res.Language))
+}
+
+// print ... タイトル以外のジャッジの詳細を出力する (langName: 表示する言語名)
+func (res *JudgeResult) print(width int, langName string) {
 	util.PrintTitle(width, 4, "=", "Language")
-	fmt.Println(res.Language)
+	fmt.Println(langName)
 	util.PrintTitle(width, 4, "=", "SourceCode")
 	fmt.Println(res.Code)
 	util.PrintTitle(width, 4, "=", "Date")
@@ -116,4 +159,51 @@ func (res *JudgeResult) Print() {
 	fmt.Println(res.URL)
 	util.PrintTitle(width, 4, "=", "Status")
 	fmt.Println(res.Status)
+
+	details := []string{}
+	for _, d := range [][2]string{{"score", res.Score}, {"time", res.Time}, {"memory", res.Memory}} {
+		if d[1] != "" {
+			details = append(details, d[0]+": "+d[1])
+		}
+	}
+	if len(details) > 0 {
+		fmt.Println(strings.Join(details, ", "))
+	}
+
+	if res.CompileMessage != "" {
+		util.PrintTitle(width, 4, "=", "CompileMessage")
+		fmt.Println(res.CompileMessage)
+	}
+
+	if len(res.Cases) > 0 {
+		util.PrintTitle(width, 4, "=", "Cases")
+		fmt.Println(res.caseSummary())
+		title := []string{"case", "time", "memory", "verdict"}
+		data := [][]string{}
+		for _, c := range res.Cases {
+			data = append(data, []string{c.Name, c.Time, c.Memory, c.Status.GetColorESCS() + c.Verdict + util.ESCS_COL_OFF})
+		}
+		util.PrintTable(title, data, true)
+	}
+}
+
+// caseSummary ... テストケースの判定ごとの数 (`AC x 10, TLE x 2` のような形式, 多い順)
+func (res *JudgeResult) caseSummary() string {
+	count := map[string]int{}
+	labels := []string{}
+	color := map[string]string{}
+	for _, c := range res.Cases {
+		if _, ok := count[c.Verdict]; !ok {
+			labels = append(labels, c.Verdict)
+			color[c.Verdict] = c.Status.GetColorESCS()
+		}
+		count[c.Verdict]++
+	}
+	sort.SliceStable(labels, func(i, j int) bool { return count[labels[i]] > count[labels[j]] })
+
+	ret := []string{}
+	for _, l := range labels {
+		ret = append(ret, fmt.Sprintf("%s%s%s x %d", color[l], l, util.ESCS_COL_OFF, count[l]))
+	}
+	return strings.Join(ret, ", ")
 }
@@ -0,0 +1,386 @@
+package online_judge
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/algon-320/KIDE/util"
+	"github.com/headzoo/surf/browser"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// statusRows ... kide status で表示する提出の数 (新しいものから)
+const statusRows = 20
+
+// Submission ... 自分の提出 (提出一覧の1行)
+type Submission struct {
+	ID       string
+	URL      string
+	Problem  string
+	Language string
+	Date     time.Time // 取得出来なかった場合はゼロ値
+	Status   JudgeStatus
+	Verdict  string // 判定の文字列 (ジャッジ中は進捗など)
+	Waiting  bool   // ジャッジ中かどうか
+	Time     string // 実行時間 (表示用)
+	Memory   string // メモリ (表示用)
+}
+
+// SubmissionLister ... 自分の提出を取得出来るオンラインジャッジが実装するインターフェース (kide status で使う)
+type SubmissionLister interface {
+	// ListSubmissions ... contest の自分の提出の一覧 (新しい順)
+	// contest が空文字列の場合はコンテストに関係なく最近の提出 (対応していない場合は ErrContestRequired)
+	ListSubmissions(contest string) ([]*Submission, error)
+	// GetSubmission ... submissionURL の提出
+	GetSubmission(submissionURL string) (*Submission, error)
+}
+
+// statusFromLabel ... `AC` や `TLE` のような判定の略称を JudgeStatus にする (分からない場合は JudgeStatusUNK)
+func statusFromLabel(label string) JudgeStatus {
+	switch strings.ToUpper(strings.TrimSpace(label)) {
+	case "AC":
+		return JudgeStatusAC
+	case "WA":
+		return JudgeStatusWA
+	case "CE":
+		return JudgeStatusCE
+	case "RE":
+		return JudgeStatusRE
+	case "TLE":
+		return JudgeStatusTLE
+	case "MLE":
+		return JudgeStatusMLE
+	case "OLE":
+		return JudgeStatusOLE
+	case "IE":
+		return JudgeStatusIE
+	}
+	return JudgeStatusUNK
+}
+
+// waitingLabels ... ジャッジ中を表す判定の文字列 (AtCoder, yukicoder, AOJ)
+var waitingLabels = map[string]bool{
+	"": true, "WJ": true, "WR": true, "JUDGE": true, "JUDGING": true, "WAITING": true, "ジャッジ中": true, "ジャッジ待ち": true,
+	"-": true, "RUNNING": true, "WAITING JUDGE": true, "PENDING": true,
+}
+
+// isWaitingLabel ... 判定の文字列 label がジャッジ中を表すかどうか
+func isWaitingLabel(label string) bool {
+	return waitingLabels[strings.ToUpper(strings.TrimSpace(label))]
+}
+
+// findSubmission ... subs から URL が submissionURL のものを探す
+func findSubmission(subs []*Submission, submissionURL string) (*Submission, error) {
+	for _, s := range subs {
+		if s.URL == submissionURL {
+			return s, nil
+		}
+	}
+	return nil, &ErrNoSuchSubmission{url: submissionURL}
+}
+
+// submissionTableColumns ... 提出一覧の表の見出し (AtCoder, yukicoder)
+var submissionTableColumns = map[string][]string{
+	"date":     {"提出日時", "Submission Time"},
+	"problem":  {"問題", "Task", "Problem"},
+	"language": {"言語", "Language"},
+	"status":   {"結果", "判定", "Status"},
+	"time":     {"実行時間", "Exec Time"},
+	"memory":   {"メモリ", "Memory"},
+}
+
+// scrapeSubmissionTable ... 提出一覧のページの表から提出の一覧を作る (見出しの文字列から列を決める)
+// resolve: 提出の詳細ページへのリンクを絶対URLにする
+func scrapeSubmissionTable(doc *goquery.Selection, resolve func(string) string) []*Submission {
+	ret := []*Submission{}
+	doc.Find("table").EachWithBreak(func(_ int, table *goquery.Selection) bool {
+		column := tableColumns(table, submissionTableColumns)
+		if _, ok := column["status"]; !ok {
+			return true // 提出一覧の表ではない
+		}
+
+		table.Find("tbody > tr").Each(func(_ int, tr *goquery.Selection) {
+			tds := tr.Find("td")
+			var s Submission
+			tr.Find("a").Each(func(_ int, a *goquery.Selection) {
+				href, _ := a.Attr("href")
+				if strings.Contains(href, "/submissions/") {
+					s.URL = resolve(href)
+					s.ID = path.Base(href)
+				}
+			})
+			if s.URL == "" {
+				return
+			}
+			s.Problem = strings.TrimSpace(tableCell(tds, column, "problem").Text())
+			s.Language = strings.TrimSpace(tableCell(tds, column, "language").Text())
+			s.Date = parseSubmissionDate(strings.TrimSpace(tableCell(tds, column, "date").Text()))
+			s.Time = strings.TrimSpace(tableCell(tds, column, "time").Text())
+			s.Memory = strings.TrimSpace(tableCell(tds, column, "memory").Text())
+
+			statusCell := tableCell(tds, column, "status")
+			s.Verdict = strings.Join(strings.Fields(statusCell.Text()), " ")
+			s.Status = statusFromLabel(s.Verdict)
+			// `3/10 WA` のように進捗が表示されているものや WJ はジャッジ中とみなす
+			// (`QLE` のような知らない判定は JudgeStatusUNK のまま終わったものとして扱う)
+			s.Waiting = statusCell.HasClass("waiting-judge") || strings.Contains(s.Verdict, "/") || isWaitingLabel(s.Verdict)
+			ret = append(ret, &s)
+		})
+		return false
+	})
+	return ret
+}
+
+// tableColumns ... 表の見出しの文字列から、columns の名前ごとの列の番号を決める
+func tableColumns(table *goquery.Selection, columns map[string][]string) map[string]int {
+	ret := map[string]int{}
+	table.Find("thead th").Each(func(i int, th *goquery.Selection) {
+		text := strings.TrimSpace(th.Text())
+		for name, headers := range columns {
+			for _, h := range headers {
+				if text == h {
+					ret[name] = i
+				}
+			}
+		}
+	})
+	return ret
+}
+
+// tableCell ... 行 tds の name の列 (無い場合は空の Selection)
+func tableCell(tds *goquery.Selection, column map[string]int, name string) *goquery.Selection {
+	i, ok := column[name]
+	if !ok {
+		return &goquery.Selection{}
+	}
+	return tds.Eq(i)
+}
+
+// judgeDetailColumns ... 提出の詳細ページの見出し (AtCoder, yukicoder)
+var judgeDetailColumns = map[string][]string{
+	"case":   {"ケース名", "Case Name", "テストケース", "Test Case"},
+	"status": {"結果", "判定", "Status"},
+	"time":   {"実行時間", "Exec Time"},
+	"memory": {"メモリ", "実行メモリ", "Memory"},
+	"score":  {"得点", "Score"},
+}
+
+// scrapeJudgeDetail ... 提出の詳細ページから実行時間、メモリ、得点、コンパイルメッセージ、テストケースごとの結果を res に読み込む
+// (見出しの文字列から項目を決める)
+func scrapeJudgeDetail(doc *goquery.Selection, res *JudgeResult) {
+	// 提出の情報 (見出しと値が1行になっている表)
+	doc.Find("tr").Each(func(_ int, tr *goquery.Selection) {
+		th, td := tr.Find("th"), tr.Find("td")
+		if th.Length() != 1 || td.Length() != 1 {
+			return
+		}
+		text := strings.TrimSpace(th.Text())
+		value := strings.Join(strings.Fields(td.Text()), " ")
+		for name, headers := range judgeDetailColumns {
+			for _, h := range headers {
+				if text != h {
+					continue
+				}
+				switch name {
+				case "time":
+					res.Time = value
+				case "memory":
+					res.Memory = value
+				case "score":
+					res.Score = value
+				}
+			}
+		}
+	})
+
+	// コンパイルメッセージ (見出しの次の pre)
+	doc.Find("h3, h4, h5, h6").EachWithBreak(func(_ int, h *goquery.Selection) bool {
+		text := h.Text()
+		if strings.Contains(text, "コンパイル") || strings.Contains(text, "Compil") {
+			res.CompileMessage = strings.TrimSpace(h.NextAllFiltered("pre").First().Text())
+			return false
+		}
+		return true
+	})
+
+	// テストケースごとの結果
+	doc.Find("table").Each(func(_ int, table *goquery.Selection) {
+		column := tableColumns(table, judgeDetailColumns)
+		if _, ok := column["case"]; !ok {
+			return
+		}
+		table.Find("tbody > tr").Each(func(_ int, tr *goquery.Selection) {
+			tds := tr.Find("td")
+			c := CaseResult{
+				Name:    strings.TrimSpace(tableCell(tds, column, "case").Text()),
+				Verdict: strings.Join(strings.Fields(tableCell(tds, column, "status").Text()), " "),
+				Time:    strings.TrimSpace(tableCell(tds, column, "time").Text()),
+				Memory:  strings.TrimSpace(tableCell(tds, column, "memory").Text()),
+			}
+			if c.Name == "" {
+				return
+			}
+			c.Status = statusFromLabel(c.Verdict)
+			res.Cases = append(res.Cases, c)
+		})
+	})
+}
+
+// parseSubmissionDate ... 提出日時の文字列を読む (読めない場合はゼロ値)
+func parseSubmissionDate(text string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05-0700", "2006-01-02 15:04:05", "2006/01/02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// statusPoller ... 提出の状態を確認する間隔を変えたいオンラインジャッジが実装するインターフェース
+type statusPoller interface {
+	statusInterval() time.Duration
+}
+
+// sessionCache ... 何度も提出の一覧を取得する時に、ログイン済みのブラウザを使い回す
+type sessionCache struct {
+	mu sync.Mutex
+	br *browser.Browser
+}
+
+// get ... ログイン済みのブラウザを返す (まだ無ければ login でログインする)
+func (c *sessionCache) get(login func() (*browser.Browser, error)) (*browser.Browser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.br == nil {
+		br, err := login()
+		if err != nil {
+			return nil, err
+		}
+		c.br = br
+	}
+	return c.br, nil
+}
+
+// set ... 使い回すブラウザを br にする (nil の場合は次に get した時にログインし直す)
+func (c *sessionCache) set(br *browser.Browser) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.br = br
+}
+
+// ResolveContest ... `atcoder/abc300` のようなコンテストのキーから、オンラインジャッジとコンテストidを返す
+// `abc300` のようにコンテストidだけの場合は保存されている問題から探す (空文字列の場合は現在のコンテスト)
+// オンラインジャッジの名前だけの場合、コンテストidは空文字列になる
+func ResolveContest(contest string) (OnlineJudge, string, error) {
+	contest = strings.Trim(contest, "/")
+	if contest == "" {
+		contest = CurrentContest()
+		if contest == "" {
+			return nil, "", &ErrInvalidContest{contest: contest}
+		}
+	}
+
+	parts := strings.SplitN(contest, "/", 2)
+	if oj, err := FromName(parts[0]); err == nil {
+		if len(parts) == 1 {
+			return oj, "", nil
+		}
+		return oj, parts[1], nil
+	}
+
+	// 保存されている問題のコンテストから探す
+	found := map[string]struct{}{}
+	for _, key := range GetAllProblemID() {
+		if i := strings.LastIndex(key, "/"); i >= 0 && strings.EqualFold(path.Base(key[:i]), contest) {
+			found[key[:i]] = struct{}{}
+		}
+	}
+	if len(found) != 1 {
+		return nil, "", &ErrInvalidContest{contest: contest}
+	}
+	for key := range found {
+		return ResolveContest(key)
+	}
+	return nil, "", nil
+}
+
+// WatchSubmissions ... コンテスト contest の自分の提出を表にして、ジャッジ中のものが無くなるまで更新し続ける
+// (contest の形式は ResolveContest を参照)
+// 端末に出力している場合は表を書き換え、そうでない場合は最後に一度だけ表示する
+func WatchSubmissions(contest string) error {
+	oj, contestID, err := ResolveContest(contest)
+	if err != nil {
+		return err
+	}
+	lister, ok := oj.(SubmissionLister)
+	if !ok {
+		return fmt.Errorf(util.PrefixError+"%s doesn't support showing submissions", oj.Name())
+	}
+	interval := CheckInterval
+	if p, ok := oj.(statusPoller); ok {
+		interval = p.statusInterval()
+	}
+
+	live := terminal.IsTerminal(int(os.Stdout.Fd()))
+	lines := 0
+	for {
+		subs, err := lister.ListSubmissions(contestID)
+		if err != nil {
+			return err
+		}
+		if len(subs) > statusRows {
+			subs = subs[:statusRows]
+		}
+		waiting := 0
+		for _, s := range subs {
+			if s.Waiting {
+				waiting++
+			}
+		}
+
+		if live || waiting == 0 {
+			if lines > 0 {
+				util.MoveCursorUp(lines)
+				util.ClearScreenBelow()
+			}
+			lines = printSubmissions(subs)
+			if waiting > 0 {
+				fmt.Println(util.ESCS_COL_REVERSE + fmt.Sprintf("waiting for judge (%d)", waiting) + util.ESCS_COL_OFF)
+				lines++
+			}
+		}
+		if waiting == 0 {
+			return nil
+		}
+		time.Sleep(interval)
+	}
+}
+
+// printSubmissions ... 提出の一覧を表にして表示する
+// return: 表示した行数
+func printSubmissions(subs []*Submission) int {
+	if len(subs) == 0 {
+		fmt.Println(util.PrefixInfo + "No submissions.")
+		return 1
+	}
+	title := []string{"date", "problem", "language", "time", "memory", "verdict"}
+	data := [][]string{}
+	for _, s := range subs {
+		date := ""
+		if !s.Date.IsZero() {
+			date = s.Date.Local().Format("01-02 15:04:05")
+		}
+		verdict := s.Status.GetColorESCS() + s.Verdict + util.ESCS_COL_OFF
+		if s.Waiting {
+			verdict = s.Verdict
+		}
+		data = append(data, []string{date, s.Problem, s.Language, s.Time, s.Memory, verdict})
+	}
+	util.PrintTable(title, data, true)
+	return len(data) + 2
+}
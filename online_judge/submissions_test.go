@@ -0,0 +1,155 @@
+package online_judge
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestScrapeSubmissionTable(t *testing.T) {
+	fmt.Println("testing : submissions.go > scrapeSubmissionTable")
+
+	page := `<html><body>
+<table><thead><tr><th>Contest</th></tr></thead><tbody><tr><td>x</td></tr></tbody></table>
+<table>
+<thead><tr>
+<th>提出日時</th><th>問題</th><th>ユーザ</th><th>言語</th><th>得点</th><th>コード長</th><th>結果</th><th>実行時間</th><th>メモリ</th><th></th>
+</tr></thead>
+<tbody>
+<tr>
+<td><time class="fixtime-second">2023-04-29 21:05:12+0900</time></td><td><a href="/contests/abc300/tasks/abc300_b">B - Same Map in the RPG World</a></td><td>user</td>
+<td>C++ (GCC 9.2.1)</td><td>0</td><td>1024 Byte</td><td class="waiting-judge"><span>3/20 WA</span></td><td></td><td></td>
+<td><a href="/contests/abc300/submissions/41000002">詳細</a></td>
+</tr>
+<tr>
+<td><time class="fixtime-second">2023-04-29 21:01:00+0900</time></td><td><a href="/contests/abc300/tasks/abc300_a">A - N-choice question</a></td><td>user</td>
+<td>C++ (GCC 9.2.1)</td><td>100</td><td>512 Byte</td><td><span>AC</span></td><td>6 ms</td><td>3596 KB</td>
+<td><a href="/contests/abc300/submissions/41000001">詳細</a></td>
+</tr>
+<tr>
+<td><time class="fixtime-second">2023-04-29 21:00:00+0900</time></td><td><a href="/contests/abc300/tasks/abc300_a">A - N-choice question</a></td><td>user</td>
+<td>C++ (GCC 9.2.1)</td><td>0</td><td>512 Byte</td><td><span>QLE</span></td><td>2000 ms</td><td>3596 KB</td>
+<td><a href="/contests/abc300/submissions/41000000">詳細</a></td>
+</tr>
+<tr>
+<td><time class="fixtime-second">2023-04-29 20:59:00+0900</time></td><td><a href="/contests/abc300/tasks/abc300_a">A - N-choice question</a></td><td>user</td>
+<td>C++ (GCC 9.2.1)</td><td>0</td><td>512 Byte</td><td><span>WJ</span></td><td></td><td></td>
+<td><a href="/contests/abc300/submissions/40999999">詳細</a></td>
+</tr>
+</tbody>
+</table>
+</body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subs := scrapeSubmissionTable(doc.Selection, func(href string) string { return "https://atcoder.jp" + href })
+	if len(subs) != 4 {
+		t.Fatalf("scrapeSubmissionTable returned %d submissions, expected 4", len(subs))
+	}
+
+	if s := subs[0]; !s.Waiting || s.ID != "41000002" || s.Verdict != "3/20 WA" {
+		t.Errorf("unexpected judging submission : %+v", *s)
+	}
+	s := subs[1]
+	if s.Waiting || s.Status != JudgeStatusAC {
+		t.Errorf("unexpected status : %+v", *s)
+	}
+	if s.URL != "https://atcoder.jp/contests/abc300/submissions/41000001" {
+		t.Errorf("URL is %s", s.URL)
+	}
+	if s.Problem != "A - N-choice question" || s.Language != "C++ (GCC 9.2.1)" || s.Time != "6 ms" || s.Memory != "3596 KB" {
+		t.Errorf("unexpected columns : %+v", *s)
+	}
+	if s.Date.Unix() != 1682769660 {
+		t.Errorf("Date is %v", s.Date)
+	}
+
+	// 知らない判定はジャッジが終わったものとして扱う
+	if s := subs[2]; s.Waiting || s.Status != JudgeStatusUNK || s.Verdict != "QLE" {
+		t.Errorf("unexpected unknown verdict : %+v", *s)
+	}
+	if s := subs[3]; !s.Waiting {
+		t.Errorf("WJ should be waiting : %+v", *s)
+	}
+}
+
+func TestAOJStatusSubmission(t *testing.T) {
+	fmt.Println("testing : aoj.go > aojStatus.submission")
+
+	testcase := map[string]JudgeStatus{
+		"Accepted":              JudgeStatusAC,
+		"Wrong Answer":          JudgeStatusWA,
+		"Time Limit Exceeded":   JudgeStatusTLE,
+		"Compile Error":         JudgeStatusCE,
+		"Memory Limit Exceeded": JudgeStatusMLE,
+	}
+	for status, expect := range testcase {
+		s := (&aojStatus{RunID: "\n123\n", Status: "\n" + status + "\n", Cputime: "5"}).submission()
+		if s.Waiting || s.Status != expect {
+			t.Errorf("%s : status is %v (waiting: %v)", status, s.Status.ToString(), s.Waiting)
+		}
+		if s.URL != "http://judge.u-aizu.ac.jp/onlinejudge/review.jsp?rid=123" || s.Time != "50 ms" {
+			t.Errorf("%s : unexpected submission %+v", status, *s)
+		}
+	}
+	for _, status := range []string{"-", "Running", "Waiting Judge"} {
+		if s := (&aojStatus{Status: status}).submission(); !s.Waiting {
+			t.Errorf("`%s` should be waiting", status)
+		}
+	}
+	// 知らない判定はジャッジが終わったものとして扱う
+	if s := (&aojStatus{Status: "Judge Not Available"}).submission(); s.Waiting || s.Status != JudgeStatusUNK {
+		t.Errorf("unknown status : %+v", *s)
+	}
+}
+
+func TestScrapeJudgeDetail(t *testing.T) {
+	fmt.Println("testing : submissions.go > scrapeJudgeDetail")
+
+	page := `<html><body>
+<table>
+<tr><th>提出日時</th><td>2023-04-29 21:01:00+0900</td></tr>
+<tr><th>得点</th><td>100</td></tr>
+<tr><th>結果</th><td><span>TLE</span></td></tr>
+<tr><th>実行時間</th><td>2205 ms</td></tr>
+<tr><th>メモリ</th><td>3596 KB</td></tr>
+</table>
+<h4>コンパイルエラー</h4>
+<pre>./Main.cpp: warning: unused variable</pre>
+<table>
+<thead><tr><th>ケース名</th><th>結果</th><th>実行時間</th><th>メモリ</th></tr></thead>
+<tbody>
+<tr><td>sample_01.txt</td><td><span>AC</span></td><td>6 ms</td><td>3512 KB</td></tr>
+<tr><td>random_01.txt</td><td><span>TLE</span></td><td>2205 ms</td><td>3596 KB</td></tr>
+<tr><td>random_02.txt</td><td><span>AC</span></td><td>8 ms</td><td>3560 KB</td></tr>
+</tbody>
+</table>
+</body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res JudgeResult
+	scrapeJudgeDetail(doc.Selection, &res)
+
+	if res.Score != "100" || res.Time != "2205 ms" || res.Memory != "3596 KB" {
+		t.Errorf("unexpected details : score %q, time %q, memory %q", res.Score, res.Time, res.Memory)
+	}
+	if res.CompileMessage != "./Main.cpp: warning: unused variable" {
+		t.Errorf("CompileMessage is %q", res.CompileMessage)
+	}
+	if len(res.Cases) != 3 {
+		t.Fatalf("%d cases, expected 3", len(res.Cases))
+	}
+	if c := res.Cases[1]; c.Name != "random_01.txt" || c.Status != JudgeStatusTLE || c.Time != "2205 ms" {
+		t.Errorf("unexpected case : %+v", c)
+	}
+
+	expect := JudgeStatusAC.GetColorESCS() + "AC" + "\033[0m x 2, " + JudgeStatusTLE.GetColorESCS() + "TLE" + "\033[0m x 1"
+	if got := res.caseSummary(); got != expect {
+		t.Errorf("caseSummary returned %q, expected %q", got, expect)
+	}
+}
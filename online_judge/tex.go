@@ -0,0 +1,201 @@
+package online_judge
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// texMathRegexp ... 問題文中の数式 (`$$$...$$$` は Codeforces、`$...$`、`\(...\)` は AtCoder などで使われる)
+// `$...$` は `$` のすぐ内側が空白でないものだけを数式とみなす (`$5 and $3` のような普通の文を数式にしない)
+var texMathRegexp = regexp.MustCompile(`(?s)\$\$\$(.+?)\$\$\$|\$\$(.+?)\$\$|\$([^\s$](?:[^$]*?[^\s$])?)\$|\\\((.+?)\\\)|\\\[(.+?)\\\]`)
+
+// replaceTeXMath ... text 中の数式を texToUnicode で読みやすい文字列にする
+func replaceTeXMath(text string) string {
+	return texMathRegexp.ReplaceAllStringFunc(text, func(m string) string {
+		group := texMathRegexp.FindStringSubmatch(m)
+		for _, g := range group[1:] {
+			if g != "" {
+				return texToUnicode(g)
+			}
+		}
+		return m
+	})
+}
+
+// texSymbols ... 引数を取らないコマンド -> 文字
+var texSymbols = map[string]string{
+	"leq": "≤", "le": "≤", "leqq": "≤", "geq": "≥", "ge": "≥", "geqq": "≥", "neq": "≠", "ne": "≠",
+	"lt": "<", "gt": ">", "times": "×", "cdot": "⋅", "cdots": "⋯", "ldots": "…", "dots": "…", "vdots": "⋮",
+	"pm": "±", "mp": "∓", "div": "÷", "mid": "|", "infty": "∞", "sum": "Σ", "prod": "Π",
+	"in": "∈", "notin": "∉", "ni": "∋", "subset": "⊂", "subseteq": "⊆", "supset": "⊃", "supseteq": "⊇",
+	"cup": "∪", "cap": "∩", "emptyset": "∅", "setminus": "∖",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "Rightarrow": "⇒", "Leftarrow": "⇐",
+	"leftrightarrow": "↔", "Leftrightarrow": "⇔", "iff": "⇔", "mapsto": "↦",
+	"forall": "∀", "exists": "∃", "neg": "¬", "lnot": "¬", "land": "∧", "wedge": "∧", "lor": "∨", "vee": "∨",
+	"oplus": "⊕", "otimes": "⊗", "equiv": "≡", "approx": "≈", "sim": "∼", "simeq": "≃", "propto": "∝",
+	"lfloor": "⌊", "rfloor": "⌋", "lceil": "⌈", "rceil": "⌉", "langle": "⟨", "rangle": "⟩",
+	"circ": "∘", "bullet": "∙", "star": "⋆", "prime": "′", "partial": "∂", "nabla": "∇",
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ε", "varepsilon": "ε", "zeta": "ζ",
+	"eta": "η", "theta": "θ", "iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+	"pi": "π", "rho": "ρ", "sigma": "σ", "tau": "τ", "phi": "φ", "varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π", "Sigma": "Σ", "Phi": "Φ",
+	"Psi": "Ψ", "Omega": "Ω",
+	"bmod": " mod ", "mod": " mod ", "log": "log", "ln": "ln", "max": "max", "min": "min", "gcd": "gcd",
+	"lcm": "lcm", "sin": "sin", "cos": "cos", "tan": "tan", "exp": "exp", "lim": "lim",
+	",": " ", ";": " ", ":": " ", "!": "", " ": " ", "quad": "  ", "qquad": "    ",
+	"{": "{", "}": "}", "%": "%", "_": "_", "&": "&", "#": "#", "$": "$", "|": "‖", "\\": "\n",
+	"left": "", "right": "", "big": "", "Big": "", "bigl": "", "bigr": "", "displaystyle": "", "limits": "",
+}
+
+// texStyleCommands ... 引数をそのまま出力するコマンド
+var texStyleCommands = map[string]bool{
+	"mathrm": true, "text": true, "textrm": true, "mathbf": true, "textbf": true, "mathit": true, "textit": true,
+	"mathtt": true, "texttt": true, "mathsf": true, "mathcal": true, "mathbb": true, "operatorname": true,
+	"mbox": true, "boldsymbol": true, "underline": true, "overline": true,
+}
+
+var superscripts = map[rune]rune{
+	'0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴', '5': '⁵', '6': '⁶', '7': '⁷', '8': '⁸', '9': '⁹',
+	'+': '⁺', '-': '⁻', '=': '⁼', '(': '⁽', ')': '⁾', 'n': 'ⁿ', 'i': 'ⁱ', 'k': 'ᵏ', 'm': 'ᵐ', 'x': 'ˣ', 'y': 'ʸ',
+	'a': 'ᵃ', 'b': 'ᵇ', 'c': 'ᶜ', 'd': 'ᵈ', 'e': 'ᵉ', 'j': 'ʲ', 'N': 'ᴺ', 'M': 'ᴹ', 'K': 'ᴷ', 'T': 'ᵀ',
+	'′': '′',
+}
+
+var subscripts = map[rune]rune{
+	'0': '₀', '1': '₁', '2': '₂', '3': '₃', '4': '₄', '5': '₅', '6': '₆', '7': '₇', '8': '₈', '9': '₉',
+	'+': '₊', '-': '₋', '=': '₌', '(': '₍', ')': '₎', 'a': 'ₐ', 'e': 'ₑ', 'h': 'ₕ', 'i': 'ᵢ', 'j': 'ⱼ',
+	'k': 'ₖ', 'l': 'ₗ', 'm': 'ₘ', 'n': 'ₙ', 'o': 'ₒ', 'p': 'ₚ', 'r': 'ᵣ', 's': 'ₛ', 't': 'ₜ', 'u': 'ᵤ',
+	'v': 'ᵥ', 'x': 'ₓ',
+}
+
+// texToUnicode ... TeX の数式を Unicode の文字で読みやすく書き直す (`N \leq 10^5` -> `N ≤ 10⁵`)
+// 完全な変換は目指さず、競技プログラミングの問題文でよく使われるものだけに対応する
+func texToUnicode(tex string) string {
+	p := &texParser{src: []rune(tex)}
+	return strings.TrimSpace(p.parseUntil(0))
+}
+
+type texParser struct {
+	src []rune
+	pos int
+}
+
+// parseUntil ... end の文字 (0 なら最後) まで読んで変換する
+func (p *texParser) parseUntil(end rune) string {
+	var sb strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if end != 0 && c == end {
+			p.pos++
+			break
+		}
+		switch c {
+		case '{':
+			p.pos++
+			sb.WriteString(p.parseUntil('}'))
+		case '}':
+			p.pos++
+		case '^', '_':
+			p.pos++
+			sb.WriteString(script(p.argument(), c == '^'))
+		case '\\':
+			sb.WriteString(p.command())
+		case '~':
+			p.pos++
+			sb.WriteRune(' ')
+		default:
+			p.pos++
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+// argument ... コマンドや `^`、`_` の引数 (`{...}` または1文字またはコマンド)
+func (p *texParser) argument() string {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	switch c := p.src[p.pos]; c {
+	case '{':
+		p.pos++
+		return p.parseUntil('}')
+	case '\\':
+		return p.command()
+	default:
+		p.pos++
+		return string(c)
+	}
+}
+
+// command ... `\` から始まるコマンドを読んで変換する
+func (p *texParser) command() string {
+	p.pos++ // `\`
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	start := p.pos
+	for p.pos < len(p.src) && unicode.IsLetter(p.src[p.pos]) && p.src[p.pos] < unicode.MaxASCII {
+		p.pos++
+	}
+	if p.pos == start {
+		p.pos++ // `\{` のような記号1文字のコマンド
+	}
+	name := string(p.src[start:p.pos])
+
+	switch {
+	case name == "frac" || name == "dfrac" || name == "tfrac":
+		num, den := p.argument(), p.argument()
+		return wrapCompound(num) + "/" + wrapCompound(den)
+	case name == "binom":
+		n, k := p.argument(), p.argument()
+		return "C(" + n + ", " + k + ")"
+	case name == "sqrt":
+		index := ""
+		if p.pos < len(p.src) && p.src[p.pos] == '[' {
+			p.pos++
+			index = script(p.parseUntil(']'), true)
+		}
+		return index + "√" + wrapCompound(p.argument())
+	case name == "pmod":
+		return " (mod " + p.argument() + ")"
+	case texStyleCommands[name]:
+		return p.argument()
+	}
+	if s, ok := texSymbols[name]; ok {
+		return s
+	}
+	return name
+}
+
+// wrapCompound ... 2文字以上の式は括弧で囲む (分数や平方根の中身に使う)
+func wrapCompound(s string) string {
+	if len([]rune(s)) <= 1 {
+		return s
+	}
+	return "(" + s + ")"
+}
+
+// script ... 上付き (sup が true) または下付きの文字にする (Unicode に無い文字を含む場合は `^(...)`、`_(...)` にする)
+func script(s string, sup bool) string {
+	table, mark := subscripts, "_"
+	if sup {
+		table, mark = superscripts, "^"
+	}
+	var sb strings.Builder
+	for _, c := range s {
+		r, ok := table[c]
+		if !ok {
+			if len([]rune(s)) == 1 {
+				return mark + s
+			}
+			return mark + "(" + s + ")"
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
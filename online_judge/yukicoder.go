@@ -1,37 +1,61 @@
 package online_judge
 
 import (
+	"encoding/json"
 	"fmt"
 	"html"
+	"net/http"
 	"os"
+	"path"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/algon-320/KIDE/credential"
 	"github.com/algon-320/KIDE/language"
 	"github.com/algon-320/KIDE/setting"
 	"github.com/algon-320/KIDE/util"
 	"github.com/headzoo/surf/browser"
-	"gopkg.in/headzoo/surf.v1"
 )
 
 type yukicoder struct {
-	name        string
-	url         string
-	loginURL    string
-	sessionFile string
+	name              string
+	url               string
+	loginURL          string
+	sessionFile       string
+	settingKey        string // 設定ファイルでの名前 (OnlineJudge.{settingKey})
+	languageCacheFile string
+	session           sessionCache // 提出の一覧を取得する時のログイン済みのブラウザ
 }
 
 // Yukicoder ... オンラインジャッジ: yukicoder
 var Yukicoder = &yukicoder{
-	name:        "yukicoder",
-	url:         "https://yukicoder.me/",
-	loginURL:    "https://yukicoder.me/auth/twitter",
-	sessionFile: "session_yukicoder.dat",
+	name:              "yukicoder",
+	url:               "https://yukicoder.me/",
+	loginURL:          "https://yukicoder.me/auth/twitter",
+	sessionFile:       "session_yukicoder.dat",
+	settingKey:        "yukicoder",
+	languageCacheFile: "languages_yukicoder.json",
 }
 
-func (yc *yukicoder) getLangID(lang language.Language) (string, error) {
+func init() {
+	Register(&Registration{
+		Key:          "yukicoder",
+		Judge:        Yukicoder,
+		Aliases:      []string{Yukicoder.settingKey},
+		Hosts:        []string{"yukicoder.me"},
+		Capabilities: CapabilityContestDownload | CapabilityAPI,
+		MainClass:    "Main",
+	})
+}
+
+// getLangID ... lang (profile) で提出する時の言語ID
+// 設定ファイルの OnlineJudge.yukicoder.LanguageID に指定されていればそれを、無ければデフォルトのものを返す
+func (yc *yukicoder) getLangID(lang language.Language, profile string) (string, error) {
+	if id, ok := langIDFromSetting(yc.settingKey, lang, profile); ok {
+		return id, nil
+	}
 	switch lang {
 	case language.CPP:
 		return "cpp14", nil // C++14 (gcc 7.1.0)
@@ -55,19 +79,13 @@ func (yc *yukicoder) loadAccount() (string, string) {
 		setting.Set("OnlineJudge.yukicoder.Handle", handle)
 	}
 
-	var password string
-	if tmp, ok := setting.Get("OnlineJudge.yukicoder.Password", "YUKICODER_PASSWORD"); ok {
-		password = tmp.(string)
-	} else {
-		password = util.AskString("What is your yukicoder account password (twitter password) ?")
-		setting.Set("OnlineJudge.yukicoder.Password", password)
-	}
+	password := credential.LoadPassword("yukicoder", handle, "YUKICODER_PASSWORD", "What is your yukicoder account password (twitter password) ?")
 
 	return handle, password
 }
 
 func (yc *yukicoder) login() (*browser.Browser, error) {
-	br := surf.NewBrowser()
+	br, rec := newSessionBrowser()
 
 	cjar := util.LoadLoginSession(yc.sessionFile, yc.url)
 	if cjar != nil {
@@ -107,12 +125,24 @@ func (yc *yukicoder) login() (*browser.Browser, error) {
 
 	if yc.checkLoggedin(br) {
 		cookies := br.SiteCookies()
-		util.SaveLoginSession(yc.sessionFile, cookies)
+		util.SaveLoginSession(yc.sessionFile, rec.withExpiry(cookies))
 		return br, nil
 	}
 	return nil, &ErrFailedToLogin{oj_name: yc.Name(), message: "Incorrect username or password"}
 }
 
+// accountInfo ... アカウントとセッションの情報
+func (yc *yukicoder) accountInfo() accountInfo {
+	return accountInfo{
+		settingKey:  yc.settingKey,
+		envPrefix:   "YUKICODER",
+		sessionFile: yc.sessionFile,
+		cookieURL:   yc.url,
+		loginURL:    yc.loginURL,
+		session:     &yc.session,
+	}
+}
+
 func (yc *yukicoder) checkLoggedin(br *browser.Browser) bool {
 	prevURL := br.Url()
 	if prevURL != nil {
@@ -125,11 +155,16 @@ func (yc *yukicoder) checkLoggedin(br *browser.Browser) bool {
 	return br.Title() != "yukicoder"
 }
 
+// sessionRejected ... ログインしていないと提出ページはトップページのタイトルで表示される
+func (yc *yukicoder) sessionRejected(br *browser.Browser) bool {
+	return br.Url() != nil && strings.HasSuffix(br.Url().Path, "/submit") && br.Title() == "yukicoder"
+}
+
 func (yc *yukicoder) Name() string {
 	return yc.name
 }
 
-func (yc *yukicoder) Submit(p *Problem, sourceCode string, lang language.Language) (*JudgeResult, error) {
+func (yc *yukicoder) Submit(p *Problem, sourceCode string, lang language.Language, profile string) (*JudgeResult, error) {
 	if sourceCode == "" {
 		return nil, &ErrFailedToSubmit{message: "should not be empty."}
 	}
@@ -140,11 +175,11 @@ func (yc *yukicoder) Submit(p *Problem, sourceCode string, lang language.Languag
 	}
 
 	submitURL := yc.url + fmt.Sprintf("problems/no/%s/submit", p.ID)
-	if err := br.Open(submitURL); err != nil {
+	if br, err = openAuthorized(yc, br, submitURL); err != nil {
 		return nil, err
 	}
 
-	langID, err := yc.getLangID(lang)
+	langID, err := yc.getLangID(lang, profile)
 	if err != nil {
 		return nil, err
 	}
@@ -187,7 +222,7 @@ waiting:
 		br.Open(mysubmissionURL)
 		status = br.Dom().Find("#status").Text()
 
-		switch status {
+		switch strings.TrimSpace(status) {
 		case "AC":
 			res.Status = JudgeStatusAC
 			break waiting
@@ -212,6 +247,11 @@ waiting:
 		case "IE":
 			res.Status = JudgeStatusIE
 			break waiting
+		default:
+			// 知らない判定 (QLE など) は JudgeStatusUNK のまま終わる
+			if !isWaitingLabel(status) {
+				break waiting
+			}
 		}
 
 		util.SaveCursorPos()
@@ -228,70 +268,121 @@ waiting:
 	}
 	fmt.Print("\n")
 
+	// 提出の詳細 (実行時間、メモリ、テストケースごとの結果など)
+	scrapeJudgeDetail(br.Dom(), &res)
+
 	return &res, nil
 }
 
-func (yc *yukicoder) NewProblem(url string) error {
-	isValid, isSet := yc.IsValidURL(url)
-	if !isValid {
-		return &ErrInvalidProblemURL{url: url}
-	}
+// fetchProblem ... problemURL の問題を br でダウンロードする (保存はしない)
+func (yc *yukicoder) fetchProblem(br *browser.Browser, problemURL string) (*Problem, error) {
+	var p Problem
+	p.Oj = Yukicoder
+	p.URL = problemURL
 
-	br, err := yc.login()
-	if err != nil {
-		return err
+	re := regexp.MustCompile(yc.url + "problems/no/(.+)")
+	group := re.FindSubmatch([]byte(problemURL))
+	if group == nil {
+		return nil, &ErrInvalidProblemURL{url: problemURL}
 	}
 
-	downloadProblem := func(problemURL string) error {
-		var p Problem
-		p.Oj = Yukicoder
-		p.URL = problemURL
+	p.ContestID = ""
+	p.ID = string(group[1])
+	p.Name = string(group[1])
 
-		re := regexp.MustCompile(yc.url + "problems/no/(.+)")
-		group := re.FindSubmatch([]byte(problemURL))
-		if group == nil {
-			return &ErrInvalidProblemURL{url: problemURL}
+	if err := openPage(br, problemURL); err != nil {
+		return nil, err
+	}
+	doc := br.Dom()
+
+	content := doc.Find("#content").First()
+	fillLimits(&p, content.Text())
+	fillStatementMetadata(&p, content)
+	content.Find("a[href*='tag']").Each(func(_ int, s *goquery.Selection) {
+		if tag := strings.TrimSpace(s.Text()); tag != "" {
+			p.Tags = append(p.Tags, tag)
 		}
+	})
+
+	var testCase TestCase
+	doc.Find("div.sample > div").Each(func(_ int, s *goquery.Selection) {
+		testCase.Input = s.Find("pre:nth-of-type(1)").Text()
+		testCase.Input = html.UnescapeString(testCase.Input)
+		testCase.Input = util.AddBR(testCase.Input)
+		testCase.Output = s.Find("pre:nth-of-type(2)").Text()
+		testCase.Output = html.UnescapeString(testCase.Output)
+		testCase.Output = util.AddBR(testCase.Output)
+		testCase.Sample = true
+
+		p.Cases = append(p.Cases, testCase)
+	})
+	return &p, nil
+}
 
-		p.ContestID = ""
-		p.ID = string(group[1])
-		p.Name = string(group[1])
+// problemURLs ... コンテストの問題一覧ページから問題のURLの一覧を取得する
+func (yc *yukicoder) problemURLs(br *browser.Browser, url string) ([]string, error) {
+	if err := openPage(br, url); err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	br.Dom().Find("tbody").Find("tr").Each(func(i int, tr *goquery.Selection) {
+		problemURL, ok := tr.Find("td:nth-of-type(3) > a").Attr("href")
+		if !ok {
+			return
+		}
+		problemURL, _ = br.ResolveStringUrl(problemURL)
+		ret = append(ret, problemURL)
+	})
+	return ret, nil
+}
 
-		br.Open(problemURL)
-		doc := br.Dom()
+// contestStartTime ... yukicoder の API からコンテストの開始時刻を取得する
+func (yc *yukicoder) contestStartTime(contestURL string) (time.Time, error) {
+	group := regexp.MustCompile(yc.url + "contests/([0-9]+)").FindStringSubmatch(contestURL)
+	if group == nil {
+		return time.Time{}, &ErrInvalidContestURL{url: contestURL}
+	}
+	resp, err := httpClient(nil).Get(yc.url + "api/v1/contest/id/" + group[1])
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, &ErrFailedToGetContestStartTime{message: resp.Status}
+	}
+	var contest struct {
+		Date time.Time `json:"Date"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&contest); err != nil {
+		return time.Time{}, &ErrFailedToGetContestStartTime{message: err.Error()}
+	}
+	return contest.Date, nil
+}
 
-		var testCase TestCase
-		doc.Find("div.sample > div").Each(func(_ int, s *goquery.Selection) {
-			testCase.Input = s.Find("pre:nth-of-type(1)").Text()
-			testCase.Input = html.UnescapeString(testCase.Input)
-			testCase.Input = util.AddBR(testCase.Input)
-			testCase.Output = s.Find("pre:nth-of-type(2)").Text()
-			testCase.Output = html.UnescapeString(testCase.Output)
-			testCase.Output = util.AddBR(testCase.Output)
-			p.Cases = append(p.Cases, testCase)
-		})
+func (yc *yukicoder) NewProblem(url string) error {
+	isValid, isSet := yc.IsValidURL(url)
+	if !isValid {
+		return &ErrInvalidProblemURL{url: url}
+	}
 
-		p.Print()
-		return p.Save()
+	br, err := yc.login()
+	if err != nil {
+		return err
 	}
 
 	if isSet {
 		util.DebugPrint("download [yukicoder] problem set")
 
-		br.Open(url)
-		doc := br.Dom()
-		doc.Find("tbody").Find("tr").Each(func(i int, tr *goquery.Selection) {
-			problemURL, _ := tr.Find("td:nth-of-type(3) > a").Attr("href")
-			problemURL, _ = br.ResolveStringUrl(problemURL)
-			// fmt.Println(problemURL)
-			err := downloadProblem(problemURL)
-			if err != nil {
-				fmt.Println(util.PrefixError + fmt.Sprintf("%s", err))
-			}
-		})
-		return nil
+		_, err := downloadProblemSet(yc, br, url)
+		return err
 	}
-	return downloadProblem(url)
+
+	p, err := yc.fetchProblem(br, url)
+	if err != nil {
+		return err
+	}
+	p.Print()
+	return p.Save()
 }
 
 func (yc *yukicoder) IsValidURL(url string) (bool, bool) {
@@ -305,6 +396,63 @@ func (yc *yukicoder) IsValidURL(url string) (bool, bool) {
 	}
 }
 
-func (yc *yukicoder) MarshalJSON() ([]byte, error) {
-	return []byte(`"` + yc.Name() + `"`), nil
+// scrapeLanguages ... 提出フォームから言語の一覧を取得する
+func (yc *yukicoder) scrapeLanguages() ([]JudgeLanguage, error) {
+	br, err := yc.login()
+	if err != nil {
+		return nil, err
+	}
+	if br, err = openAuthorized(yc, br, yc.url+"problems/no/1/submit"); err != nil {
+		return nil, err
+	}
+	langs := scrapeLanguageOptions(br.Dom().Find("select[name='lang']").First())
+	if len(langs) == 0 {
+		return nil, &ErrFailedToGetLanguages{oj_name: yc.Name()}
+	}
+	return langs, nil
+}
+
+// Languages ... 提出時に選択できる言語の一覧
+func (yc *yukicoder) Languages(refresh bool) ([]JudgeLanguage, error) {
+	return getLanguages(yc.languageCacheFile, refresh, yc.scrapeLanguages)
+}
+
+// LanguageMappings ... 設定ファイルで言語IDに対応付けられた言語
+func (yc *yukicoder) LanguageMappings() map[string][]string {
+	return languageMappings(yc.settingKey)
+}
+
+// ListSubmissions ... コンテストの自分の提出の一覧 (contest が空文字列の場合は全ての問題の提出)
+func (yc *yukicoder) ListSubmissions(contest string) ([]*Submission, error) {
+	br, err := yc.session.get(yc.login)
+	if err != nil {
+		return nil, err
+	}
+	listURL := yc.url + "submissions?my_submission=enabled"
+	if contest != "" {
+		listURL = yc.url + fmt.Sprintf("contests/%s/submissions?my_submission=enabled", contest)
+	}
+	if br, err = openAuthorized(yc, br, listURL); err != nil {
+		return nil, err
+	}
+	return scrapeSubmissionTable(br.Dom(), func(href string) string {
+		u, _ := br.ResolveStringUrl(href)
+		return u
+	}), nil
+}
+
+// GetSubmission ... submissionURL の提出 (提出の詳細ページから判定を取得する)
+func (yc *yukicoder) GetSubmission(submissionURL string) (*Submission, error) {
+	br, err := yc.session.get(yc.login)
+	if err != nil {
+		return nil, err
+	}
+	if br, err = openAuthorized(yc, br, submissionURL); err != nil {
+		return nil, err
+	}
+	s := &Submission{ID: path.Base(submissionURL), URL: submissionURL}
+	s.Verdict = strings.TrimSpace(br.Dom().Find("#status").Text())
+	s.Status = statusFromLabel(s.Verdict)
+	s.Waiting = s.Status == JudgeStatusUNK
+	return s, nil
 }
@@ -2,11 +2,56 @@ package online_judge
 
 import (
 	"fmt"
+	"net/http"
+	"os"
+	"reflect"
 	"testing"
 )
 
+// yukicoderRoutes ... yukicoder (Twitterでの認証) のログインの流れと記録しておいたページを返すハンドラ
+// ユーザー名 kide、パスワード password で認証すると REVEL_SESSION が設定される
+func yukicoderRoutes(t *testing.T) map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"yukicoder.me/": fixture(t, "yukicoder_top.html"),
+		"yukicoder.me/auth/twitter": func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "https://api.twitter.com/oauth/authenticate?oauth_token=dummy-oauth-token", http.StatusFound)
+		},
+		"api.twitter.com/oauth/authenticate": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "POST" {
+				r.ParseForm()
+				if r.Form.Get("cancel") == "" && r.Form.Get("session[username_or_email]") == "kide" && r.Form.Get("session[password]") == "password" {
+					http.Redirect(w, r, "https://yukicoder.me/auth/twitter/callback?oauth_token=dummy-oauth-token&oauth_verifier=ok", http.StatusFound)
+					return
+				}
+			}
+			fixture(t, "twitter_authenticate.html")(w, r)
+		},
+		"yukicoder.me/auth/twitter/callback": func(w http.ResponseWriter, r *http.Request) {
+			http.SetCookie(w, &http.Cookie{Name: "REVEL_SESSION", Value: "loggedin", Path: "/"})
+			http.Redirect(w, r, "/", http.StatusFound)
+		},
+		"yukicoder.me/problems/no/1/submit": func(w http.ResponseWriter, r *http.Request) {
+			if c, err := r.Cookie("REVEL_SESSION"); err != nil || c.Value != "loggedin" {
+				fixture(t, "yukicoder_top.html")(w, r) // ログインしていない場合はタイトルが `yukicoder` のページになる
+				return
+			}
+			fixture(t, "yukicoder_submit.html")(w, r)
+		},
+		"yukicoder.me/problems/no/1":      fixture(t, "yukicoder_problem_1.html"),
+		"yukicoder.me/contests/173":       fixture(t, "yukicoder_contest_173.html"),
+		"yukicoder.me/submissions/200001": fixture(t, "yukicoder_submission.html"),
+	}
+}
+
 func TestYukicoderLogin(t *testing.T) {
 	fmt.Println("testing : yukicoder.go > Yukicoder.login")
+	defer serveFixtures(t, yukicoderRoutes(t))()
+
+	os.Setenv("YUKICODER_HANDLE", "kide")
+	os.Setenv("YUKICODER_PASSWORD", "password")
+	defer os.Unsetenv("YUKICODER_HANDLE")
+	defer os.Unsetenv("YUKICODER_PASSWORD")
+
 	br, err := Yukicoder.login()
 	if err != nil {
 		t.Error("yukicoderのログイン処理でエラーが発生しました。")
@@ -20,32 +65,79 @@ func TestYukicoderLogin(t *testing.T) {
 	}
 }
 
-func TestYukicoderNewProblem(t *testing.T) {
-	fmt.Println("testing : yukicoder.go > Yukicoder.NewProblem")
-	if err := Yukicoder.NewProblem("https://yukicoder.me/problems/no/543"); err != nil {
-		t.Error(err)
-	}
+func TestYukicoderFetchProblem(t *testing.T) {
+	fmt.Println("testing : yukicoder.go > Yukicoder.fetchProblem")
+	defer serveFixtures(t, yukicoderRoutes(t))()
 
-	p, err := LoadProblem("543")
+	p, err := Yukicoder.fetchProblem(newBrowser(), "https://yukicoder.me/problems/no/1")
 	if err != nil {
-		t.Error(err)
-		return
+		t.Fatal(err)
 	}
 
-	if p.ID != "543" {
+	if p.ID != "1" {
 		t.Error("id検出エラー")
 	}
-	if p.Name != "543" {
+	if p.Name != "1" {
 		t.Error("問題名検出エラー")
 	}
-	if p.URL != "https://yukicoder.me/problems/no/543" {
+	if p.URL != "https://yukicoder.me/problems/no/1" {
 		t.Error("URLエラー")
 	}
-	if p.Oj.Name() != Yukicoder.Name() {
+	if p.Oj != Yukicoder {
 		t.Error("OJエラー")
 	}
 	if len(p.Cases) != 2 {
-		t.Error("サンプルケースを抽出出来ていません！")
+		t.Fatal("サンプルケースを抽出出来ていません！")
+	}
+	if p.Cases[1].Input != "3\n100\n3\n1 2 1\n2 3 1\n10 90 10\n10 10 50\n" || p.Cases[1].Output != "20\n" {
+		t.Errorf("サンプルケースの内容が違います : %+v", p.Cases[1])
+	}
+	if p.TimeLimitMs != 5000 || p.MemoryLimitMB != 512 || len(p.Tags) != 2 || p.Tags[0] != "ダイクストラ" {
+		t.Errorf("問題の情報が違います : %v", p.Metadata())
+	}
+}
+
+func TestYukicoderProblemURLs(t *testing.T) {
+	fmt.Println("testing : yukicoder.go > Yukicoder.problemURLs")
+	defer serveFixtures(t, yukicoderRoutes(t))()
+
+	urls, err := Yukicoder.problemURLs(newBrowser(), "https://yukicoder.me/contests/173")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"https://yukicoder.me/problems/no/556",
+		"https://yukicoder.me/problems/no/557",
+	}
+	if !reflect.DeepEqual(urls, expect) {
+		t.Errorf("problemURLs returned %v, expected %v", urls, expect)
+	}
+}
+
+func TestYukicoderGetSubmission(t *testing.T) {
+	fmt.Println("testing : yukicoder.go > Yukicoder.GetSubmission")
+	defer serveFixtures(t, yukicoderRoutes(t))()
+
+	br := newBrowser()
+	Yukicoder.session.br = br
+	defer func() { Yukicoder.session.br = nil }()
+
+	s, err := Yukicoder.GetSubmission("https://yukicoder.me/submissions/200001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != "200001" || s.Status != JudgeStatusWA || s.Waiting {
+		t.Errorf("unexpected submission : %+v", *s)
+	}
+
+	// 提出の詳細 (GetSubmission で開いたページ)
+	var res JudgeResult
+	scrapeJudgeDetail(br.Dom(), &res)
+	if res.Time != "2 ms" {
+		t.Errorf("Time is %q", res.Time)
+	}
+	if len(res.Cases) != 2 || res.Cases[1].Status != JudgeStatusWA || res.Cases[1].Memory != "6,944 KB" {
+		t.Errorf("unexpected cases : %+v", res.Cases)
 	}
 }
 
@@ -6,8 +6,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/algon-320/KIDE/util"
 )
@@ -19,20 +19,25 @@ const (
 
 var wrapper map[string]interface{} = make(map[string]interface{})
 
+// defaults ... SetDefault で登録されたデフォルト値 (設定ファイルに無い場合に使う, 保存はしない)
+var defaults map[string]interface{} = make(map[string]interface{})
+
+// mu ... wrapper と設定ファイルを守る (並列にダウンロードしている間にも設定が読み書きされる)
+var mu sync.RWMutex
+
+// Path ... 設定ファイルのパス (util.ConfigDir の下)
+func Path() string {
+	return util.ConfigPath(SettingFilename)
+}
+
 func init() {
-	// 実行ファイルのディレクトリに移動
-	prevDir, _ := os.Getwd()
-	defer os.Chdir(prevDir)
-	exeDir, _ := os.Executable()
-	exeDir = filepath.Dir(exeDir)
-	os.Chdir(exeDir)
-
-	if !util.FileExists(SettingFilename) {
-		fmt.Fprintln(os.Stderr, util.PrefixCaution+"No setting file `"+SettingFilename+"`.")
+	path := Path()
+	if !util.FileExists(path) {
+		fmt.Fprintln(os.Stderr, util.PrefixCaution+"No setting file `"+path+"`.")
 		return
 	}
 
-	bytes, err := ioutil.ReadFile(SettingFilename)
+	bytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, util.PrefixError+"File open error:", err)
 		return
@@ -56,28 +61,55 @@ func Get(selector string, envVarKey string) (interface{}, bool) {
 		}
 	}
 
+	mu.RLock()
+	defer mu.RUnlock()
+
 	sel := strings.Split(selector, ".")
-	cur := wrapper
+	if ret, ok := lookup(wrapper, sel); ok {
+		return ret, true
+	}
+	return lookup(defaults, sel)
+}
+
+// lookup ... root から sel で指定された値を探す
+func lookup(root map[string]interface{}, sel []string) (interface{}, bool) {
+	cur := root
 	for i := 0; i < len(sel)-1; i++ {
-		v := sel[i]
-		m, ok := cur[v]
+		m, ok := cur[sel[i]].(map[string]interface{})
 		if !ok {
 			return nil, false
 		}
-		cur = m.(map[string]interface{})
+		cur = m
 	}
+	ret, ok := cur[sel[len(sel)-1]]
+	return ret, ok
+}
 
-	v := sel[len(sel)-1]
-	ret, ok := cur[v]
-	if !ok {
-		return nil, false
+// SetDefault ... selectorで指定された設定のデフォルト値を登録する
+// 設定ファイルに値が無い場合に Get で返されるが、設定ファイルには保存しない (パッケージの初期化時に使う)
+func SetDefault(selector string, value interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	sel := strings.Split(selector, ".")
+	cur := defaults
+	for i := 0; i < len(sel)-1; i++ {
+		m, ok := cur[sel[i]].(map[string]interface{})
+		if !ok {
+			m = make(map[string]interface{})
+			cur[sel[i]] = m
+		}
+		cur = m
 	}
-	return ret, true
+	cur[sel[len(sel)-1]] = value
 }
 
 // Set ... selectorで指定された設定に値を上書きする
 // selector: `.`区切りで指定する value:書き込む値
 func Set(selector string, value interface{}) error {
+	mu.Lock()
+	defer mu.Unlock()
+
 	sel := strings.Split(selector, ".")
 	cur := wrapper
 	for i := 0; i < len(sel)-1; i++ {
@@ -95,14 +127,31 @@ func Set(selector string, value interface{}) error {
 	return nil
 }
 
-func save() {
-	// 実行ファイルのディレクトリに移動
-	prevDir, _ := os.Getwd()
-	defer os.Chdir(prevDir)
-	exeDir, _ := os.Executable()
-	exeDir = filepath.Dir(exeDir)
-	os.Chdir(exeDir)
+// Delete ... selectorで指定された設定を削除する (存在しない場合は何もしない)
+// selector: `.`区切りで指定する
+func Delete(selector string) {
+	mu.Lock()
+	defer mu.Unlock()
 
+	sel := strings.Split(selector, ".")
+	cur := wrapper
+	for i := 0; i < len(sel)-1; i++ {
+		m, ok := cur[sel[i]].(map[string]interface{})
+		if !ok {
+			return
+		}
+		cur = m
+	}
+	v := sel[len(sel)-1]
+	if _, ok := cur[v]; !ok {
+		return
+	}
+	delete(cur, v)
+	save()
+}
+
+// save ... 設定ファイルに書き込む (mu を取った状態で呼ぶ)
+func save() {
 	jsonBytes, err := json.Marshal(wrapper)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, util.PrefixError+"JSON Marshal error:", err)
@@ -112,7 +161,7 @@ func save() {
 	var buf bytes.Buffer
 	json.Indent(&buf, jsonBytes, "", "  ")
 
-	err = ioutil.WriteFile(SettingFilename, buf.Bytes(), 0600)
+	err = util.WriteFileAtomic(Path(), buf.Bytes(), 0600)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, util.PrefixError+"File write error:", err)
 		return
@@ -30,7 +30,7 @@ func parseSnippet(filePath string) (Snippet, error) {
 					}
 				}
 				if flag != 0 && flag != 2 && flag != 3 {
-					return nil, fmt.Errorf(util.PrefixError + "invalid format: " + l)
+					return nil, fmt.Errorf(util.PrefixError+"invalid format: %s", l)
 				}
 				if len(tagname) > 1 {
 					if tagname[0] == '*' {
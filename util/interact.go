@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 // AskChoose ... 選択肢を表示してユーザに選ばせる
@@ -47,3 +49,22 @@ func AskString(message string) string {
 	fmt.Scanln(&ans)
 	return ans
 }
+
+// AskPassword ... ユーザにパスワードを入力させる (端末の場合は入力した文字を表示しない)
+// message: 表示されるメッセージ "PrefixQuestion {message} > "の形式で表示される
+// return: 入力された文字列
+func AskPassword(message string) string {
+	fmt.Fprint(os.Stderr, PrefixQuestion+message+" > ")
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		var ans string
+		fmt.Scanln(&ans)
+		return ans
+	}
+	ans, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return ""
+	}
+	return string(ans)
+}
@@ -8,34 +8,16 @@ import (
 	"net/http/cookiejar"
 	"net/url"
 	"os"
-	"path/filepath"
-
-	"gopkg.in/headzoo/surf.v1/util"
 )
 
-// LoadLoginSession ... セッションファイルを読み込む
+// LoadLoginSession ... セッションファイルを読み込む (ファイルが無い場合は nil)
 func LoadLoginSession(filename string, cookieURL string) *cookiejar.Jar {
-	// 実行ファイルのディレクトリに移動
-	prevDir, _ := os.Getwd()
-	defer os.Chdir(prevDir)
-	exeDir, _ := os.Executable()
-	exeDir = filepath.Dir(exeDir)
-	os.Chdir(exeDir)
-
-	if !util.FileExists(filename) {
-		return nil
-	}
-
-	bytes, err := ioutil.ReadFile(filename)
+	cookies, err := ReadLoginSession(filename)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, PrefixError+"File open error:", err)
+		fmt.Fprintln(os.Stderr, PrefixError+"Failed to load the session:", err)
 		return nil
 	}
-
-	var cookies []*http.Cookie
-	err = json.Unmarshal(bytes, &cookies)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, PrefixError+"JSON Unmarshal error:", err)
+	if cookies == nil {
 		return nil
 	}
 	jar, _ := cookiejar.New(nil)
@@ -46,23 +28,47 @@ func LoadLoginSession(filename string, cookieURL string) *cookiejar.Jar {
 
 // SaveLoginSession .. セッションファイルを保存する
 func SaveLoginSession(filename string, cookies []*http.Cookie) {
-	// 実行ファイルのディレクトリに移動
-	prev, _ := os.Getwd()
-	defer os.Chdir(prev)
-	exeDir, _ := os.Executable()
-	exeDir = filepath.Dir(exeDir)
-	os.Chdir(exeDir)
-
 	bytes, err := json.Marshal(cookies)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, PrefixError+"JSON Marshal error:", err)
 		return
 	}
 
-	err = ioutil.WriteFile(filename, bytes, 0600)
+	path := LoginSessionPath(filename)
+	err = WriteFileAtomic(path, bytes, 0600)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, PrefixError+"File write error:", err)
 		return
 	}
-	fmt.Fprintln(os.Stderr, PrefixInfo+fmt.Sprintf("Saved session as `%s`", filename))
+	fmt.Fprintln(os.Stderr, PrefixInfo+fmt.Sprintf("Saved session as `%s`", path))
+}
+
+// LoginSessionPath ... セッションファイルのパス (DataDir の下)
+func LoginSessionPath(filename string) string {
+	return DataPath(filename)
+}
+
+// ReadLoginSession ... セッションファイルに保存されているクッキーを返す (ファイルが無い場合は nil)
+func ReadLoginSession(filename string) ([]*http.Cookie, error) {
+	bytes, err := ioutil.ReadFile(LoginSessionPath(filename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cookies []*http.Cookie
+	if err := json.Unmarshal(bytes, &cookies); err != nil {
+		return nil, err
+	}
+	return cookies, nil
+}
+
+// RemoveLoginSession ... セッションファイルを削除する (ファイルが無い場合は何もしない)
+func RemoveLoginSession(filename string) error {
+	err := os.Remove(LoginSessionPath(filename))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
 }
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"reflect"
 	"strings"
 )
@@ -45,6 +46,38 @@ func FileCopy(targetPath, destinationPath string) error {
 	return ioutil.WriteFile(destinationPath, cont, 0644)
 }
 
+// WriteFileAtomic ... data を path に書き込む (ディレクトリが無ければ作る)
+// 同じディレクトリの一時ファイルに書いてから名前を変えるので、途中で失敗したり並行に書き込まれたりしても中途半端なファイルが残らない
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
 // Command ... ` `で分割されたコマンド文字列から`*exec.Cmd`を作って返す
 func Command(spaceSeparatedCmd string) *exec.Cmd {
 	cmdSep := strings.Split(spaceSeparatedCmd, " ")
@@ -0,0 +1,153 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// KIDEが作るファイルは XDG Base Directory に従って次の場所に置かれる
+// - 設定 (settings.json)                                     : ConfigDir ($XDG_CONFIG_HOME/kide, ~/.config/kide)
+// - 問題、ログインのセッション、パスワードなど                : DataDir   ($XDG_DATA_HOME/kide, ~/.local/share/kide)
+// - コンパイル結果、言語一覧など消えても作り直せるもの         : CacheDir  ($XDG_CACHE_HOME/kide, ~/.cache/kide)
+// 環境変数 KIDE_CONFIG_DIR、KIDE_DATA_DIR、KIDE_CACHE_DIR でそれぞれを、
+// KIDE_HOME で全てを1つのディレクトリにまとめて変更できる (プロジェクトごとに分けたい場合など)
+
+const appDirName = "kide"
+
+// ExeDir ... 実行ファイルのディレクトリ (以前のバージョンではここに全てのファイルを置いていた)
+func ExeDir() string {
+	exe, _ := os.Executable()
+	return filepath.Dir(exe)
+}
+
+// baseDir ... 環境変数 envKey、KIDE_HOME、$xdgKey/kide、~/homeRel/kide の順に探したディレクトリ
+// (ホームディレクトリが分からない場合は実行ファイルのディレクトリ)
+func baseDir(envKey, xdgKey, homeRel string) string {
+	if dir := os.Getenv(envKey); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("KIDE_HOME"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv(xdgKey); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, appDirName)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return ExeDir()
+	}
+	return filepath.Join(home, homeRel, appDirName)
+}
+
+// ConfigDir ... 設定ファイルを置くディレクトリ
+func ConfigDir() string {
+	return baseDir("KIDE_CONFIG_DIR", "XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir ... 問題やセッションなどのデータを置くディレクトリ
+func DataDir() string {
+	return baseDir("KIDE_DATA_DIR", "XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// CacheDir ... 消えても作り直せるものを置くディレクトリ
+func CacheDir() string {
+	return baseDir("KIDE_CACHE_DIR", "XDG_CACHE_HOME", ".cache")
+}
+
+// ConfigPath ... ConfigDir 以下のパス (以前のバージョンで実行ファイルのディレクトリに置かれていたものは移動する)
+func ConfigPath(elem ...string) string {
+	return migratedPath(ConfigDir(), elem)
+}
+
+// DataPath ... DataDir 以下のパス (以前のバージョンで実行ファイルのディレクトリに置かれていたものは移動する)
+func DataPath(elem ...string) string {
+	return migratedPath(DataDir(), elem)
+}
+
+// CachePath ... CacheDir 以下のパス (キャッシュは作り直せるので移動しない)
+func CachePath(elem ...string) string {
+	return filepath.Join(append([]string{CacheDir()}, elem...)...)
+}
+
+var (
+	migrateMu sync.Mutex
+	migrated  = map[string]bool{}
+)
+
+// migratedPath ... dir 以下のパスを返す
+// 初めて使う名前 (elem の最初の要素) の場合は、実行ファイルのディレクトリにある同じ名前のファイル・ディレクトリを dir に移動する
+func migratedPath(dir string, elem []string) string {
+	path := filepath.Join(append([]string{dir}, elem...)...)
+	if len(elem) == 0 {
+		return path
+	}
+
+	migrateMu.Lock()
+	defer migrateMu.Unlock()
+	newPath := filepath.Join(dir, elem[0])
+	if migrated[newPath] {
+		return path
+	}
+	migrated[newPath] = true
+
+	oldPath := filepath.Join(ExeDir(), elem[0])
+	if filepath.Clean(oldPath) == filepath.Clean(newPath) || !FileExists(oldPath) || FileExists(newPath) {
+		return path
+	}
+	if err := movePath(oldPath, newPath); err != nil {
+		fmt.Fprintln(os.Stderr, PrefixCaution+fmt.Sprintf("Failed to move `%s` to `%s` : %s", oldPath, newPath, err))
+		return path
+	}
+	fmt.Fprintln(os.Stderr, PrefixInfo+fmt.Sprintf("Moved `%s` to `%s`", oldPath, newPath))
+	return path
+}
+
+// movePath ... ファイルまたはディレクトリを移動する (別のファイルシステムへはコピーしてから削除する)
+func movePath(oldPath, newPath string) error {
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(oldPath, newPath); err == nil {
+		return nil
+	}
+	if err := copyTree(oldPath, newPath); err != nil {
+		os.RemoveAll(newPath)
+		return err
+	}
+	return os.RemoveAll(oldPath)
+}
+
+// copyTree ... src 以下を権限を保ったまま dst にコピーする
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
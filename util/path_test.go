@@ -0,0 +1,124 @@
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBaseDir(t *testing.T) {
+	fmt.Println("testing : path.go > ConfigDir, DataDir, CacheDir")
+
+	for _, key := range []string{"KIDE_HOME", "KIDE_CONFIG_DIR", "KIDE_DATA_DIR", "KIDE_CACHE_DIR", "XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME"} {
+		if v, ok := os.LookupEnv(key); ok {
+			defer os.Setenv(key, v)
+		} else {
+			defer os.Unsetenv(key)
+		}
+		os.Unsetenv(key)
+	}
+
+	os.Setenv("HOME", "/home/kide")
+	if got := ConfigDir(); got != "/home/kide/.config/kide" {
+		t.Errorf("ConfigDir() = %s", got)
+	}
+	if got := DataDir(); got != "/home/kide/.local/share/kide" {
+		t.Errorf("DataDir() = %s", got)
+	}
+
+	os.Setenv("XDG_CACHE_HOME", "/tmp/xdg_cache")
+	if got := CacheDir(); got != "/tmp/xdg_cache/kide" {
+		t.Errorf("CacheDir() = %s", got)
+	}
+
+	os.Setenv("KIDE_HOME", "/tmp/kide_home")
+	os.Setenv("KIDE_DATA_DIR", "/tmp/kide_data")
+	if got := ConfigDir(); got != "/tmp/kide_home" {
+		t.Errorf("ConfigDir() = %s", got)
+	}
+	if got := CacheDir(); got != "/tmp/kide_home" {
+		t.Errorf("CacheDir() = %s", got)
+	}
+	if got := DataDir(); got != "/tmp/kide_data" {
+		t.Errorf("DataDir() = %s", got)
+	}
+}
+
+func TestMigratedPath(t *testing.T) {
+	fmt.Println("testing : path.go > DataPath")
+
+	home, err := ioutil.TempDir("", "kide_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	defer os.Unsetenv("KIDE_DATA_DIR")
+	os.Setenv("KIDE_DATA_DIR", home)
+
+	// 以前のバージョンで実行ファイルのディレクトリに保存されたファイル
+	legacyDir := filepath.Join(ExeDir(), "kide_test_samplecases")
+	if err := os.MkdirAll(filepath.Join(legacyDir, "atcoder"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(legacyDir)
+	if err := ioutil.WriteFile(filepath.Join(legacyDir, "atcoder", "A.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path := DataPath("kide_test_samplecases", "atcoder", "A.json")
+	if path != filepath.Join(home, "kide_test_samplecases", "atcoder", "A.json") {
+		t.Errorf("DataPath returned %s", path)
+	}
+	if bytes, err := ioutil.ReadFile(path); err != nil || string(bytes) != "{}" {
+		t.Errorf("the file was not moved : %v", err)
+	}
+	if FileExists(legacyDir) {
+		t.Error("the old directory still exists")
+	}
+
+	// 移動先が既にある場合は移動しない
+	other := filepath.Join(ExeDir(), "kide_test_other")
+	ioutil.WriteFile(other, []byte("old"), 0644)
+	defer os.Remove(other)
+	ioutil.WriteFile(filepath.Join(home, "kide_test_other"), []byte("new"), 0644)
+	if bytes, _ := ioutil.ReadFile(DataPath("kide_test_other")); string(bytes) != "new" || !FileExists(other) {
+		t.Error("the existing file was overwritten")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	fmt.Println("testing : os.go > WriteFileAtomic")
+
+	dir, err := ioutil.TempDir("", "kide_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sub", "file.json")
+
+	// 並行に書き込んでも、どれか1つの内容が完全に残る
+	done := make(chan error)
+	for i := 0; i < 16; i++ {
+		go func(i int) {
+			done <- WriteFileAtomic(path, []byte(fmt.Sprintf("content %02d", i)), 0600)
+		}(i)
+	}
+	for i := 0; i < 16; i++ {
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil || len(bytes) != len("content 00") {
+		t.Errorf("unexpected content %q : %v", bytes, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("unexpected permission : %v", info.Mode())
+	}
+	files, _ := ioutil.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Errorf("temporary files are left : %d files", len(files))
+	}
+}
@@ -127,3 +127,13 @@ func RestoreCursorPos() {
 func ClearCurrentLine() {
 	fmt.Print("\033[2K")
 }
+
+// MoveCursorUp ... カーソルを n 行上の行頭に移動する
+func MoveCursorUp(n int) {
+	fmt.Printf("\033[%dF", n)
+}
+
+// ClearScreenBelow ... カーソルの位置から画面の最後までをクリアする
+func ClearScreenBelow() {
+	fmt.Print("\033[J")
+}
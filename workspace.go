@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/algon-320/KIDE/language"
+	"github.com/algon-320/KIDE/online_judge"
+	"github.com/algon-320/KIDE/setting"
+	"github.com/algon-320/KIDE/util"
+)
+
+const (
+	// workspaceMarkerFilename ... 問題のディレクトリに置く、保存されている問題との対応を記録するファイル
+	workspaceMarkerFilename = ".kide_problem.json"
+	// workspaceSourceName ... 問題のディレクトリに作るソースファイルの名前 (拡張子を除く)
+	workspaceSourceName = "Main"
+)
+
+// workspaceMarker ... 問題のディレクトリと保存されている問題の対応
+type workspaceMarker struct {
+	Problem string `json:"problem"` // 問題のキー (`atcoder/abc300/A` など)
+	URL     string `json:"url"`
+}
+
+// writeWorkspaceMarker ... dir を問題 p のディレクトリとして記録する
+func writeWorkspaceMarker(dir string, p *online_judge.Problem) error {
+	jsonBytes, err := json.MarshalIndent(&workspaceMarker{Problem: p.Key(), URL: p.URL}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, workspaceMarkerFilename), jsonBytes, 0644)
+}
+
+// readWorkspaceMarker ... dir に記録されている問題を返す
+func readWorkspaceMarker(dir string) (*workspaceMarker, error) {
+	bytes, err := ioutil.ReadFile(filepath.Join(dir, workspaceMarkerFilename))
+	if err != nil {
+		return nil, err
+	}
+	var m workspaceMarker
+	if err := json.Unmarshal(bytes, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// loadTemplate ... lang のソースファイルのテンプレートを返す
+// Language.{言語名}.Template にテンプレートのファイルのパスを設定できる ({EXE_DIR} は実行ファイルのディレクトリに置換される)
+// 設定されていない場合は問題のURLと日付をコメントとして書いただけのもの
+func loadTemplate(lang language.Language) (string, error) {
+	tmp, ok := setting.Get("Language."+lang.Name()+".Template", "")
+	if !ok {
+		return lang.CommentOut("problem: {PROBLEM_URL}") + "\n" +
+			lang.CommentOut("{DATE}") + "\n\n", nil
+	}
+	templatePath, ok := tmp.(string)
+	if !ok {
+		return "", fmt.Errorf(util.PrefixError+"`Language.%s.Template` must be a path", lang.Name())
+	}
+	exeDir, _ := os.Executable()
+	templatePath = strings.Replace(templatePath, "{EXE_DIR}", filepath.Dir(exeDir), 1)
+
+	bytes, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		return "", fmt.Errorf(util.PrefixError+"%s", err)
+	}
+	return string(bytes), nil
+}
+
+// renderTemplate ... テンプレートのプレースホルダを問題 p の情報で置換する
+func renderTemplate(template string, p *online_judge.Problem, now time.Time) string {
+	ojName := ""
+	if r := online_judge.Lookup(p.Oj); r != nil {
+		ojName = r.Name()
+	}
+	return strings.NewReplacer(
+		"{PROBLEM_NAME}", p.Name,
+		"{PROBLEM_ID}", p.ID,
+		"{PROBLEM_KEY}", p.Key(),
+		"{PROBLEM_URL}", p.URL,
+		"{CONTEST_ID}", p.ContestID,
+		"{OJ_NAME}", ojName,
+		"{TIME_LIMIT}", p.TimeLimit(),
+		"{MEMORY_LIMIT}", p.MemoryLimit(),
+		"{DATE}", now.Format("2006-01-02 15:04:05"),
+	).Replace(template)
+}
+
+// newWorkspace ... コンテストの問題をダウンロードして、問題ごとのディレクトリを作る
+// wait: trueならコンテストの開始を待ってからダウンロードする
+func newWorkspace(contestURL string, lang language.Language, baseDir string, wait bool) error {
+	var problems []*online_judge.Problem
+	var dlErr error
+	if wait {
+		problems, dlErr = online_judge.WaitAndDownloadContest(contestURL)
+	} else {
+		problems, dlErr = online_judge.DownloadContest(contestURL)
+	}
+	if len(problems) == 0 {
+		return dlErr
+	}
+	if err := scaffoldWorkspace(problems, lang, baseDir); err != nil {
+		return err
+	}
+	return dlErr
+}
+
+// scaffoldWorkspace ... baseDir/{コンテストID}/{問題id} に問題ごとのディレクトリを作る
+// 各ディレクトリにはテンプレートから作ったソースファイルと、問題との対応を記録するファイルを置く
+// (既にあるソースファイルは上書きしない)
+func scaffoldWorkspace(problems []*online_judge.Problem, lang language.Language, baseDir string) error {
+	template, err := loadTemplate(lang)
+	if err != nil {
+		return err
+	}
+
+	contestDir := filepath.Join(baseDir, path.Base(problems[0].ContestKey()))
+	now := time.Now()
+
+	title := []string{"problem", "directory", "source"}
+	data := [][]string{}
+	for _, p := range problems {
+		dir := filepath.Join(contestDir, p.ID)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := writeWorkspaceMarker(dir, p); err != nil {
+			return err
+		}
+
+		sourcePath := filepath.Join(dir, workspaceSourceName+lang.FileExtension())
+		status := "created"
+		if util.FileExists(sourcePath) {
+			status = "exists (skipped)"
+		} else if err := ioutil.WriteFile(sourcePath, []byte(renderTemplate(template, p, now)), 0644); err != nil {
+			return err
+		}
+		data = append(data, []string{p.Key(), dir, filepath.Base(sourcePath) + " " + status})
+	}
+	util.PrintTable(title, data, true)
+	return nil
+}
+
+// problemHint ... カレントディレクトリから推測した問題
+type problemHint struct {
+	key  string // 問題のキー
+	from string // 推測の根拠 (表示用)
+}
+
+// inferProblems ... カレントディレクトリから問題を推測する
+// 問題との対応を記録したファイル、ソースファイルの `problem: {URL}` のコメント、ディレクトリ名の順に調べ、見つかったものを全て返す
+func inferProblems(lang language.Language) []problemHint {
+	hints := []problemHint{}
+
+	// 問題との対応を記録したファイル
+	if m, err := readWorkspaceMarker("."); err == nil {
+		if key, err := online_judge.ResolveProblemID(m.Problem); err == nil {
+			hints = append(hints, problemHint{key: key, from: workspaceMarkerFilename})
+		} else if key, err := online_judge.FindProblemByURL(m.URL); err == nil {
+			hints = append(hints, problemHint{key: key, from: workspaceMarkerFilename})
+		}
+	}
+
+	// ソースファイルのコメント (ソースファイルが1つに決まる場合のみ)
+	if sourcePath := uniqueSourceFile(lang); sourcePath != "" {
+		if problemURL := problemURLInSource(sourcePath, lang); problemURL != "" {
+			if key, err := online_judge.FindProblemByURL(problemURL); err == nil {
+				hints = append(hints, problemHint{key: key, from: sourcePath})
+			}
+		}
+	}
+
+	// ディレクトリ名 (`abc300/A` のように親ディレクトリも含めたもの、問題idだけのものの順)
+	if wd, err := os.Getwd(); err == nil {
+		ids := []string{
+			filepath.Base(filepath.Dir(wd)) + "/" + filepath.Base(wd),
+			filepath.Base(wd),
+		}
+		for _, id := range ids {
+			if key, err := online_judge.ResolveProblemID(id); err == nil {
+				hints = append(hints, problemHint{key: key, from: "directory name"})
+				break
+			}
+		}
+	}
+	return hints
+}
+
+// uniqueSourceFile ... カレントディレクトリにある lang のソースファイルが1つだけならそのファイル名を返す
+func uniqueSourceFile(lang language.Language) string {
+	files, err := ioutil.ReadDir(".")
+	if err != nil {
+		return ""
+	}
+	ret := ""
+	for _, file := range files {
+		if !file.IsDir() && filepath.Ext(file.Name()) == lang.FileExtension() {
+			if ret != "" {
+				return ""
+			}
+			ret = file.Name()
+		}
+	}
+	return ret
+}
+
+// problemURLInSource ... ソースファイルの `problem: {URL}` というコメントから問題のURLを返す (無ければ空文字列)
+func problemURLInSource(sourcePath string, lang language.Language) string {
+	bytes, err := ioutil.ReadFile(sourcePath)
+	if err != nil {
+		return ""
+	}
+	minLen := len(lang.CommentOut(""))
+	for _, line := range strings.Split(string(bytes), "\n") {
+		line = strings.TrimRight(line, "\r")
+		// コメントになっている行だけを見る
+		if len(line) < minLen || lang.CommentOut(lang.UnComment(line)) != line {
+			continue
+		}
+		text := strings.TrimSpace(lang.UnComment(line))
+		if strings.HasPrefix(text, "problem:") {
+			return strings.TrimSpace(strings.TrimPrefix(text, "problem:"))
+		}
+	}
+	return ""
+}
+
+// chooseProblem ... 引数で指定された問題のキーを返す (引数が無い場合はカレントディレクトリから推測した問題)
+// 指定された問題と推測した問題が異なる場合や、推測した問題同士が異なる場合は警告する
+func chooseProblem(args []string, lang language.Language) (string, error) {
+	hints := inferProblems(lang)
+
+	if len(args) >= 1 {
+		key, err := online_judge.ResolveProblemID(args[0])
+		if err != nil {
+			return "", err
+		}
+		if len(hints) > 0 && hints[0].key != key {
+			fmt.Fprintln(os.Stderr, util.PrefixCaution+fmt.Sprintf(
+				"The problem `%s` differs from the problem of this directory `%s` (from %s)", key, hints[0].key, hints[0].from))
+		}
+		return key, nil
+	}
+
+	if len(hints) == 0 {
+		return "", fmt.Errorf(util.PrefixError + "few args (couldn't infer the problem from this directory)")
+	}
+	for _, h := range hints[1:] {
+		if h.key != hints[0].key {
+			fmt.Fprintln(os.Stderr, util.PrefixCaution+fmt.Sprintf(
+				"The problem `%s` (from %s) differs from `%s` (from %s)", hints[0].key, hints[0].from, h.key, h.from))
+		}
+	}
+	fmt.Fprintln(os.Stderr, util.PrefixInfo+fmt.Sprintf("Problem : %s (from %s)", hints[0].key, hints[0].from))
+	return hints[0].key, nil
+}